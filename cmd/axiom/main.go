@@ -93,6 +93,9 @@ func main() {
 	// Finally execute the root command.
 	if cmd, err := rootCmd.ExecuteContextC(ctx); err != nil {
 		printError(f.IO.ErrOut(), err, cmd)
+		if exitErr, ok := errors.AsType[*cmdutil.ExitError](err); ok {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	} else if root.HasFailed() {
 		os.Exit(1)
@@ -0,0 +1,303 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/ingest"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/pkg/utils"
+)
+
+type options struct {
+	*cmdutil.Factory
+
+	// ConfigFile is the path of the agent configuration file.
+	ConfigFile string
+	// Validate the configuration and exit.
+	Validate bool
+}
+
+// NewCmd creates and returns the agent command.
+func NewCmd(f *cmdutil.Factory) *cobra.Command {
+	opts := &options{
+		Factory: f,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "agent (-c|--agent-config) <filename> [--validate]",
+		Short: "Ship logs continuously",
+		Long: heredoc.Doc(`
+			Run a long-running agent that reads events from files, the systemd
+			journal and sockets and continuously ships them to Axiom.
+
+			The sources, how their lines are parsed and the datasets they are
+			shipped to are read from a TOML configuration file, given using
+			"--agent-config". Credentials are taken from the Axiom CLI
+			configuration file, which is chosen using "--config" like for all
+			other commands, and the environment. A source ships to the
+			deployment configured for it, the deployment configured for the
+			agent or the active deployment, in that order.
+
+			Every source has a type:
+
+			  - file: Tails the files matching the glob patterns of "paths".
+			    Rotated and truncated files are detected.
+			  - journald: Follows the systemd journal, optionally limited to the
+			    systemd units given in "units". Requires "journalctl".
+			  - socket: Receives lines on the "listen" address, in the form
+			    "tcp://<host>:<port>", "udp://<host>:<port>" or "unix://<path>".
+
+			The "parser" of a source decides how lines are turned into events:
+			"text" keeps each line in the "message" field, "json" expects JSON
+			objects, "docker" and "cri" parse container logs and "journal"
+			parses entries of the systemd journal.
+
+			Events are shipped in batches of "batch_size" events, at least every
+			"flush_every". Batches that fail to ship are retried until they are
+			shipped. The position up to which a file or the journal has been
+			shipped is kept in the positions database, so shipping resumes where
+			it left off when the agent restarts.
+
+			If "health_addr" is set, the agent serves its status as JSON on
+			"/health". The endpoint responds with "503 Service Unavailable"
+			while shipping to any dataset fails.
+
+			The agent is stopped by sending it SIGINT or SIGTERM. Events read by
+			then are shipped before it exits.
+
+			A configuration shipping web server logs, container logs of a
+			Kubernetes node, the journal of the SSH daemon and syslog messages
+			received over UDP looks like this:
+
+			  deployment = "prod"
+			  positions = "/var/lib/axiom/positions.json"
+			  health_addr = "127.0.0.1:8686"
+			  flush_every = "5s"
+			  batch_size = 10000
+
+			  [[sources]]
+			  name = "nginx"
+			  type = "file"
+			  paths = ["/var/log/nginx/*.log"]
+			  dataset = "nginx-logs"
+
+			  [[sources]]
+			  name = "pods"
+			  type = "file"
+			  paths = ["/var/log/pods/*/*/*.log"]
+			  parser = "cri"
+			  dataset = "k8s-logs"
+
+			  [[sources]]
+			  name = "sshd"
+			  type = "journald"
+			  units = ["sshd.service"]
+			  dataset = "journal"
+
+			  [[sources]]
+			  name = "syslog"
+			  type = "socket"
+			  listen = "udp://0.0.0.0:5140"
+			  dataset = "syslog"
+			  deployment = "eu"
+		`),
+
+		DisableFlagsInUseLine: true,
+
+		Args: cobra.NoArgs,
+
+		Example: heredoc.Doc(`
+			# Run the agent with the configuration in "agent.toml":
+			$ axiom agent --agent-config agent.toml
+
+			# Validate the configuration without running the agent:
+			$ axiom agent --agent-config agent.toml --validate
+		`),
+
+		Annotations: map[string]string{
+			"IsCore": "true",
+		},
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return run(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.ConfigFile, "agent-config", "c", "", "Path to the agent configuration file")
+	cmd.Flags().BoolVar(&opts.Validate, "validate", false, "Validate the configuration and exit")
+
+	_ = cmd.MarkFlagRequired("agent-config")
+
+	_ = cmd.RegisterFlagCompletionFunc("agent-config", tomlFileCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("validate", cmdutil.NoCompletion)
+
+	return cmd
+}
+
+func run(ctx context.Context, opts *options) error {
+	ac, err := loadConfig(opts.ConfigFile, opts.Config)
+	if err != nil {
+		return err
+	}
+
+	cs := opts.IO.ColorScheme()
+
+	if opts.Validate {
+		if opts.IO.IsStderrTTY() {
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Configuration %s is valid\n",
+				cs.SuccessIcon(), cs.Bold(opts.ConfigFile))
+		}
+		return nil
+	}
+
+	positions, err := loadPositions(ac.Positions)
+	if err != nil {
+		return err
+	}
+
+	logger := log.New(opts.IO.ErrOut(), "", log.LstdFlags)
+
+	// Sources shipping to the same dataset share a shipper.
+	var (
+		shippers  = make(map[string]*shipper)
+		statuses  = make([]*sourceStatus, len(ac.Sources))
+		sources   = make([]source, len(ac.Sources))
+		clients   = make(map[string]*axiom.Client)
+		shipOrder []*shipper
+	)
+	for i, sc := range ac.Sources {
+		if sc.Deployment == "" {
+			sc.Deployment = ac.Deployment
+		}
+		dest := sc.destination()
+
+		if _, ok := shippers[dest]; !ok {
+			client, ok := clients[sc.Deployment]
+			if !ok {
+				if sc.Deployment == "" {
+					client, err = opts.Client(ctx)
+				} else {
+					client, err = opts.DeploymentClient(ctx, sc.Deployment)
+				}
+				if err != nil {
+					return err
+				}
+				clients[sc.Deployment] = client
+			}
+
+			dataset := sc.Dataset
+			ingestEvents := func(ctx context.Context, events []axiom.Event) (*ingest.Status, error) {
+				return client.IngestEvents(ctx, dataset, events)
+			}
+			shippers[dest] = newShipper(dest, ingestEvents, positions, ac, logger)
+			shipOrder = append(shipOrder, shippers[dest])
+		}
+
+		statuses[i] = &sourceStatus{
+			name: sc.Name,
+			typ:  sc.Type,
+			dest: dest,
+			log:  logger,
+		}
+
+		switch sc.Type {
+		case sourceFile:
+			sources[i] = newFileSource(sc, positions, statuses[i])
+		case sourceJournald:
+			sources[i] = newJournaldSource(sc, positions, statuses[i])
+		case sourceSocket:
+			sources[i] = newSocketSource(sc, statuses[i])
+		}
+	}
+
+	if ac.HealthAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/health", &health{
+			started:  time.Now(),
+			sources:  statuses,
+			shippers: shipOrder,
+		})
+		srv := &http.Server{
+			Handler: mux,
+
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+
+		var lc net.ListenConfig
+		ln, err := lc.Listen(ctx, "tcp", ac.HealthAddr)
+		if err != nil {
+			return fmt.Errorf("start health endpoint: %w", err)
+		}
+		go func() {
+			if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				logger.Printf("Health endpoint failed: %s", err)
+			}
+		}()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+			defer cancel()
+			_ = srv.Shutdown(ctx)
+		}()
+	}
+
+	logger.Printf("Agent started with %s shipping to %s",
+		utils.Pluralize(cs, "source", len(sources)), utils.Pluralize(cs, "dataset", len(shipOrder)))
+
+	var shipWG sync.WaitGroup
+	for _, s := range shipOrder {
+		shipWG.Go(func() { s.Run(ctx) })
+	}
+
+	// Once all sources shipping to a dataset stopped, its shipper ships the
+	// remaining events and stops.
+	var (
+		sourceWGs = make(map[*shipper]*sync.WaitGroup, len(shipOrder))
+		closeWG   sync.WaitGroup
+	)
+	for i, src := range sources {
+		s := shippers[statuses[i].dest]
+		if sourceWGs[s] == nil {
+			sourceWGs[s] = new(sync.WaitGroup)
+		}
+		sourceWGs[s].Go(func() {
+			if err := src.Run(ctx, s.in); err != nil {
+				statuses[i].error(err)
+				logger.Printf("Source %s stopped", statuses[i].name)
+			}
+		})
+	}
+	for s, wg := range sourceWGs {
+		closeWG.Go(func() {
+			wg.Wait()
+			close(s.in)
+		})
+	}
+
+	closeWG.Wait()
+	shipWG.Wait()
+
+	if err = positions.Save(); err != nil {
+		return fmt.Errorf("save positions: %w", err)
+	} else if ctx.Err() == nil {
+		return errors.New("all sources stopped")
+	}
+
+	logger.Print("Agent stopped")
+
+	return nil
+}
+
+func tomlFileCompletion(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{"toml"}, cobra.ShellCompDirectiveFilterFileExt
+}
@@ -0,0 +1,253 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml"
+
+	"github.com/axiomhq/cli/internal/cmd/ingest"
+	"github.com/axiomhq/cli/internal/config"
+)
+
+// Source types.
+const (
+	sourceFile     = "file"
+	sourceJournald = "journald"
+	sourceSocket   = "socket"
+)
+
+var validSourceTypes = []string{
+	sourceFile,
+	sourceJournald,
+	sourceSocket,
+}
+
+// Parsers used by default.
+const (
+	parserText    = "text"
+	parserJournal = "journal"
+)
+
+// Positions to start reading a source at, if no position is stored for it.
+const (
+	startAtBeginning = "beginning"
+	startAtEnd       = "end"
+)
+
+// agentConfig is the configuration of the agent, read from a TOML file.
+type agentConfig struct {
+	// Deployment is the alias of the deployment to ship to, unless set for a
+	// source. If empty, the active deployment is used.
+	Deployment string `toml:"deployment"`
+	// Positions is the path of the positions database. It is required if any
+	// file or journald sources are configured.
+	Positions string `toml:"positions"`
+	// HealthAddr is the address the health endpoint listens on. If empty, the
+	// health endpoint is disabled.
+	HealthAddr string `toml:"health_addr"`
+	// FlushEvery is the interval events are shipped at, at the latest.
+	FlushEvery time.Duration `toml:"flush_every"`
+	// BatchSize is the maximum number of events shipped at once.
+	BatchSize int `toml:"batch_size"`
+	// Sources to read events from.
+	Sources []sourceConfig `toml:"sources"`
+}
+
+// sourceConfig is the configuration of a single source.
+type sourceConfig struct {
+	// Name of the source. It identifies the source in the positions database
+	// and on the health endpoint. Defaults to the type and the index of the
+	// source.
+	Name string `toml:"name"`
+	// Type of the source: "file", "journald" or "socket".
+	Type string `toml:"type"`
+	// Paths are the glob patterns of the files to tail. File sources only.
+	Paths []string `toml:"paths"`
+	// Units are the systemd units to read the journal of. If empty, the
+	// whole journal is read. Journald sources only.
+	Units []string `toml:"units"`
+	// Listen is the address to listen on in the form "<network>://<address>"
+	// with network being "tcp", "udp" or "unix". Socket sources only.
+	Listen string `toml:"listen"`
+	// Parser is the format of the lines read. Defaults to "journal" for
+	// journald sources and "text" for all others.
+	Parser string `toml:"parser"`
+	// StartAt is where to start reading if no position is stored: at the
+	// "beginning" or the "end". Defaults to the beginning for file sources and
+	// the end for journald sources.
+	StartAt string `toml:"start_at"`
+	// Dataset to ship to.
+	Dataset string `toml:"dataset"`
+	// Deployment is the alias of the deployment to ship to. If empty, the
+	// deployment of the agent is used.
+	Deployment string `toml:"deployment"`
+}
+
+// destination returns the destination the source ships to, in the form
+// "[<deployment>:]<dataset>".
+func (sc sourceConfig) destination() string {
+	if sc.Deployment == "" {
+		return sc.Dataset
+	}
+	return sc.Deployment + ":" + sc.Dataset
+}
+
+// loadConfig reads the agent configuration from the TOML file at the given
+// path, applies defaults and validates it against the CLI configuration.
+func loadConfig(path string, cfg *config.Config) (*agentConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ac, err := parseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	} else if err = ac.validate(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ac, nil
+}
+
+func parseConfig(r io.Reader) (*agentConfig, error) {
+	tree, err := toml.LoadReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ac := &agentConfig{
+		FlushEvery: 5 * time.Second,
+		BatchSize:  10_000,
+	}
+	if err = tree.Unmarshal(ac); err != nil {
+		return nil, err
+	}
+
+	for i := range ac.Sources {
+		sc := &ac.Sources[i]
+		sc.Type = strings.ToLower(sc.Type)
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("%s-%d", sc.Type, i+1)
+		}
+		if sc.Parser == "" && sc.Type == sourceJournald {
+			sc.Parser = parserJournal
+		} else if sc.Parser == "" {
+			sc.Parser = parserText
+		}
+		if sc.StartAt == "" && sc.Type == sourceJournald {
+			sc.StartAt = startAtEnd
+		} else if sc.StartAt == "" {
+			sc.StartAt = startAtBeginning
+		}
+	}
+
+	return ac, nil
+}
+
+func (ac *agentConfig) validate(cfg *config.Config) error {
+	if ac.Deployment != "" {
+		if _, ok := cfg.Deployments[ac.Deployment]; !ok {
+			return fmt.Errorf("deployment %q not configured", ac.Deployment)
+		}
+	}
+	if ac.FlushEvery <= 0 {
+		return errors.New("flush_every must be positive")
+	} else if ac.BatchSize <= 0 {
+		return errors.New("batch_size must be positive")
+	} else if len(ac.Sources) == 0 {
+		return errors.New("no sources configured")
+	}
+
+	names := make(map[string]bool, len(ac.Sources))
+	for _, sc := range ac.Sources {
+		if names[sc.Name] {
+			return fmt.Errorf("duplicate source name %q", sc.Name)
+		}
+		names[sc.Name] = true
+
+		if err := sc.validate(cfg); err != nil {
+			return fmt.Errorf("source %q: %w", sc.Name, err)
+		}
+
+		if ac.Positions == "" && sc.Type != sourceSocket {
+			return fmt.Errorf("source %q: positions database required for %s sources", sc.Name, sc.Type)
+		}
+	}
+
+	return nil
+}
+
+func (sc sourceConfig) validate(cfg *config.Config) error {
+	switch sc.Type {
+	case sourceFile:
+		if len(sc.Paths) == 0 {
+			return errors.New("no paths configured")
+		}
+		for _, pattern := range sc.Paths {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid path %q: %w", pattern, err)
+			}
+		}
+	case sourceJournald:
+	case sourceSocket:
+		if _, _, err := parseListenAddr(sc.Listen); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid type %q (valid types: %s)", sc.Type, strings.Join(validSourceTypes, ", "))
+	}
+
+	if !slices.Contains(ingest.LineFormats(), sc.Parser) {
+		return fmt.Errorf("invalid parser %q (valid parsers: %s)", sc.Parser, strings.Join(ingest.LineFormats(), ", "))
+	} else if sc.Type == sourceJournald && sc.Parser != parserJournal {
+		return fmt.Errorf("invalid parser %q: journald sources only support the journal parser", sc.Parser)
+	}
+
+	if sc.StartAt != startAtBeginning && sc.StartAt != startAtEnd {
+		return fmt.Errorf("invalid start_at %q (valid values: %s, %s)", sc.StartAt, startAtBeginning, startAtEnd)
+	}
+
+	if sc.Dataset == "" {
+		return errors.New("no dataset configured")
+	} else if _, ok := cfg.Deployments[sc.Deployment]; !ok && sc.Deployment != "" {
+		return fmt.Errorf("deployment %q not configured", sc.Deployment)
+	}
+
+	return nil
+}
+
+// parseListenAddr splits an address in the form "<network>://<address>" into
+// the network and the address.
+func parseListenAddr(s string) (string, string, error) {
+	if s == "" {
+		return "", "", errors.New("no listen address configured")
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid listen address %q: %w", s, err)
+	}
+
+	switch u.Scheme {
+	case "tcp", "udp":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("invalid listen address %q: missing host and port", s)
+		}
+		return u.Scheme, u.Host, nil
+	case "unix":
+		if path := u.Host + u.Path; path != "" {
+			return u.Scheme, path, nil
+		}
+		return "", "", fmt.Errorf("invalid listen address %q: missing path", s)
+	}
+	return "", "", fmt.Errorf("invalid listen address %q: network must be tcp, udp or unix", s)
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/cli/internal/config"
+)
+
+var testConfig = &config.Config{
+	Deployments: map[string]config.Deployment{
+		"prod": {URL: "https://api.axiom.co"},
+		"eu":   {URL: "https://api.eu.axiom.co"},
+	},
+}
+
+func TestParseConfig(t *testing.T) {
+	ac, err := parseConfig(strings.NewReader(`
+		deployment = "prod"
+		positions = "/var/lib/axiom/positions.json"
+		flush_every = "10s"
+
+		[[sources]]
+		type = "file"
+		paths = ["/var/log/*.log"]
+		dataset = "logs"
+
+		[[sources]]
+		name = "sshd"
+		type = "journald"
+		units = ["sshd.service"]
+		dataset = "journal"
+
+		[[sources]]
+		type = "socket"
+		listen = "udp://0.0.0.0:5140"
+		parser = "json"
+		dataset = "syslog"
+		deployment = "eu"
+	`))
+	require.NoError(t, err)
+	require.NoError(t, ac.validate(testConfig))
+
+	assert.Equal(t, 10*time.Second, ac.FlushEvery)
+	assert.Equal(t, 10_000, ac.BatchSize)
+	assert.Equal(t, []sourceConfig{
+		{
+			Name:    "file-1",
+			Type:    sourceFile,
+			Paths:   []string{"/var/log/*.log"},
+			Parser:  parserText,
+			StartAt: startAtBeginning,
+			Dataset: "logs",
+		},
+		{
+			Name:    "sshd",
+			Type:    sourceJournald,
+			Units:   []string{"sshd.service"},
+			Parser:  parserJournal,
+			StartAt: startAtEnd,
+			Dataset: "journal",
+		},
+		{
+			Name:       "socket-3",
+			Type:       sourceSocket,
+			Listen:     "udp://0.0.0.0:5140",
+			Parser:     "json",
+			StartAt:    startAtBeginning,
+			Dataset:    "syslog",
+			Deployment: "eu",
+		},
+	}, ac.Sources)
+	assert.Equal(t, "eu:syslog", ac.Sources[2].destination())
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "no sources",
+			config: `positions = "positions.json"`,
+			err:    "no sources configured",
+		},
+		{
+			name:   "unknown deployment",
+			config: "deployment = \"us\"\n[[sources]]\ntype = \"socket\"\nlisten = \"tcp://:5140\"\ndataset = \"logs\"",
+			err:    `deployment "us" not configured`,
+		},
+		{
+			name:   "invalid type",
+			config: "[[sources]]\ntype = \"kafka\"\ndataset = \"logs\"",
+			err:    `source "kafka-1": invalid type "kafka" (valid types: file, journald, socket)`,
+		},
+		{
+			name:   "invalid parser",
+			config: "[[sources]]\ntype = \"socket\"\nlisten = \"tcp://:5140\"\nparser = \"xml\"\ndataset = \"logs\"",
+			err:    `source "socket-1": invalid parser "xml" (valid parsers: text, json, docker, cri, journal)`,
+		},
+		{
+			name:   "invalid listen address",
+			config: "[[sources]]\ntype = \"socket\"\nlisten = \"http://:5140\"\ndataset = \"logs\"",
+			err:    `source "socket-1": invalid listen address "http://:5140": network must be tcp, udp or unix`,
+		},
+		{
+			name:   "missing dataset",
+			config: "[[sources]]\ntype = \"socket\"\nlisten = \"unix:///run/axiom.sock\"",
+			err:    `source "socket-1": no dataset configured`,
+		},
+		{
+			name:   "missing positions",
+			config: "[[sources]]\ntype = \"file\"\npaths = [\"*.log\"]\ndataset = \"logs\"",
+			err:    `source "file-1": positions database required for file sources`,
+		},
+		{
+			name:   "duplicate name",
+			config: "[[sources]]\nname = \"a\"\ntype = \"socket\"\nlisten = \"tcp://:1\"\ndataset = \"logs\"\n[[sources]]\nname = \"a\"\ntype = \"socket\"\nlisten = \"tcp://:2\"\ndataset = \"logs\"",
+			err:    `duplicate source name "a"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, err := parseConfig(strings.NewReader(tt.config))
+			require.NoError(t, err)
+			assert.EqualError(t, ac.validate(testConfig), tt.err)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/cli/internal/cmd/ingest"
+)
+
+const (
+	// pollInterval is the interval files are checked for new lines at.
+	pollInterval = time.Second
+	// maxLineSize is the maximum size of a line. Longer lines are split.
+	maxLineSize = 1 << 20
+)
+
+// fileSource tails the files matching a set of glob patterns. Files are
+// checked for new lines periodically. Files that appear later are read from
+// the beginning. Rotated files are read to the end before the new file is
+// picked up, and truncated files are read again from the beginning.
+type fileSource struct {
+	cfg       sourceConfig
+	positions *positions
+	status    *sourceStatus
+
+	files map[string]*tailedFile
+}
+
+func newFileSource(cfg sourceConfig, positions *positions, status *sourceStatus) *fileSource {
+	return &fileSource{
+		cfg:       cfg,
+		positions: positions,
+		status:    status,
+
+		files: make(map[string]*tailedFile),
+	}
+}
+
+// Run implements source.
+func (s *fileSource) Run(ctx context.Context, out chan<- record) error {
+	defer func() {
+		for _, tf := range s.files {
+			_ = tf.Close()
+		}
+	}()
+
+	s.prunePositions()
+
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for initial := true; ; initial = false {
+		if !s.poll(ctx, out, initial) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// poll picks up new files and reads new lines from all files. It returns false
+// if the context is canceled.
+func (s *fileSource) poll(ctx context.Context, out chan<- record, initial bool) bool {
+	for _, path := range s.glob() {
+		if _, ok := s.files[path]; ok {
+			continue
+		}
+
+		// Only files present at startup respect the configured start
+		// position. Files appearing later are read from the beginning.
+		startAtEnd := initial && s.cfg.StartAt == startAtEnd
+
+		tf, err := openTailedFile(path, s.positionKey(path), s.positions, startAtEnd)
+		if err != nil {
+			s.status.error(err)
+			continue
+		}
+
+		parser, err := ingest.NewLineParser(s.cfg.Parser, path)
+		if err != nil {
+			_ = tf.Close()
+			s.status.error(err)
+			continue
+		}
+		tf.reader = &lineReader{status: s.status, parser: parser, out: out}
+
+		s.files[path] = tf
+	}
+
+	for path, tf := range s.files {
+		gone, err := tf.Poll(ctx)
+		if ctx.Err() != nil {
+			return false
+		} else if err != nil {
+			s.status.error(err)
+		}
+		if gone {
+			_ = tf.Close()
+			delete(s.files, path)
+		}
+	}
+
+	return true
+}
+
+// glob returns the files matching the configured patterns.
+func (s *fileSource) glob() []string {
+	var paths []string
+	for _, pattern := range s.cfg.Paths {
+		// The pattern is validated when the configuration is loaded.
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// positionKey returns the key of the position of a file in the positions
+// database.
+func (s *fileSource) positionKey(path string) string {
+	return s.cfg.Name + ":" + path
+}
+
+// prunePositions deletes the positions of files of the source that no longer
+// exist.
+func (s *fileSource) prunePositions() {
+	prefix := s.cfg.Name + ":"
+	for _, key := range s.positions.Keys() {
+		if path, ok := strings.CutPrefix(key, prefix); ok {
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+				s.positions.Delete(key)
+			}
+		}
+	}
+}
+
+// tailedFile is a file being tailed.
+type tailedFile struct {
+	path   string
+	key    string
+	reader *lineReader
+
+	f    *os.File
+	info os.FileInfo
+	// offset is the offset of the first byte after the last complete line.
+	offset int64
+	// buf holds the incomplete last line read so far.
+	buf []byte
+}
+
+// openTailedFile opens the file at the given path and seeks to the stored
+// position, if any and the file is still the same. Otherwise, it starts at the
+// beginning or the end of the file.
+func openTailedFile(path, key string, positions *positions, startAtEnd bool) (*tailedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	var offset int64
+	if pos, ok := positions.Get(key); ok {
+		if (pos.FileID == 0 || pos.FileID == fileID(info)) && pos.Offset <= info.Size() {
+			offset = pos.Offset
+		}
+	} else if startAtEnd {
+		offset = info.Size()
+	}
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &tailedFile{
+		path:   path,
+		key:    key,
+		f:      f,
+		info:   info,
+		offset: offset,
+	}, nil
+}
+
+// Poll reads the lines appended to the file since the last poll. If the file
+// was rotated, the old file is read to the end and the new one is opened. It
+// returns true if the file is gone.
+func (tf *tailedFile) Poll(ctx context.Context) (bool, error) {
+	info, err := os.Stat(tf.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Read what was written before the file was removed.
+		return true, tf.read(ctx)
+	} else if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(tf.info, info) {
+		if err = tf.read(ctx); err != nil {
+			return false, err
+		}
+		return false, tf.reopen()
+	}
+
+	if info.Size() < tf.offset {
+		// The file was truncated.
+		if _, err = tf.f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		tf.offset, tf.buf = 0, nil
+	}
+
+	return false, tf.read(ctx)
+}
+
+// read reads and sends complete lines until the end of the file is reached.
+func (tf *tailedFile) read(ctx context.Context) error {
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := tf.f.Read(chunk)
+		tf.buf = append(tf.buf, chunk[:n]...)
+
+		for len(tf.buf) > 0 {
+			i := bytes.IndexByte(tf.buf, '\n')
+			if i < 0 && len(tf.buf) < maxLineSize {
+				break
+			}
+
+			var line []byte
+			if i < 0 {
+				line, tf.buf = tf.buf[:maxLineSize], tf.buf[maxLineSize:]
+				tf.offset += maxLineSize
+			} else {
+				line, tf.buf = tf.buf[:i], tf.buf[i+1:]
+				tf.offset += int64(i) + 1
+			}
+
+			event := tf.reader.Parse(bytes.TrimSuffix(line, []byte("\r")))
+			if event == nil {
+				continue
+			}
+
+			rec := record{
+				event: event,
+				key:   tf.key,
+				pos:   position{Offset: tf.offset, FileID: fileID(tf.info)},
+			}
+			if !tf.reader.Send(ctx, rec) {
+				return ctx.Err()
+			}
+		}
+
+		// Keep the buffer from growing, as the incomplete line is copied.
+		tf.buf = bytes.Clone(tf.buf)
+
+		if errors.Is(err, io.EOF) || n == 0 {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// reopen opens the file at the path, which replaced the file read so far, and
+// reads it from the beginning.
+func (tf *tailedFile) reopen() error {
+	f, err := os.Open(tf.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	_ = tf.f.Close()
+	tf.f, tf.info, tf.offset, tf.buf = f, info, 0, nil
+
+	return nil
+}
+
+// Close the file.
+func (tf *tailedFile) Close() error {
+	return tf.f.Close()
+}
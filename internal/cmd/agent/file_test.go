@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/cli/internal/cmd/ingest"
+)
+
+func TestTailedFile(t *testing.T) {
+	var (
+		ctx  = t.Context()
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "app.log")
+		out  = make(chan record, 100)
+	)
+
+	positions, err := loadPositions("")
+	require.NoError(t, err)
+
+	open := func() *tailedFile {
+		tf, err := openTailedFile(path, "app:"+path, positions, false)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = tf.Close() })
+
+		parser, err := ingest.NewLineParser(parserText, path)
+		require.NoError(t, err)
+		tf.reader = &lineReader{
+			status: &sourceStatus{name: "app", log: log.New(io.Discard, "", 0)},
+			parser: parser,
+			out:    out,
+		}
+		return tf
+	}
+
+	// poll returns the messages read and the position of the last one.
+	poll := func(tf *tailedFile) ([]string, position) {
+		gone, err := tf.Poll(ctx)
+		require.NoError(t, err)
+		require.False(t, gone)
+
+		var (
+			msgs []string
+			pos  position
+		)
+		for len(out) > 0 {
+			rec := <-out
+			msgs = append(msgs, rec.event["message"].(string))
+			pos = rec.pos
+		}
+		return msgs, pos
+	}
+
+	appendFile := func(path, s string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = f.WriteString(s)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	appendFile(path, "one\ntwo\r\nthr")
+	tf := open()
+
+	msgs, pos := poll(tf)
+	assert.Equal(t, []string{"one", "two"}, msgs)
+	assert.EqualValues(t, 9, pos.Offset)
+
+	// The incomplete line is read once it is completed.
+	appendFile(path, "ee\n")
+	msgs, pos = poll(tf)
+	assert.Equal(t, []string{"three"}, msgs)
+	assert.EqualValues(t, 15, pos.Offset)
+
+	// Resume from a stored position.
+	positions.Set("app:"+path, pos)
+	appendFile(path, "four\n")
+	msgs, _ = poll(open())
+	assert.Equal(t, []string{"four"}, msgs)
+
+	// Lines written before rotation are read before the new file.
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(path+".1", "five\n")
+	appendFile(path, "six\n")
+	msgs, pos = poll(tf)
+	assert.Equal(t, []string{"four", "five"}, msgs)
+	msgs, _ = poll(tf)
+	assert.Equal(t, []string{"six"}, msgs)
+
+	// A stored position of a rotated file is not used.
+	positions.Set("app:"+path, pos)
+	msgs, _ = poll(open())
+	assert.Equal(t, []string{"six"}, msgs)
+
+	// A truncated file is read from the beginning.
+	require.NoError(t, os.Truncate(path, 0))
+	appendFile(path, "7\n")
+	msgs, pos = poll(tf)
+	assert.Equal(t, []string{"7"}, msgs)
+	assert.EqualValues(t, 2, pos.Offset)
+
+	// A removed file is gone.
+	require.NoError(t, os.Remove(path))
+	gone, err := tf.Poll(ctx)
+	require.NoError(t, err)
+	assert.True(t, gone)
+}
+
+func TestFileSource_StartAtEnd(t *testing.T) {
+	var (
+		ctx  = t.Context()
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "app.log")
+		out  = make(chan record, 100)
+	)
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o600))
+
+	positions, err := loadPositions("")
+	require.NoError(t, err)
+
+	s := newFileSource(sourceConfig{
+		Name:    "app",
+		Paths:   []string{filepath.Join(dir, "*.log")},
+		Parser:  parserText,
+		StartAt: startAtEnd,
+	}, positions, &sourceStatus{log: log.New(io.Discard, "", 0)})
+	defer func() {
+		for _, tf := range s.files {
+			_ = tf.Close()
+		}
+	}()
+
+	require.True(t, s.poll(ctx, out, true))
+	assert.Empty(t, out)
+
+	// Files appearing later are read from the beginning.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.log"), []byte("new\n"), 0o600))
+	require.True(t, s.poll(ctx, out, false))
+	require.Len(t, out, 1)
+	assert.Equal(t, "new", (<-out).event["message"])
+
+	// Reading stops when the context is canceled.
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, s.poll(ctx, make(chan record), false))
+}
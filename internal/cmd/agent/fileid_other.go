@@ -0,0 +1,17 @@
+//go:build !windows
+
+package agent
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the inode number of a file. It identifies a file across
+// restarts of the agent, e.g. to detect a file was rotated meanwhile.
+func fileID(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino) //nolint:unconvert // Not an uint64 on all platforms.
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// health serves the health endpoint. It reports the status of all sources and
+// destinations. The agent is unhealthy if shipping to any destination fails.
+type health struct {
+	started  time.Time
+	sources  []*sourceStatus
+	shippers []*shipper
+}
+
+// healthResponse is the response of the health endpoint.
+type healthResponse struct {
+	Status       string         `json:"status"`
+	Uptime       string         `json:"uptime"`
+	Sources      []sourceStats  `json:"sources"`
+	Destinations []shipperStats `json:"destinations"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	res := healthResponse{
+		Status:       "ok",
+		Uptime:       time.Since(h.started).Round(time.Second).String(),
+		Sources:      make([]sourceStats, len(h.sources)),
+		Destinations: make([]shipperStats, len(h.shippers)),
+	}
+	for i, source := range h.sources {
+		res.Sources[i] = source.Stats()
+	}
+	for i, shipper := range h.shippers {
+		if res.Destinations[i] = shipper.Stats(); !res.Destinations[i].Healthy {
+			res.Status = "failing"
+		}
+	}
+
+	code := http.StatusOK
+	if res.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if r.Method == http.MethodGet {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+	}
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	source := &sourceStatus{name: "app", typ: sourceFile, dest: "logs"}
+	source.events.Add(3)
+
+	s := newShipper("logs", nil, nil, &agentConfig{BatchSize: 1}, nil)
+	h := &health{
+		started:  time.Now(),
+		sources:  []*sourceStatus{source},
+		shippers: []*shipper{s},
+	}
+
+	get := func() (int, healthResponse) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		var res healthResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return rec.Code, res
+	}
+
+	code, res := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", res.Status)
+	assert.Equal(t, []sourceStats{{Name: "app", Type: sourceFile, Destination: "logs", Events: 3}}, res.Sources)
+
+	s.failed(errors.New("unavailable"))
+
+	code, res = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "failing", res.Status)
+	assert.Equal(t, "unavailable", res.Destinations[0].LastError)
+}
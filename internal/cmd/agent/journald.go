@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/cli/safeexec"
+
+	"github.com/axiomhq/cli/internal/cmd/ingest"
+)
+
+// journalRestartDelay is the time waited before journalctl is restarted after
+// it exited.
+const journalRestartDelay = 5 * time.Second
+
+// journaldSource follows the systemd journal by running "journalctl --follow"
+// in the journal JSON format. Running journalctl, instead of reading the
+// journal files directly, keeps the agent a static binary. The cursor of the
+// last entry shipped is stored as the position.
+type journaldSource struct {
+	cfg       sourceConfig
+	positions *positions
+	status    *sourceStatus
+
+	// cursor of the last entry read.
+	cursor string
+}
+
+func newJournaldSource(cfg sourceConfig, positions *positions, status *sourceStatus) *journaldSource {
+	return &journaldSource{
+		cfg:       cfg,
+		positions: positions,
+		status:    status,
+	}
+}
+
+// Run implements source.
+func (s *journaldSource) Run(ctx context.Context, out chan<- record) error {
+	exe, err := safeexec.LookPath("journalctl")
+	if err != nil {
+		return fmt.Errorf("journalctl not found: %w", err)
+	}
+
+	if pos, ok := s.positions.Get(s.cfg.Name); ok {
+		s.cursor = pos.Cursor
+	}
+
+	parser, err := ingest.NewLineParser(s.cfg.Parser, "")
+	if err != nil {
+		return err
+	}
+	reader := &lineReader{status: s.status, parser: parser, out: out}
+
+	for {
+		if err = s.follow(ctx, exe, reader); ctx.Err() != nil {
+			return nil
+		}
+		s.status.error(fmt.Errorf("journalctl exited, restarting in %s: %w", journalRestartDelay, err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(journalRestartDelay):
+		}
+	}
+}
+
+// follow runs journalctl and reads its output until it exits or the context is
+// canceled.
+func (s *journaldSource) follow(ctx context.Context, exe string, reader *lineReader) error {
+	cmd := exec.CommandContext(ctx, exe, s.args()...)
+	cmd.WaitDelay = time.Second
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	// Stop reading once the context is canceled, even if the output is held
+	// open by a child of journalctl.
+	stop := context.AfterFunc(ctx, func() { _ = stdout.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		event := reader.Parse(scanner.Bytes())
+		if event == nil {
+			continue
+		}
+
+		cursor, _ := event["__CURSOR"].(string)
+		rec := record{
+			event: event,
+			key:   s.cfg.Name,
+			pos:   position{Cursor: cursor},
+		}
+		if !reader.Send(ctx, rec) {
+			break
+		} else if cursor != "" {
+			s.cursor = cursor
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return err
+	}
+	return cmd.Wait()
+}
+
+// args returns the arguments to run journalctl with. It continues after the
+// last entry read, if any. Otherwise, it starts at the configured position.
+func (s *journaldSource) args() []string {
+	args := []string{"--follow", "--output=json", "--no-pager", "--quiet"}
+	if s.cursor != "" {
+		args = append(args, "--after-cursor="+s.cursor)
+	} else if s.cfg.StartAt == startAtEnd {
+		args = append(args, "--lines=0")
+	} else {
+		args = append(args, "--lines=all")
+	}
+	for _, unit := range s.cfg.Units {
+		args = append(args, "--unit="+unit)
+	}
+	return args
+}
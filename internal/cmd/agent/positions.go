@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// A position is how far a source has been shipped. For files, it is the
+// offset of the first byte not shipped, along with the identity of the file.
+// For the journal, it is the cursor of the last entry shipped.
+type position struct {
+	Offset int64  `json:"offset,omitempty"`
+	FileID uint64 `json:"file_id,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// positions is the persistent positions database. Positions are only updated
+// once the events read up to them are shipped, so nothing is lost when the
+// agent restarts. The database is written atomically.
+type positions struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]position
+	dirty   bool
+}
+
+// positionsFile is the on-disk format of the positions database.
+type positionsFile struct {
+	Version   int                 `json:"version"`
+	Positions map[string]position `json:"positions"`
+}
+
+// loadPositions loads the positions database at the given path. A database
+// that doesn't exist yet is created on the first save. If the path is empty,
+// positions are not persisted.
+func loadPositions(path string) (*positions, error) {
+	p := &positions{
+		path:    path,
+		entries: make(map[string]position),
+	}
+	if path == "" {
+		return p, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var pf positionsFile
+	if err = json.Unmarshal(b, &pf); err != nil {
+		return nil, fmt.Errorf("malformed positions database %s: %w", path, err)
+	} else if pf.Version != 1 {
+		return nil, fmt.Errorf("unsupported version %d of positions database %s", pf.Version, path)
+	}
+	if pf.Positions != nil {
+		p.entries = pf.Positions
+	}
+
+	return p, nil
+}
+
+// Get returns the stored position for the given key.
+func (p *positions) Get(key string) (position, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pos, ok := p.entries[key]
+	return pos, ok
+}
+
+// Keys returns the keys of all stored positions.
+func (p *positions) Keys() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Collect(maps.Keys(p.entries))
+}
+
+// Set the position for the given key.
+func (p *positions) Set(key string, pos position) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.entries[key] != pos {
+		p.entries[key] = pos
+		p.dirty = true
+	}
+}
+
+// Delete the position for the given key.
+func (p *positions) Delete(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.entries[key]; ok {
+		delete(p.entries, key)
+		p.dirty = true
+	}
+}
+
+// Save writes the positions database, if it changed since the last save. It
+// writes to a temporary file first, which is then renamed, so the database is
+// never left half-written.
+func (p *positions) Save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.path == "" || !p.dirty {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(positionsFile{
+		Version:   1,
+		Positions: p.entries,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(p.path)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(p.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	} else if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	} else if err = f.Close(); err != nil {
+		return err
+	} else if err = os.Rename(f.Name(), p.path); err != nil {
+		return err
+	}
+
+	p.dirty = false
+
+	return nil
+}
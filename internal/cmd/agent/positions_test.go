@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "positions.json")
+
+	p, err := loadPositions(path)
+	require.NoError(t, err)
+
+	_, ok := p.Get("app:/var/log/app.log")
+	assert.False(t, ok)
+
+	// Nothing is written as long as nothing changed.
+	require.NoError(t, p.Save())
+	assert.NoFileExists(t, path)
+
+	p.Set("app:/var/log/app.log", position{Offset: 42, FileID: 7})
+	p.Set("sshd", position{Cursor: "s=1;i=2"})
+	p.Set("gone", position{Offset: 1})
+	p.Delete("gone")
+	require.NoError(t, p.Save())
+
+	p, err = loadPositions(path)
+	require.NoError(t, err)
+
+	pos, ok := p.Get("app:/var/log/app.log")
+	require.True(t, ok)
+	assert.Equal(t, position{Offset: 42, FileID: 7}, pos)
+	assert.ElementsMatch(t, []string{"app:/var/log/app.log", "sshd"}, p.Keys())
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestPositions_Malformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "positions.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":2}`), 0o600))
+
+	_, err := loadPositions(path)
+	assert.ErrorContains(t, err, "unsupported version 2")
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/ingest"
+)
+
+const (
+	// minBackoff and maxBackoff bound the time waited between attempts to
+	// ship a batch.
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// shutdownTimeout is the time given to ship the events read when the
+	// agent is stopped.
+	shutdownTimeout = 10 * time.Second
+)
+
+// A record is an event read by a source, along with the position reached by
+// reading it.
+type record struct {
+	event map[string]any
+
+	// key of the position the record advances. Empty for sources without
+	// positions.
+	key string
+	pos position
+}
+
+// ingestFunc ingests events into a dataset.
+type ingestFunc func(ctx context.Context, events []axiom.Event) (*ingest.Status, error)
+
+// A shipper ships the records of one or more sources to a dataset, in batches.
+// A batch is shipped when it is full or the flush interval elapsed. Failed
+// batches are retried until they are shipped, holding back the sources. The
+// positions of the records are only updated once they are shipped.
+type shipper struct {
+	// name is the destination, in the form "[<deployment>:]<dataset>".
+	name       string
+	ingest     ingestFunc
+	positions  *positions
+	batchSize  int
+	flushEvery time.Duration
+	log        *log.Logger
+
+	// in receives the records to ship. It is closed once all sources
+	// shipping to the dataset stopped.
+	in chan record
+
+	mu    sync.Mutex
+	stats shipperStats
+}
+
+// shipperStats are the statistics of a shipper, as reported by the health
+// endpoint.
+type shipperStats struct {
+	Name           string    `json:"name"`
+	Healthy        bool      `json:"healthy"`
+	Events         uint64    `json:"events"`
+	FailedEvents   uint64    `json:"failed_events"`
+	Batches        uint64    `json:"batches"`
+	FailedAttempts uint64    `json:"failed_attempts"`
+	LastShipped    time.Time `json:"last_shipped,omitzero"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+func newShipper(name string, ingest ingestFunc, positions *positions, ac *agentConfig, logger *log.Logger) *shipper {
+	return &shipper{
+		name:       name,
+		ingest:     ingest,
+		positions:  positions,
+		batchSize:  ac.BatchSize,
+		flushEvery: ac.FlushEvery,
+		log:        logger,
+
+		in: make(chan record, ac.BatchSize),
+
+		stats: shipperStats{
+			Name:    name,
+			Healthy: true,
+		},
+	}
+}
+
+// Stats returns the statistics of the shipper.
+func (s *shipper) Stats() shipperStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
+
+// Run ships records until its input is closed. The records received by then
+// are given a last chance to be shipped, even if the context is canceled.
+func (s *shipper) Run(ctx context.Context) {
+	t := time.NewTicker(s.flushEvery)
+	defer t.Stop()
+
+	batch := make([]record, 0, s.batchSize)
+	for {
+		select {
+		case rec, ok := <-s.in:
+			if !ok {
+				if len(batch) > 0 {
+					ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+					defer cancel()
+
+					if !s.ship(ctx, batch) {
+						s.log.Printf("Failed to ship %d events to %s before shutdown", len(batch), s.name)
+					}
+				}
+				return
+			}
+
+			if batch = append(batch, rec); len(batch) < s.batchSize {
+				continue
+			}
+		case <-t.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		// Records of a batch that isn't shipped because the agent is stopping
+		// are kept for the last attempt.
+		if s.ship(ctx, batch) {
+			batch = batch[:0]
+		}
+		t.Reset(s.flushEvery)
+	}
+}
+
+// ship the batch, retrying with exponential backoff. It returns false if the
+// context is canceled before the batch is shipped.
+func (s *shipper) ship(ctx context.Context, batch []record) bool {
+	events := make([]axiom.Event, len(batch))
+	for i, rec := range batch {
+		events[i] = rec.event
+	}
+
+	backoff := minBackoff
+	for {
+		res, err := s.ingest(ctx, events)
+		if err == nil {
+			s.shipped(res)
+			break
+		} else if ctx.Err() != nil {
+			return false
+		}
+
+		s.failed(err)
+		s.log.Printf("Failed to ship %d events to %s, retrying in %s: %s", len(events), s.name, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+
+	for _, rec := range batch {
+		if rec.key != "" {
+			s.positions.Set(rec.key, rec.pos)
+		}
+	}
+	if err := s.positions.Save(); err != nil {
+		s.log.Printf("Failed to save positions: %s", err)
+	}
+
+	return true
+}
+
+func (s *shipper) shipped(res *ingest.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Healthy = true
+	s.stats.Events += res.Ingested
+	s.stats.FailedEvents += res.Failed
+	s.stats.Batches++
+	s.stats.LastShipped = time.Now()
+
+	// Events rejected by the server are not retried.
+	if res.Failed > 0 {
+		var reason string
+		if len(res.Failures) > 0 {
+			reason = ": " + res.Failures[0].Error
+		}
+		s.stats.LastError = fmt.Sprintf("%d events rejected%s", res.Failed, reason)
+		s.log.Printf("%d events rejected by %s%s", res.Failed, s.name, reason)
+	}
+}
+
+func (s *shipper) failed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Healthy = false
+	s.stats.FailedAttempts++
+	s.stats.LastError = err.Error()
+}
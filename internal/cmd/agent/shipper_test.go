@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShipper(t *testing.T) {
+	positions, err := loadPositions("")
+	require.NoError(t, err)
+
+	var (
+		mu      sync.Mutex
+		calls   int
+		shipped []axiom.Event
+	)
+	ingestEvents := func(_ context.Context, events []axiom.Event) (*ingest.Status, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// The first attempt fails, positions must not be updated by then.
+		if calls++; calls == 1 {
+			_, ok := positions.Get("app")
+			assert.False(t, ok)
+			return nil, errors.New("unavailable")
+		}
+		shipped = append(shipped, events...)
+		return &ingest.Status{Ingested: uint64(len(events))}, nil
+	}
+
+	s := newShipper("logs", ingestEvents, positions, &agentConfig{
+		FlushEvery: time.Hour,
+		BatchSize:  2,
+	}, log.New(io.Discard, "", 0))
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(t.Context())
+		close(done)
+	}()
+
+	// A full batch is shipped right away, after being retried.
+	s.in <- record{event: map[string]any{"n": 1}, key: "app", pos: position{Offset: 2}}
+	s.in <- record{event: map[string]any{"n": 2}, key: "app", pos: position{Offset: 4}}
+	assert.Eventually(t, func() bool {
+		pos, _ := positions.Get("app")
+		return pos.Offset == 4
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The remaining records are shipped when the input is closed.
+	s.in <- record{event: map[string]any{"n": 3}}
+	close(s.in)
+	<-done
+
+	assert.Equal(t, []axiom.Event{{"n": 1}, {"n": 2}, {"n": 3}}, shipped)
+
+	stats := s.Stats()
+	assert.True(t, stats.Healthy)
+	assert.EqualValues(t, 3, stats.Events)
+	assert.EqualValues(t, 2, stats.Batches)
+	assert.EqualValues(t, 1, stats.FailedAttempts)
+	assert.Equal(t, "unavailable", stats.LastError)
+}
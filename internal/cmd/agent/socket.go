@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/axiomhq/cli/internal/cmd/ingest"
+)
+
+// maxDatagramSize is the maximum size of a UDP datagram.
+const maxDatagramSize = 64 * 1024
+
+// socketSource receives lines on a TCP, UDP or unix socket, e.g. from syslog
+// daemons or applications logging to the network. Every line of a stream or a
+// datagram is parsed separately. Sockets have no positions, so lines received
+// but not yet shipped when the agent stops are lost.
+type socketSource struct {
+	cfg    sourceConfig
+	status *sourceStatus
+}
+
+func newSocketSource(cfg sourceConfig, status *sourceStatus) *socketSource {
+	return &socketSource{
+		cfg:    cfg,
+		status: status,
+	}
+}
+
+// Run implements source.
+func (s *socketSource) Run(ctx context.Context, out chan<- record) error {
+	network, addr, err := parseListenAddr(s.cfg.Listen)
+	if err != nil {
+		return err
+	}
+
+	if network == "udp" {
+		return s.runPacket(ctx, network, addr, out)
+	}
+	return s.runStream(ctx, network, addr, out)
+}
+
+func (s *socketSource) runStream(ctx context.Context, network, addr string, out chan<- record) error {
+	// Remove the socket file left behind by a previous run.
+	if network == "unix" {
+		if fi, err := os.Stat(addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(addr)
+		}
+	}
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, network, addr)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	stop := context.AfterFunc(ctx, func() { _ = ln.Close() })
+	defer stop()
+
+	for {
+		conn, err := ln.Accept()
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		wg.Go(func() { s.handleConn(ctx, conn, out) })
+	}
+}
+
+// handleConn reads lines from a connection until it is closed or the context
+// is canceled.
+func (s *socketSource) handleConn(ctx context.Context, conn net.Conn, out chan<- record) {
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	// Every connection gets its own parser, as parsers might keep state
+	// between lines.
+	parser, err := ingest.NewLineParser(s.cfg.Parser, conn.RemoteAddr().String())
+	if err != nil {
+		s.status.error(err)
+		return
+	}
+	reader := &lineReader{status: s.status, parser: parser, out: out}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSuffix(scanner.Bytes(), []byte("\r"))
+		if event := reader.Parse(line); event != nil && !reader.Send(ctx, record{event: event}) {
+			return
+		}
+	}
+	if err = scanner.Err(); err != nil && ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+		s.status.error(err)
+	}
+}
+
+func (s *socketSource) runPacket(ctx context.Context, network, addr string, out chan<- record) error {
+	var lc net.ListenConfig
+	conn, err := lc.ListenPacket(ctx, network, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	parser, err := ingest.NewLineParser(s.cfg.Parser, addr)
+	if err != nil {
+		return err
+	}
+	reader := &lineReader{status: s.status, parser: parser, out: out}
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		for line := range bytes.Lines(buf[:n]) {
+			line = bytes.TrimRight(line, "\r\n")
+			if event := reader.Parse(line); event != nil && !reader.Send(ctx, record{event: event}) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/axiomhq/cli/internal/cmd/ingest"
+)
+
+// A source reads events and sends them to a shipper, until the context is
+// canceled.
+type source interface {
+	Run(ctx context.Context, out chan<- record) error
+}
+
+// sourceStatus is the status of a source, shared by all its readers.
+type sourceStatus struct {
+	name string
+	typ  string
+	dest string
+	log  *log.Logger
+
+	events atomic.Uint64
+	errors atomic.Uint64
+
+	mu        sync.Mutex
+	lastError string
+}
+
+// sourceStats are the statistics of a source, as reported by the health
+// endpoint.
+type sourceStats struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Destination string `json:"destination"`
+	Events      uint64 `json:"events"`
+	Errors      uint64 `json:"errors"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// Stats returns the statistics of the source.
+func (s *sourceStatus) Stats() sourceStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sourceStats{
+		Name:        s.name,
+		Type:        s.typ,
+		Destination: s.dest,
+		Events:      s.events.Load(),
+		Errors:      s.errors.Load(),
+		LastError:   s.lastError,
+	}
+}
+
+// error records and logs an error the source recovered from.
+func (s *sourceStatus) error(err error) {
+	s.errors.Add(1)
+	s.log.Printf("Source %s: %s", s.name, err)
+
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.mu.Unlock()
+}
+
+// lineReader parses lines and sends the resulting events to a shipper. Lines
+// that fail to parse are skipped.
+type lineReader struct {
+	status *sourceStatus
+	parser ingest.LineParser
+	out    chan<- record
+}
+
+// Parse the line. It returns nil if the line doesn't complete an event or
+// fails to parse.
+func (lr *lineReader) Parse(line []byte) map[string]any {
+	event, err := lr.parser.ParseLine(line)
+	if err != nil {
+		lr.status.error(err)
+		return nil
+	}
+	return event
+}
+
+// Send the record to the shipper. It returns false if the context is canceled
+// before the record is sent.
+func (lr *lineReader) Send(ctx context.Context, rec record) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case lr.out <- rec:
+		lr.status.events.Add(1)
+		return true
+	}
+}
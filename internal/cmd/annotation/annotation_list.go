@@ -30,6 +30,8 @@ type listOptions struct {
 
 	// Format to output data in. Defaults to tabular output.
 	Format string
+	// Transform shapes the output using a template or jq expression.
+	Transform iofmt.Transform
 }
 
 func newListCmd(f *cmdutil.Factory) *cobra.Command {
@@ -38,7 +40,7 @@ func newListCmd(f *cmdutil.Factory) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:   "list [(-f|--format)=json|table] [(-d|--datasets) <datasets>] [(-start-time) <start-time>] [(--end-time) <end-time>",
+		Use:   "list [(-f|--format) <format> | (-t|--template) <template> | (-q|--jq) <expression>] [(-d|--datasets) <datasets>] [(-start-time) <start-time>] [(--end-time) <end-time>",
 		Short: "List all annotations",
 
 		Aliases: []string{"ls"},
@@ -46,6 +48,9 @@ func newListCmd(f *cmdutil.Factory) *cobra.Command {
 		Example: heredoc.Doc(`
 			# List all annotations:
 			$ axiom annotations list
+
+			# List the titles of all deployment annotations:
+			$ axiom annotations list --jq '.[] | select(.type == "deploy") | .title'
 		`),
 
 		RunE: func(cmd *cobra.Command, _ []string) error {
@@ -63,10 +68,23 @@ func newListCmd(f *cmdutil.Factory) *cobra.Command {
 	_ = cmd.RegisterFlagCompletionFunc("start-time", cmdutil.FormatCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("end-time", cmdutil.FormatCompletion)
 
+	cmdutil.AddTransformFlags(cmd, &opts.Transform)
+
 	return cmd
 }
 
+// annotationFields are the fields of an annotation in tabular output formats.
+var annotationFields = []string{"id", "type", "datasets", "title", "description", "url", "time", "endTime"}
+
 func runList(ctx context.Context, opts *listOptions) error {
+	format, err := cmdutil.ParseFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	if err = cmdutil.CompileTransform(opts.IO, &opts.Transform); err != nil {
+		return err
+	}
+
 	client, err := opts.Client(ctx)
 	if err != nil {
 		return err
@@ -113,8 +131,10 @@ func runList(ctx context.Context, opts *listOptions) error {
 	}
 	defer pagerStop()
 
-	if opts.Format == iofmt.JSON.String() {
-		return iofmt.FormatToJSON(opts.IO.Out(), annotations, opts.IO.ColorEnabled())
+	if opts.Transform.IsSet() {
+		return opts.Transform.Write(opts.IO.Out(), annotations, opts.IO.ColorEnabled())
+	} else if format != iofmt.Table {
+		return iofmt.FormatRecords(opts.IO.Out(), format, annotationFields, annotations, opts.IO.ColorEnabled())
 	}
 
 	if len(annotations) == 0 {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"sync"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/spf13/cobra"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/axiomhq/cli/internal/client"
 	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/pkg/iofmt"
 	"github.com/axiomhq/cli/pkg/utils"
 )
 
@@ -20,6 +20,8 @@ type statusOptions struct {
 
 	// Alias of the deployment to check the authentication status for.
 	Alias string
+	// Transform shapes the output using a template or jq expression.
+	Transform iofmt.Transform
 }
 
 func newStatusCmd(f *cmdutil.Factory) *cobra.Command {
@@ -28,7 +30,7 @@ func newStatusCmd(f *cmdutil.Factory) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:   "status [<alias>]",
+		Use:   "status [<alias>] [(-t|--template) <template> | (-q|--jq) <expression>]",
 		Short: "View authentication status",
 
 		DisableFlagsInUseLine: true,
@@ -42,6 +44,9 @@ func newStatusCmd(f *cmdutil.Factory) *cobra.Command {
 			
 			# Check authentication status of a specified deployment:
 			$ axiom auth status axiom-eu-west-1
+
+			# Print the aliases of all deployments that fail to authenticate:
+			$ axiom auth status --jq '.[] | select(.error) | .alias'
 		`),
 
 		PreRunE: cmdutil.ChainRunFuncs(
@@ -58,10 +63,26 @@ func newStatusCmd(f *cmdutil.Factory) *cobra.Command {
 		},
 	}
 
+	cmdutil.AddTransformFlags(cmd, &opts.Transform)
+
 	return cmd
 }
 
+// deploymentStatus is the authentication status of a deployment.
+type deploymentStatus struct {
+	Alias        string `json:"alias"`
+	URL          string `json:"url"`
+	Active       bool   `json:"active"`
+	User         string `json:"user,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
 func runStatus(ctx context.Context, opts *statusOptions) error {
+	if err := cmdutil.CompileTransform(opts.IO, &opts.Transform); err != nil {
+		return err
+	}
+
 	deploymentAliases := opts.Config.DeploymentAliases()
 	if opts.Alias != "" {
 		deploymentAliases = []string{opts.Alias}
@@ -71,54 +92,49 @@ func runStatus(ctx context.Context, opts *statusOptions) error {
 	defer stop()
 
 	var (
-		cs           = opts.IO.ColorScheme()
-		statusInfo   = map[string][]string{}
-		statusInfoMu = new(sync.Mutex)
+		statuses = make([]deploymentStatus, len(deploymentAliases))
 		// We don't care about the context here. If an errors occurs, still try
 		// to get the status of the other deployments.
 		eg, _ = errgroup.WithContext(ctx)
 	)
-	for _, deploymentAlias := range deploymentAliases {
+	for i, deploymentAlias := range deploymentAliases {
+		status := &statuses[i]
+		status.Alias = deploymentAlias
+		status.Active = deploymentAlias == opts.Config.ActiveDeployment
+
 		deployment, ok := opts.Config.Deployments[deploymentAlias]
 		if !ok {
 			continue
 		}
+		status.URL = deployment.URL
 
-		eg.Go(func() error {
-			var info string
+		eg.Go(func() (err error) {
 			defer func() {
-				statusInfoMu.Lock()
-				defer statusInfoMu.Unlock()
-
-				statusInfo[deploymentAlias] = append(statusInfo[deploymentAlias], info)
+				if err != nil {
+					status.Error = err.Error()
+				}
 			}()
 
 			client, err := client.New(ctx, deployment.URL, deployment.Token, deployment.OrganizationID, "", "", opts.Config.Insecure)
 			if err != nil {
-				info = fmt.Sprintf("%s %s", cs.ErrorIcon(), err)
 				return err
 			}
 
 			user, err := client.Users.Current(ctx)
 			if err != nil {
-				info = fmt.Sprintf("%s %s", cs.ErrorIcon(), err)
 				return err
 			}
+			status.User = user.Name
 
 			if deployment.OrganizationID == "" {
-				info = fmt.Sprintf("%s Logged in as %s", cs.SuccessIcon(),
-					cs.Bold(user.Name))
 				return nil
 			}
 
 			organization, err := client.Organizations.Get(ctx, deployment.OrganizationID)
 			if err != nil {
-				info = fmt.Sprintf("%s %s", cs.ErrorIcon(), err)
 				return err
 			}
-
-			info = fmt.Sprintf("%s Logged in to %s as %s", cs.SuccessIcon(),
-				cs.Bold(organization.Name), cs.Bold(user.Name))
+			status.Organization = organization.Name
 
 			return nil
 		})
@@ -131,21 +147,45 @@ func runStatus(ctx context.Context, opts *statusOptions) error {
 
 	stop()
 
+	if opts.Transform.IsSet() {
+		if err := opts.Transform.Write(opts.IO.Out(), statuses, opts.IO.ColorEnabled()); err != nil {
+			return err
+		}
+		if failed {
+			return cmdutil.ErrSilent
+		}
+		return nil
+	}
+
+	cs := opts.IO.ColorScheme()
+
 	var buf strings.Builder
-	for _, alias := range deploymentAliases {
+	for _, status := range statuses {
+		var info string
+		switch {
+		case status.Error != "":
+			info = fmt.Sprintf("%s %s", cs.ErrorIcon(), status.Error)
+		case status.Organization != "":
+			info = fmt.Sprintf("%s Logged in to %s as %s", cs.SuccessIcon(),
+				cs.Bold(status.Organization), cs.Bold(status.User))
+		case status.User != "":
+			info = fmt.Sprintf("%s Logged in as %s", cs.SuccessIcon(),
+				cs.Bold(status.User))
+		}
+
 		if opts.IO.IsStdoutTTY() {
-			if alias == opts.Config.ActiveDeployment {
-				fmt.Fprintf(&buf, "%s %s\n", cs.Yellow("➜"), cs.Bold(alias))
+			if status.Active {
+				fmt.Fprintf(&buf, "%s %s\n", cs.Yellow("➜"), cs.Bold(status.Alias))
 			} else {
-				fmt.Fprintf(&buf, "  %s\n", cs.Bold(alias))
+				fmt.Fprintf(&buf, "  %s\n", cs.Bold(status.Alias))
 			}
-			for _, line := range statusInfo[alias] {
-				fmt.Fprintf(&buf, "    %s\n", line)
+			if info != "" {
+				fmt.Fprintf(&buf, "    %s\n", info)
 			}
 		} else {
-			fmt.Fprintf(&buf, "%s\n", alias)
-			for _, line := range statusInfo[alias] {
-				fmt.Fprintf(&buf, "  %s\n", line)
+			fmt.Fprintf(&buf, "%s\n", status.Alias)
+			if info != "" {
+				fmt.Fprintf(&buf, "  %s\n", info)
 			}
 		}
 	}
@@ -19,6 +19,8 @@ type listOptions struct {
 
 	// Format to output data in. Defaults to tabular output.
 	Format string
+	// Transform shapes the output using a template or jq expression.
+	Transform iofmt.Transform
 }
 
 func newListCmd(f *cmdutil.Factory) *cobra.Command {
@@ -27,7 +29,7 @@ func newListCmd(f *cmdutil.Factory) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:   "list [(-f|--format)=json|table]",
+		Use:   "list [(-f|--format) <format> | (-t|--template) <template> | (-q|--jq) <expression>]",
 		Short: "List all datasets",
 
 		Aliases: []string{"ls"},
@@ -35,6 +37,15 @@ func newListCmd(f *cmdutil.Factory) *cobra.Command {
 		Example: heredoc.Doc(`
 			# List all datasets:
 			$ axiom dataset list
+
+			# List all datasets as CSV:
+			$ axiom dataset list --format csv
+
+			# List the names of all datasets:
+			$ axiom dataset list --template '{{range .}}{{.name}}{{"\n"}}{{end}}'
+
+			# List the names of all datasets without a description:
+			$ axiom dataset list --jq '.[] | select(.description == "") | .name'
 		`),
 
 		PreRunE: cmdutil.NeedsDatasets(f),
@@ -48,10 +59,23 @@ func newListCmd(f *cmdutil.Factory) *cobra.Command {
 
 	_ = cmd.RegisterFlagCompletionFunc("format", cmdutil.FormatCompletion)
 
+	cmdutil.AddTransformFlags(cmd, &opts.Transform)
+
 	return cmd
 }
 
+// datasetFields are the fields of a dataset in tabular output formats.
+var datasetFields = []string{"name", "kind", "description", "who", "created"}
+
 func runList(ctx context.Context, opts *listOptions) error {
+	format, err := cmdutil.ParseFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	if err = cmdutil.CompileTransform(opts.IO, &opts.Transform); err != nil {
+		return err
+	}
+
 	client, err := opts.Client(ctx)
 	if err != nil {
 		return err
@@ -73,8 +97,10 @@ func runList(ctx context.Context, opts *listOptions) error {
 	}
 	defer pagerStop()
 
-	if opts.Format == iofmt.JSON.String() {
-		return iofmt.FormatToJSON(opts.IO.Out(), datasets, opts.IO.ColorEnabled())
+	if opts.Transform.IsSet() {
+		return opts.Transform.Write(opts.IO.Out(), datasets, opts.IO.ColorEnabled())
+	} else if format != iofmt.Table {
+		return iofmt.FormatRecords(opts.IO.Out(), format, datasetFields, datasets, opts.IO.ColorEnabled())
 	}
 
 	cs := opts.IO.ColorScheme()
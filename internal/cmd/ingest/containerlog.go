@@ -0,0 +1,227 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Client-side input formats of container logs.
+const (
+	// formatDocker is the format of the Docker json-file logging driver.
+	formatDocker = "docker"
+	// formatCRI is the format of the Container Runtime Interface, as used by
+	// Kubernetes nodes.
+	formatCRI = "cri"
+)
+
+// podLogPathRe matches the path of a container log file written by the
+// kubelet: /var/log/pods/<namespace>_<pod>_<pod-uid>/<container>/<n>.log. Log
+// files rotated by the kubelet carry an additional suffix.
+var podLogPathRe = regexp.MustCompile(`(?:^|/)pods/([^_/]+)_([^_/]+)_([^_/]+)/([^/]+)/[^/]+\.log(?:\.[^/]*)?$`)
+
+// A containerLogEntry is a single line of a container log.
+type containerLogEntry struct {
+	// Time the line was written at.
+	Time string
+	// Stream the line was written to, "stdout" or "stderr".
+	Stream string
+	// Message is the line, without the trailing newline.
+	Message string
+	// Partial is true if the line was split by the container runtime and
+	// continues in the following entry of the same stream.
+	Partial bool
+}
+
+// containerLogEventReader reads events from container logs, line by line.
+type containerLogEventReader struct {
+	r      *bufio.Reader
+	parser *containerLogParser
+}
+
+func newContainerLogEventReader(r io.Reader, format, name string) *containerLogEventReader {
+	return &containerLogEventReader{
+		r:      bufio.NewReader(r),
+		parser: newContainerLogParser(format, name),
+	}
+}
+
+// ReadEvent implements eventReader.
+func (er *containerLogEventReader) ReadEvent() (map[string]any, error) {
+	for {
+		line, err := er.r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			if event, ok := er.parser.flushPartial(); ok {
+				return event, nil
+			}
+			return nil, io.EOF
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		event, err := er.parser.ParseLine(bytes.TrimRight(line, "\r\n"))
+		if err != nil {
+			return nil, err
+		} else if event != nil {
+			return event, nil
+		}
+	}
+}
+
+// containerLogParser parses container log lines. Lines split by the container
+// runtime are joined. If a line is a JSON object, its fields become event
+// fields. Otherwise, the line is kept in the "message" field. The fields
+// "_time" and "stream" are set from the container runtime. For logs read from
+// a kubelet log file, the namespace, pod and container are added to the
+// "kubernetes" field.
+type containerLogParser struct {
+	parse func([]byte) (containerLogEntry, error)
+
+	// kubernetes is the Kubernetes metadata derived from the file path, if
+	// any.
+	kubernetes map[string]any
+	// partials are the partial entries read so far, by stream.
+	partials map[string]*containerLogEntry
+}
+
+func newContainerLogParser(format, name string) *containerLogParser {
+	p := &containerLogParser{
+		kubernetes: kubernetesFromPath(name),
+		partials:   make(map[string]*containerLogEntry),
+	}
+	if format == formatDocker {
+		p.parse = parseDockerLogLine
+	} else {
+		p.parse = parseCRILogLine
+	}
+	return p
+}
+
+// ParseLine implements LineParser.
+func (p *containerLogParser) ParseLine(line []byte) (map[string]any, error) {
+	if len(line) == 0 {
+		return nil, nil
+	}
+
+	entry, err := p.parse(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if partial, ok := p.partials[entry.Stream]; ok {
+		partial.Message += entry.Message
+		partial.Partial = entry.Partial
+		if entry.Partial {
+			return nil, nil
+		}
+		delete(p.partials, entry.Stream)
+		entry = *partial
+	} else if entry.Partial {
+		p.partials[entry.Stream] = &entry
+		return nil, nil
+	}
+
+	return p.event(entry), nil
+}
+
+// flushPartial returns an event for a partial entry that is never completed
+// because the input ended. It returns false if there are none left.
+func (p *containerLogParser) flushPartial() (map[string]any, bool) {
+	if len(p.partials) == 0 {
+		return nil, false
+	}
+
+	stream := slices.Min(slices.Collect(maps.Keys(p.partials)))
+	partial := p.partials[stream]
+	delete(p.partials, stream)
+
+	return p.event(*partial), true
+}
+
+func (p *containerLogParser) event(entry containerLogEntry) map[string]any {
+	event := structuredMessage(entry.Message)
+	event["_time"] = entry.Time
+	event["stream"] = entry.Stream
+	if p.kubernetes != nil {
+		event["kubernetes"] = maps.Clone(p.kubernetes)
+	}
+
+	return event
+}
+
+// dockerLogLine is a line written by the Docker json-file logging driver.
+type dockerLogLine struct {
+	Log    *string `json:"log"`
+	Stream string  `json:"stream"`
+	Time   string  `json:"time"`
+}
+
+// parseDockerLogLine parses a line in the format of the Docker json-file
+// logging driver: {"log":"<message>\n","stream":"stdout","time":"<time>"}.
+// Lines split by Docker don't end with a newline.
+func parseDockerLogLine(line []byte) (containerLogEntry, error) {
+	var l dockerLogLine
+	if err := json.Unmarshal(line, &l); err != nil {
+		return containerLogEntry{}, fmt.Errorf("malformed Docker log line: %w", err)
+	} else if l.Log == nil {
+		return containerLogEntry{}, errors.New(`malformed Docker log line: missing "log" field`)
+	}
+
+	msg, full := strings.CutSuffix(*l.Log, "\n")
+	return containerLogEntry{
+		Time:    l.Time,
+		Stream:  l.Stream,
+		Message: msg,
+		Partial: !full,
+	}, nil
+}
+
+// parseCRILogLine parses a line in the CRI logging format:
+// "<time> <stream> <tags> <message>". The first of the colon separated tags is
+// "P" for lines split by the container runtime and "F" otherwise.
+func parseCRILogLine(line []byte) (containerLogEntry, error) {
+	parts := strings.SplitN(string(line), " ", 4)
+	if len(parts) < 3 {
+		return containerLogEntry{}, fmt.Errorf("malformed CRI log line: %q", line)
+	}
+
+	entry := containerLogEntry{
+		Time:   parts[0],
+		Stream: parts[1],
+	}
+	if len(parts) == 4 {
+		entry.Message = parts[3]
+	}
+
+	switch tag, _, _ := strings.Cut(parts[2], ":"); tag {
+	case "P":
+		entry.Partial = true
+	case "F":
+	default:
+		return containerLogEntry{}, fmt.Errorf("malformed CRI log line: invalid tag %q", parts[2])
+	}
+
+	return entry, nil
+}
+
+// kubernetesFromPath derives the namespace, pod and container from the path of
+// a log file written by the kubelet. It returns nil for other paths.
+func kubernetesFromPath(path string) map[string]any {
+	m := podLogPathRe.FindStringSubmatch(path)
+	if m == nil {
+		return nil
+	}
+	return map[string]any{
+		"namespace": m[1],
+		"pod":       m[2],
+		"pod_uid":   m[3],
+		"container": m[4],
+	}
+}
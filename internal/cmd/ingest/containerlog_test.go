@@ -0,0 +1,98 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerLogEventReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		path   string
+		input  string
+		want   []map[string]any
+	}{
+		{
+			name:   "docker",
+			format: formatDocker,
+			input: `{"log":"hello\n","stream":"stdout","time":"2024-01-01T00:00:00.1Z"}` + "\n" +
+				`{"log":"part one, ","stream":"stderr","time":"2024-01-01T00:00:01Z"}` + "\n" +
+				`{"log":"{\"level\":\"info\",\"status\":200}\n","stream":"stdout","time":"2024-01-01T00:00:02Z"}` + "\n" +
+				`{"log":"part two\n","stream":"stderr","time":"2024-01-01T00:00:03Z"}` + "\n",
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00.1Z", "stream": "stdout", "message": "hello"},
+				{"_time": "2024-01-01T00:00:02Z", "stream": "stdout", "level": "info", "status": json.Number("200")},
+				{"_time": "2024-01-01T00:00:01Z", "stream": "stderr", "message": "part one, part two"},
+			},
+		},
+		{
+			name:   "cri",
+			format: formatCRI,
+			path:   "/var/log/pods/default_web-7d4b9_0f1e2d3c-aaaa-bbbb-cccc-123456789abc/nginx/0.log",
+			input: "2024-01-01T00:00:00.123456789Z stdout F hello world\n" +
+				"2024-01-01T00:00:01Z stdout P {\"msg\":\n" +
+				"2024-01-01T00:00:01Z stdout P \"split\"\n" +
+				"2024-01-01T00:00:01Z stdout F }\n" +
+				"2024-01-01T00:00:02Z stderr F\n",
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00.123456789Z", "stream": "stdout", "message": "hello world", "kubernetes": testPodMetadata},
+				{"_time": "2024-01-01T00:00:01Z", "stream": "stdout", "msg": "split", "kubernetes": testPodMetadata},
+				{"_time": "2024-01-01T00:00:02Z", "stream": "stderr", "message": "", "kubernetes": testPodMetadata},
+			},
+		},
+		{
+			name:   "cri unterminated partial",
+			format: formatCRI,
+			input:  "2024-01-01T00:00:00Z stdout P incomplete\n",
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00Z", "stream": "stdout", "message": "incomplete"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			er := newContainerLogEventReader(strings.NewReader(tt.input), tt.format, tt.path)
+
+			var got []map[string]any
+			for {
+				event, err := er.ReadEvent()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, event)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+var testPodMetadata = map[string]any{
+	"namespace": "default",
+	"pod":       "web-7d4b9",
+	"pod_uid":   "0f1e2d3c-aaaa-bbbb-cccc-123456789abc",
+	"container": "nginx",
+}
+
+func TestContainerLogEventReader_Malformed(t *testing.T) {
+	_, err := newContainerLogEventReader(strings.NewReader("2024-01-01T00:00:00Z stdout X msg\n"), formatCRI, "").ReadEvent()
+	assert.ErrorContains(t, err, "invalid tag")
+
+	_, err = newContainerLogEventReader(strings.NewReader(`{"stream":"stdout"}`), formatDocker, "").ReadEvent()
+	assert.ErrorContains(t, err, "missing \"log\" field")
+}
+
+func TestKubernetesFromPath(t *testing.T) {
+	assert.Equal(t, testPodMetadata, kubernetesFromPath("/var/log/pods/default_web-7d4b9_0f1e2d3c-aaaa-bbbb-cccc-123456789abc/nginx/0.log"))
+	assert.Equal(t, testPodMetadata, kubernetesFromPath("s3://bucket/node-1/pods/default_web-7d4b9_0f1e2d3c-aaaa-bbbb-cccc-123456789abc/nginx/1.log.20240101-000000.gz"))
+	assert.Nil(t, kubernetesFromPath("/var/log/containers/web.log"))
+	assert.Nil(t, kubernetesFromPath("stdin"))
+}
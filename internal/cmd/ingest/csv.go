@@ -0,0 +1,234 @@
+package ingest
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/araddon/dateparse"
+)
+
+// Types of CSV columns converted client-side.
+const (
+	csvString    = "string"
+	csvInt       = "int"
+	csvFloat     = "float"
+	csvBool      = "bool"
+	csvTimestamp = "timestamp"
+)
+
+var validCSVTypes = []string{
+	csvString,
+	csvInt,
+	csvFloat,
+	csvBool,
+	csvTimestamp,
+}
+
+// csvEventReader reads events from CSV data. Column values are converted to
+// their column type, which is either given or inferred from a sample of the
+// first rows. Empty values and "null" are converted to null. Values that can't
+// be converted to their column type are kept as strings.
+type csvEventReader struct {
+	r *csv.Reader
+
+	// fields are the field names of the columns. Read from the header row, if
+	// not given.
+	fields []string
+	// overrides maps field names to their explicitly given column type.
+	overrides map[string]string
+	// sampleSize is the number of rows to infer the column types from. If
+	// zero, the types of all columns without an override are string.
+	sampleSize int
+
+	types  []string
+	sample [][]string
+	init   bool
+}
+
+func newCSVEventReader(r io.Reader, delimiter string, fields []string, overrides map[string]string, sampleSize int) (*csvEventReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	if delimiter != "" {
+		if utf8.RuneCountInString(delimiter) != 1 {
+			return nil, fmt.Errorf("invalid CSV delimiter %q: must be a single character", delimiter)
+		}
+		cr.Comma, _ = utf8.DecodeRuneInString(delimiter)
+	}
+
+	return &csvEventReader{
+		r:          cr,
+		fields:     fields,
+		overrides:  overrides,
+		sampleSize: sampleSize,
+	}, nil
+}
+
+// ReadEvent implements eventReader.
+func (er *csvEventReader) ReadEvent() (map[string]any, error) {
+	if !er.init {
+		if err := er.initialize(); err != nil {
+			return nil, err
+		}
+		er.init = true
+	}
+
+	var record []string
+	if len(er.sample) > 0 {
+		record, er.sample = er.sample[0], er.sample[1:]
+	} else {
+		var err error
+		if record, err = er.r.Read(); err != nil {
+			return nil, err
+		}
+	}
+
+	event := make(map[string]any, len(er.fields))
+	for i, value := range record {
+		if i >= len(er.fields) {
+			break
+		}
+		event[er.fields[i]] = convertCSVValue(value, er.types[i])
+	}
+	return event, nil
+}
+
+// initialize reads the header row, if needed, and the sample and determines
+// the column types.
+func (er *csvEventReader) initialize() error {
+	if len(er.fields) == 0 {
+		header, err := er.r.Read()
+		if errors.Is(err, io.EOF) {
+			return io.EOF
+		} else if err != nil {
+			return fmt.Errorf("read CSV header: %w", err)
+		}
+		er.fields = header
+	}
+
+	for field := range er.overrides {
+		if !slices.Contains(er.fields, field) {
+			return fmt.Errorf("unknown CSV column %q", field)
+		}
+	}
+
+	for len(er.sample) < er.sampleSize {
+		record, err := er.r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		er.sample = append(er.sample, record)
+	}
+
+	er.types = make([]string, len(er.fields))
+	for i, field := range er.fields {
+		if typ, ok := er.overrides[field]; ok {
+			er.types[i] = typ
+		} else if er.sampleSize > 0 {
+			er.types[i] = inferCSVColumnType(er.sample, i)
+		} else {
+			er.types[i] = csvString
+		}
+	}
+
+	return nil
+}
+
+// inferCSVColumnType returns the narrowest type all non-null values of the
+// column at the given index in the sample can be converted to.
+func inferCSVColumnType(sample [][]string, idx int) string {
+	candidates := []string{csvInt, csvFloat, csvBool, csvTimestamp}
+	for _, record := range sample {
+		if idx >= len(record) || isCSVNull(record[idx]) {
+			continue
+		}
+		remaining := make([]string, 0, len(candidates))
+		for _, typ := range candidates {
+			if _, ok := parseCSVValue(record[idx], typ); ok {
+				remaining = append(remaining, typ)
+			}
+		}
+		if candidates = remaining; len(candidates) == 0 {
+			return csvString
+		}
+	}
+
+	// A column without any non-null values in the sample gives no hint.
+	if len(candidates) == 4 {
+		return csvString
+	}
+	return candidates[0]
+}
+
+func convertCSVValue(value, typ string) any {
+	if isCSVNull(value) {
+		return nil
+	}
+	if v, ok := parseCSVValue(value, typ); ok {
+		return v
+	}
+	return value
+}
+
+func parseCSVValue(value, typ string) (any, bool) {
+	switch typ {
+	case csvInt:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v, true
+		}
+	case csvFloat:
+		// Not a number and infinity can't be represented in JSON.
+		if v, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return v, true
+		}
+	case csvBool:
+		switch strings.ToLower(value) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	case csvTimestamp:
+		// Plain numbers are ambiguous and never inferred to be timestamps.
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return nil, false
+		}
+		if v, err := dateparse.ParseIn(value, time.UTC); err == nil {
+			return v.Format(time.RFC3339Nano), true
+		}
+	case csvString:
+		return value, true
+	}
+	return nil, false
+}
+
+func isCSVNull(value string) bool {
+	return value == "" || strings.EqualFold(value, "null")
+}
+
+// parseCSVTypes parses column type overrides in the form "<column>:<type>".
+func parseCSVTypes(ss []string) (map[string]string, error) {
+	res := make(map[string]string, len(ss))
+	for _, s := range ss {
+		field, typ, ok := strings.Cut(s, ":")
+		if !ok || field == "" {
+			return nil, fmt.Errorf("malformed CSV column type: %q", s)
+		}
+		typ = strings.ToLower(typ)
+		if !slices.Contains(validCSVTypes, typ) {
+			return nil, fmt.Errorf("invalid CSV column type %q for column %q (valid types: %s)",
+				typ, field, strings.Join(validCSVTypes, ", "))
+		}
+		res[field] = typ
+	}
+	return res, nil
+}
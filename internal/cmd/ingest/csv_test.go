@@ -0,0 +1,49 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVEventReader(t *testing.T) {
+	input := strings.Join([]string{
+		`time;status;latency;ok;msg;missing`,
+		`2024-01-02T03:04:05Z;200;1.5;true;"hello; world";`,
+		`2024-01-02 03:04:06;404;2;FALSE;"say ""hi""";null`,
+		`2024-01-02 03:04:07;oops;3;true;plain;`,
+	}, "\n")
+
+	er, err := newCSVEventReader(strings.NewReader(input), ";", nil, map[string]string{"msg": csvString}, 2)
+	require.NoError(t, err)
+
+	var events []map[string]any
+	for {
+		event, err := er.ReadEvent()
+		if err != nil {
+			break
+		}
+		events = append(events, event)
+	}
+
+	assert.Equal(t, []string{csvTimestamp, csvInt, csvFloat, csvBool, csvString, csvString}, er.types)
+	assert.Equal(t, []map[string]any{
+		{"time": "2024-01-02T03:04:05Z", "status": int64(200), "latency": 1.5, "ok": true, "msg": "hello; world", "missing": nil},
+		{"time": "2024-01-02T03:04:06Z", "status": int64(404), "latency": float64(2), "ok": false, "msg": `say "hi"`, "missing": nil},
+		{"time": "2024-01-02T03:04:07Z", "status": "oops", "latency": float64(3), "ok": true, "msg": "plain", "missing": nil},
+	}, events)
+}
+
+func TestParseCSVTypes(t *testing.T) {
+	types, err := parseCSVTypes([]string{"status:int", "ok:BOOL"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"status": csvInt, "ok": csvBool}, types)
+
+	_, err = parseCSVTypes([]string{"status"})
+	assert.EqualError(t, err, `malformed CSV column type: "status"`)
+
+	_, err = parseCSVTypes([]string{"status:integer"})
+	assert.ErrorContains(t, err, `invalid CSV column type "integer" for column "status"`)
+}
@@ -0,0 +1,236 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cli/safeexec"
+)
+
+// Enrichment presets.
+const (
+	enrichHost    = "host"
+	enrichProcess = "process"
+	enrichGit     = "git"
+	enrichK8s     = "k8s"
+	enrichCI      = "ci"
+)
+
+var validEnrichPresets = []string{
+	enrichHost,
+	enrichProcess,
+	enrichGit,
+	enrichK8s,
+	enrichCI,
+}
+
+// enricher adds fields to every event. Fields the event already has are kept.
+type enricher struct {
+	// fields are the fields to add, by the name of the field they are added
+	// under.
+	fields map[string]map[string]any
+}
+
+// newEnricher collects the fields of the given presets. Each preset adds its
+// fields under a field named after the preset. It returns the presets that
+// didn't yield any fields along with the enricher.
+func newEnricher(ctx context.Context, presets []string) (*enricher, []string, error) {
+	var (
+		fields = make(map[string]map[string]any, len(presets))
+		empty  []string
+	)
+	for _, preset := range presets {
+		var (
+			name   string
+			values map[string]any
+		)
+		switch preset = strings.ToLower(preset); preset {
+		case enrichHost:
+			name, values = "host", hostFields()
+		case enrichProcess:
+			name, values = "process", processFields()
+		case enrichGit:
+			name, values = "git", gitFields(ctx)
+		case enrichK8s:
+			name, values = "kubernetes", kubernetesFields()
+		case enrichCI:
+			name, values = "ci", ciFields()
+		default:
+			return nil, nil, fmt.Errorf("invalid enrichment preset %q (valid presets: %s)",
+				preset, strings.Join(validEnrichPresets, ", "))
+		}
+
+		if len(values) == 0 {
+			empty = append(empty, preset)
+			continue
+		}
+		fields[name] = values
+	}
+	return &enricher{fields: fields}, empty, nil
+}
+
+// Process implements processor.
+func (e *enricher) Process(event map[string]any) error {
+	for name, values := range e.fields {
+		switch existing := event[name].(type) {
+		case nil:
+			event[name] = maps.Clone(values)
+		case map[string]any:
+			for k, v := range values {
+				if _, ok := existing[k]; !ok {
+					existing[k] = v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hostFields() map[string]any {
+	fields := map[string]any{
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		fields["name"] = hostname
+	}
+	return fields
+}
+
+func processFields() map[string]any {
+	fields := map[string]any{
+		"pid":  os.Getpid(),
+		"ppid": os.Getppid(),
+	}
+	if u, err := user.Current(); err == nil {
+		fields["user"] = u.Username
+	}
+	return fields
+}
+
+// gitFields returns the repository, branch and commit of the git repository
+// the current working directory is in. It returns nil if git is not available
+// or the working directory is not in a git repository.
+func gitFields(ctx context.Context) map[string]any {
+	exe, err := safeexec.LookPath("git")
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	git := func(args ...string) string {
+		out, err := exec.CommandContext(ctx, exe, args...).Output()
+		if err != nil {
+			return ""
+		}
+		return string(bytes.TrimSpace(out))
+	}
+
+	commit := git("rev-parse", "HEAD")
+	if commit == "" {
+		return nil
+	}
+
+	fields := map[string]any{
+		"commit": commit,
+	}
+	if branch := git("rev-parse", "--abbrev-ref", "HEAD"); branch != "" && branch != "HEAD" {
+		fields["branch"] = branch
+	}
+	if repo := git("config", "--get", "remote.origin.url"); repo != "" {
+		fields["repository"] = repo
+	} else if root := git("rev-parse", "--show-toplevel"); root != "" {
+		fields["repository"] = root
+	}
+	return fields
+}
+
+// kubernetesFields returns the Kubernetes metadata exposed to a container
+// through environment variables, usually set using the downward API. The
+// namespace falls back to the one of the service account.
+func kubernetesFields() map[string]any {
+	fields := envFields(map[string][]string{
+		"namespace": {"POD_NAMESPACE", "K8S_NAMESPACE", "KUBERNETES_NAMESPACE"},
+		"pod":       {"POD_NAME", "K8S_POD_NAME", "KUBERNETES_POD_NAME"},
+		"pod_uid":   {"POD_UID", "K8S_POD_UID", "KUBERNETES_POD_UID"},
+		"pod_ip":    {"POD_IP", "K8S_POD_IP", "KUBERNETES_POD_IP"},
+		"node":      {"NODE_NAME", "K8S_NODE_NAME", "KUBERNETES_NODE_NAME"},
+		"container": {"K8S_CONTAINER_NAME", "KUBERNETES_CONTAINER_NAME"},
+	})
+
+	if _, ok := fields["namespace"]; !ok {
+		if b, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+			fields["namespace"] = string(bytes.TrimSpace(b))
+		}
+	}
+
+	// Inside a cluster, the hostname is the pod name, unless overridden in the
+	// pod specification.
+	if _, ok := fields["pod"]; !ok && os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		if hostname, err := os.Hostname(); err == nil {
+			fields["pod"] = hostname
+		}
+	}
+
+	return fields
+}
+
+// ciFields returns the metadata of the CI job running, if any. GitHub Actions
+// and GitLab CI are supported.
+func ciFields() map[string]any {
+	var fields map[string]any
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		fields = envFields(map[string][]string{
+			"repository": {"GITHUB_REPOSITORY"},
+			"pipeline":   {"GITHUB_WORKFLOW"},
+			"run_id":     {"GITHUB_RUN_ID"},
+			"run_number": {"GITHUB_RUN_NUMBER"},
+			"job":        {"GITHUB_JOB"},
+			"ref":        {"GITHUB_REF_NAME"},
+			"commit":     {"GITHUB_SHA"},
+			"actor":      {"GITHUB_ACTOR"},
+		})
+		fields["provider"] = "github"
+		if server, repo, id := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"); server != "" && repo != "" && id != "" {
+			fields["url"] = fmt.Sprintf("%s/%s/actions/runs/%s", server, repo, id)
+		}
+	case os.Getenv("GITLAB_CI") == "true":
+		fields = envFields(map[string][]string{
+			"repository": {"CI_PROJECT_PATH"},
+			"pipeline":   {"CI_PIPELINE_NAME", "CI_PIPELINE_SOURCE"},
+			"run_id":     {"CI_PIPELINE_ID"},
+			"run_number": {"CI_PIPELINE_IID"},
+			"job":        {"CI_JOB_NAME"},
+			"ref":        {"CI_COMMIT_REF_NAME"},
+			"commit":     {"CI_COMMIT_SHA"},
+			"actor":      {"GITLAB_USER_LOGIN"},
+			"url":        {"CI_JOB_URL"},
+		})
+		fields["provider"] = "gitlab"
+	}
+	return fields
+}
+
+// envFields returns the value of the first set environment variable of the
+// given ones, for each field.
+func envFields(vars map[string][]string) map[string]any {
+	fields := make(map[string]any, len(vars))
+	for field, names := range vars {
+		if i := slices.IndexFunc(names, func(name string) bool { return os.Getenv(name) != "" }); i >= 0 {
+			fields[field] = os.Getenv(names[i])
+		}
+	}
+	return fields
+}
@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnricher(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "axiomhq/cli")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_SHA", "abc")
+	t.Setenv("POD_NAME", "web-0")
+	t.Setenv("POD_NAMESPACE", "default")
+
+	e, empty, err := newEnricher(t.Context(), []string{"host", "k8s", "CI"})
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	event := map[string]any{
+		"kubernetes": map[string]any{"pod": "web-1"},
+		"msg":        "hello",
+	}
+	require.NoError(t, e.Process(event))
+
+	assert.Equal(t, "hello", event["msg"])
+	assert.Equal(t, map[string]any{"pod": "web-1", "namespace": "default"}, event["kubernetes"])
+	assert.Equal(t, map[string]any{
+		"provider":   "github",
+		"repository": "axiomhq/cli",
+		"run_id":     "42",
+		"commit":     "abc",
+		"url":        "https://github.com/axiomhq/cli/actions/runs/42",
+	}, event["ci"])
+	assert.Contains(t, event["host"], "os")
+	assert.Contains(t, event["host"], "arch")
+
+	// Every event gets its own copy of the fields.
+	other := map[string]any{}
+	require.NoError(t, e.Process(other))
+	assert.Equal(t, map[string]any{"pod": "web-0", "namespace": "default"}, other["kubernetes"])
+}
+
+func TestEnricher_Presets(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("GITLAB_CI", "")
+
+	_, empty, err := newEnricher(t.Context(), []string{"ci"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci"}, empty)
+
+	_, _, err = newEnricher(t.Context(), []string{"cloud"})
+	assert.EqualError(t, err, `invalid enrichment preset "cloud" (valid presets: host, process, git, k8s, ci)`)
+}
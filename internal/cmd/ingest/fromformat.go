@@ -0,0 +1,443 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+)
+
+// Export formats of other log platforms, parsed client-side.
+const (
+	fromElastic    = "elastic"
+	fromSplunk     = "splunk"
+	fromCloudWatch = "cloudwatch"
+	fromLoki       = "loki"
+)
+
+var validFromFormats = []string{
+	fromElastic,
+	fromSplunk,
+	fromCloudWatch,
+	fromLoki,
+}
+
+// exportEventReader reads events from the export of another log platform. The
+// export is read as a sequence of records, e.g. JSON values, each of which is
+// converted to zero or more events.
+type exportEventReader struct {
+	// next returns the next record of the export.
+	next func() (map[string]any, error)
+	// convert converts a record to events.
+	convert func(map[string]any) ([]map[string]any, error)
+
+	pending []map[string]any
+}
+
+func newExportEventReader(r io.Reader, format string) (*exportEventReader, error) {
+	er := new(exportEventReader)
+	switch format {
+	case fromElastic:
+		er.convert = new(elasticConverter).Convert
+	case fromSplunk:
+		er.convert = convertSplunk
+	case fromCloudWatch:
+		er.convert = convertCloudWatch
+	case fromLoki:
+		er.convert = convertLoki
+	default:
+		return nil, fmt.Errorf("invalid export format %q", format)
+	}
+
+	// Splunk exports results as CSV, as well.
+	br := bufio.NewReader(r)
+	if format == fromSplunk && !startsWithJSON(br) {
+		cr, err := newCSVEventReader(br, "", nil, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		er.next = cr.ReadEvent
+	} else {
+		er.next = newJSONRecordReader(br).Next
+	}
+
+	return er, nil
+}
+
+// ReadEvent implements eventReader.
+func (er *exportEventReader) ReadEvent() (map[string]any, error) {
+	for len(er.pending) == 0 {
+		record, err := er.next()
+		if err != nil {
+			return nil, err
+		}
+		if er.pending, err = er.convert(record); err != nil {
+			return nil, err
+		}
+	}
+
+	event := er.pending[0]
+	er.pending = er.pending[1:]
+	return event, nil
+}
+
+// jsonRecordReader reads a sequence of JSON objects. Arrays of objects are
+// flattened.
+type jsonRecordReader struct {
+	dec     *json.Decoder
+	pending []any
+}
+
+func newJSONRecordReader(r io.Reader) *jsonRecordReader {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return &jsonRecordReader{dec: dec}
+}
+
+// Next returns the next JSON object. It returns io.EOF when there are no more
+// objects to read.
+func (jr *jsonRecordReader) Next() (map[string]any, error) {
+	for {
+		if len(jr.pending) == 0 {
+			var v any
+			if err := jr.dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			jr.pending = []any{v}
+		}
+
+		v := jr.pending[0]
+		jr.pending = jr.pending[1:]
+
+		switch v := v.(type) {
+		case map[string]any:
+			return v, nil
+		case []any:
+			jr.pending = append(v, jr.pending...)
+		default:
+			return nil, fmt.Errorf("expected a JSON object, got %T", v)
+		}
+	}
+}
+
+// elasticConverter converts Elasticsearch and OpenSearch exports: search and
+// scroll responses, the hits they contain, as written by e.g. elasticdump, and
+// requests to the bulk API. The source of a document becomes the event. Its
+// "@timestamp" field is used as the event timestamp. The index and ID of the
+// document are kept in the "elastic" field.
+type elasticConverter struct {
+	// action is the bulk action whose document is expected next, if any.
+	action map[string]any
+}
+
+// Convert a record of an Elasticsearch export to events.
+func (c *elasticConverter) Convert(record map[string]any) ([]map[string]any, error) {
+	// The document following a bulk action.
+	if action := c.action; action != nil {
+		c.action = nil
+		if doc, ok := record["doc"].(map[string]any); ok && action["_op"] == "update" {
+			record = doc
+		}
+		return []map[string]any{elasticEvent(record, action)}, nil
+	}
+
+	// A search or scroll response.
+	if hits, ok := record["hits"].(map[string]any); ok {
+		hits, _ := hits["hits"].([]any)
+		events := make([]map[string]any, 0, len(hits))
+		for _, hit := range hits {
+			if hit, ok := hit.(map[string]any); ok {
+				if source, ok := hit["_source"].(map[string]any); ok {
+					events = append(events, elasticEvent(source, hit))
+				}
+			}
+		}
+		return events, nil
+	}
+
+	// A single hit.
+	if source, ok := record["_source"].(map[string]any); ok {
+		return []map[string]any{elasticEvent(source, record)}, nil
+	}
+
+	// A bulk action, which is followed by a document, unless it is a delete.
+	// Only an action naming the index or ID of the document is told apart
+	// from a plain document with a single object field of the same name.
+	if len(record) == 1 {
+		for op, meta := range record {
+			meta, ok := meta.(map[string]any)
+			if !ok || !isBulkMetadata(meta) {
+				break
+			}
+			switch op {
+			case "index", "create", "update":
+				c.action = maps.Clone(meta)
+				c.action["_op"] = op
+				return nil, nil
+			case "delete":
+				return nil, nil
+			}
+		}
+	}
+
+	// A plain document.
+	return []map[string]any{elasticEvent(record, nil)}, nil
+}
+
+// isBulkMetadata returns true if the object holds the metadata of a bulk
+// action.
+func isBulkMetadata(meta map[string]any) bool {
+	_, hasIndex := meta["_index"]
+	_, hasID := meta["_id"]
+	return hasIndex || hasID
+}
+
+func elasticEvent(source, meta map[string]any) map[string]any {
+	event := maps.Clone(source)
+	if ts, ok := event["@timestamp"]; ok {
+		delete(event, "@timestamp")
+		event["_time"] = ts
+	}
+
+	elastic := make(map[string]any, 2)
+	for _, key := range []string{"_index", "_id"} {
+		if v, ok := meta[key]; ok {
+			elastic[strings.TrimPrefix(key, "_")] = v
+		}
+	}
+	if len(elastic) > 0 {
+		event["elastic"] = elastic
+	}
+
+	return event
+}
+
+// convertSplunk converts a record of a Splunk search export: a result as
+// exported in JSON, which is either wrapped in a "result" field or part of a
+// "results" array, or in CSV. The raw event becomes the "message" field. Other
+// internal fields, prefixed with an underscore, are dropped.
+func convertSplunk(record map[string]any) ([]map[string]any, error) {
+	if results, ok := record["results"].([]any); ok {
+		events := make([]map[string]any, 0, len(results))
+		for _, result := range results {
+			if result, ok := result.(map[string]any); ok {
+				event, err := splunkEvent(result)
+				if err != nil {
+					return nil, err
+				}
+				events = append(events, event)
+			}
+		}
+		return events, nil
+	}
+
+	if result, ok := record["result"].(map[string]any); ok {
+		record = result
+	} else if _, ok = record["preview"]; ok {
+		// Messages and other metadata of the export.
+		return nil, nil
+	}
+
+	event, err := splunkEvent(record)
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]any{event}, nil
+}
+
+func splunkEvent(result map[string]any) (map[string]any, error) {
+	event := make(map[string]any, len(result))
+	for k, v := range result {
+		switch {
+		case k == "_time":
+			ts, err := normalizeTimestamp(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Splunk field _time: %w", err)
+			}
+			event["_time"] = ts
+		case k == "_raw":
+			event["message"] = v
+		case strings.HasPrefix(k, "_"):
+			// Internal fields are dropped.
+		default:
+			event[k] = v
+		}
+	}
+	return event, nil
+}
+
+// convertCloudWatch converts a record of the output of "aws logs
+// filter-log-events" or "aws logs get-log-events": a response holding the log
+// events or a single log event. The timestamp of a log event is used as the
+// event timestamp. Messages that are JSON objects are parsed. The log stream,
+// event ID and ingestion time are kept in the "cloudwatch" field.
+func convertCloudWatch(record map[string]any) ([]map[string]any, error) {
+	logEvents, ok := record["events"].([]any)
+	if !ok {
+		if _, ok = record["message"]; !ok {
+			// A response without events.
+			return nil, nil
+		}
+		logEvents = []any{record}
+	}
+
+	events := make([]map[string]any, 0, len(logEvents))
+	for _, logEvent := range logEvents {
+		logEvent, ok := logEvent.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		msg, _ := logEvent["message"].(string)
+		event := structuredMessage(msg)
+
+		if ts, ok := logEvent["timestamp"].(json.Number); ok {
+			msec, err := ts.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("invalid CloudWatch timestamp: %w", err)
+			}
+			event["_time"] = time.UnixMilli(msec).UTC().Format(time.RFC3339Nano)
+		}
+
+		cloudwatch := make(map[string]any, 3)
+		for key, field := range map[string]string{
+			"logStreamName": "log_stream",
+			"logGroupName":  "log_group",
+			"eventId":       "event_id",
+		} {
+			if v, ok := logEvent[key]; ok {
+				cloudwatch[field] = v
+			}
+		}
+		if ts, ok := logEvent["ingestionTime"].(json.Number); ok {
+			if msec, err := ts.Int64(); err == nil {
+				cloudwatch["ingestion_time"] = time.UnixMilli(msec).UTC().Format(time.RFC3339Nano)
+			}
+		}
+		if len(cloudwatch) > 0 {
+			event["cloudwatch"] = cloudwatch
+		}
+
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// convertLoki converts a record of a Loki export: a response of the query or
+// query_range API holding streams or a single log line, as written by
+// "logcli query --output=jsonl". The timestamp of a log line is used as the
+// event timestamp. Lines that are JSON objects are parsed. The labels of the
+// stream are kept in the "labels" field.
+func convertLoki(record map[string]any) ([]map[string]any, error) {
+	// A single log line.
+	if line, ok := record["line"].(string); ok {
+		event := structuredMessage(line)
+		if ts, ok := record["timestamp"]; ok {
+			event["_time"] = ts
+		}
+		if labels, ok := record["labels"].(map[string]any); ok && len(labels) > 0 {
+			event["labels"] = labels
+		}
+		return []map[string]any{event}, nil
+	}
+
+	// A query response, with or without the envelope.
+	if data, ok := record["data"].(map[string]any); ok {
+		record = data
+	}
+	if resultType, ok := record["resultType"]; ok && resultType != "streams" {
+		return nil, fmt.Errorf("unsupported Loki result type %q (only streams are supported)", resultType)
+	}
+	streams, _ := record["result"].([]any)
+
+	var events []map[string]any
+	for _, stream := range streams {
+		stream, ok := stream.(map[string]any)
+		if !ok {
+			continue
+		}
+		labels, _ := stream["stream"].(map[string]any)
+		values, _ := stream["values"].([]any)
+		for _, value := range values {
+			// A value is a tuple of the timestamp in nanoseconds as a string
+			// and the log line.
+			value, ok := value.([]any)
+			if !ok || len(value) < 2 {
+				continue
+			}
+			ts, _ := value[0].(string)
+			line, _ := value[1].(string)
+
+			nsec, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Loki timestamp %q: %w", ts, err)
+			}
+
+			event := structuredMessage(line)
+			event["_time"] = time.Unix(0, nsec).UTC().Format(time.RFC3339Nano)
+			if len(labels) > 0 {
+				event["labels"] = maps.Clone(labels)
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// structuredMessage returns the fields of a message that is a JSON object.
+// Other messages are returned in the "message" field.
+func structuredMessage(msg string) map[string]any {
+	if s := strings.TrimSpace(msg); strings.HasPrefix(s, "{") {
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+
+		var fields map[string]any
+		if err := dec.Decode(&fields); err == nil && !dec.More() && len(fields) > 0 {
+			return fields
+		}
+	}
+	return map[string]any{"message": msg}
+}
+
+// normalizeTimestamp formats a timestamp given in any common format as RFC
+// 3339. Numbers are kept as is.
+func normalizeTimestamp(v any) (any, error) {
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return json.Number(s), nil
+	}
+	t, err := dateparse.ParseIn(s, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
+
+// startsWithJSON returns true if the first non-whitespace character read from
+// br starts a JSON object or array.
+func startsWithJSON(br *bufio.Reader) bool {
+	for n := 1; ; n++ {
+		b, err := br.Peek(n)
+		if errors.Is(err, io.EOF) || len(b) < n {
+			return false
+		}
+		switch b[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return true
+		default:
+			return false
+		}
+	}
+}
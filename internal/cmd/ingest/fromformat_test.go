@@ -0,0 +1,138 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportEventReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   []map[string]any
+	}{
+		{
+			name:   "elastic scroll responses",
+			format: fromElastic,
+			input: `{"_scroll_id":"abc","hits":{"total":{"value":2},"hits":[` +
+				`{"_index":"logs","_id":"1","_source":{"@timestamp":"2024-01-01T00:00:00Z","message":"a"}}]}}` + "\n" +
+				`{"_scroll_id":"abc","hits":{"hits":[{"_index":"logs","_id":"2","_source":{"message":"b"}}]}}`,
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00Z", "message": "a", "elastic": map[string]any{"index": "logs", "id": "1"}},
+				{"message": "b", "elastic": map[string]any{"index": "logs", "id": "2"}},
+			},
+		},
+		{
+			name:   "elastic bulk",
+			format: fromElastic,
+			input: `{"index":{"_index":"logs","_id":"1"}}` + "\n" +
+				`{"@timestamp":"2024-01-01T00:00:00Z","message":"a"}` + "\n" +
+				`{"delete":{"_index":"logs","_id":"2"}}` + "\n" +
+				`{"update":{"_index":"logs","_id":"3"}}` + "\n" +
+				`{"doc":{"message":"c"}}` + "\n",
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00Z", "message": "a", "elastic": map[string]any{"index": "logs", "id": "1"}},
+				{"message": "c", "elastic": map[string]any{"index": "logs", "id": "3"}},
+			},
+		},
+		{
+			name:   "elastic document with a single object field",
+			format: fromElastic,
+			input: `{"update":{"status":"done"}}` + "\n" +
+				`{"message":"b"}` + "\n",
+			want: []map[string]any{
+				{"update": map[string]any{"status": "done"}},
+				{"message": "b"},
+			},
+		},
+		{
+			name:   "splunk json",
+			format: fromSplunk,
+			input: `{"preview":false,"offset":0,"result":{"_time":"2024-01-01T01:00:00.000+01:00","_raw":"GET /","_cd":"1:2","host":"web-1"}}` + "\n" +
+				`{"preview":false,"offset":1,"lastrow":true,"result":{"_time":"2024-01-01 00:00:01.000 UTC","_raw":"GET /b","host":"web-2"}}` + "\n",
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00Z", "message": "GET /", "host": "web-1"},
+				{"_time": "2024-01-01T00:00:01Z", "message": "GET /b", "host": "web-2"},
+			},
+		},
+		{
+			name:   "splunk csv",
+			format: fromSplunk,
+			input:  "\"_time\",\"_raw\",host,\"_indextime\"\n\"2024-01-01T00:00:00.000+0000\",\"GET /\",\"web-1\",1704067200\n",
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00Z", "message": "GET /", "host": "web-1"},
+			},
+		},
+		{
+			name:   "cloudwatch",
+			format: fromCloudWatch,
+			input: `{"events":[` +
+				`{"logStreamName":"s1","timestamp":1704067200000,"message":"plain","ingestionTime":1704067201000,"eventId":"e1"},` +
+				`{"logStreamName":"s1","timestamp":1704067200500,"message":"{\"level\":\"error\"}","eventId":"e2"}` +
+				`],"searchedLogStreams":[],"nextToken":"t"}`,
+			want: []map[string]any{
+				{
+					"_time":      "2024-01-01T00:00:00Z",
+					"message":    "plain",
+					"cloudwatch": map[string]any{"log_stream": "s1", "event_id": "e1", "ingestion_time": "2024-01-01T00:00:01Z"},
+				},
+				{
+					"_time":      "2024-01-01T00:00:00.5Z",
+					"level":      "error",
+					"cloudwatch": map[string]any{"log_stream": "s1", "event_id": "e2"},
+				},
+			},
+		},
+		{
+			name:   "loki query range",
+			format: fromLoki,
+			input: `{"status":"success","data":{"resultType":"streams","result":[` +
+				`{"stream":{"app":"web"},"values":[["1704067200000000001","hello"],["1704067200000000002","{\"status\":200}"]]}]}}`,
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00.000000001Z", "message": "hello", "labels": map[string]any{"app": "web"}},
+				{"_time": "2024-01-01T00:00:00.000000002Z", "status": json.Number("200"), "labels": map[string]any{"app": "web"}},
+			},
+		},
+		{
+			name:   "loki jsonl",
+			format: fromLoki,
+			input:  `{"labels":{"app":"web"},"line":"hello","timestamp":"2024-01-01T00:00:00Z"}` + "\n",
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:00Z", "message": "hello", "labels": map[string]any{"app": "web"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			er, err := newExportEventReader(strings.NewReader(tt.input), tt.format)
+			require.NoError(t, err)
+
+			var got []map[string]any
+			for {
+				event, err := er.ReadEvent()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, event)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExportEventReader_UnsupportedLokiResult(t *testing.T) {
+	er, err := newExportEventReader(strings.NewReader(`{"data":{"resultType":"matrix","result":[]}}`), fromLoki)
+	require.NoError(t, err)
+
+	_, err = er.ReadEvent()
+	assert.EqualError(t, err, `unsupported Loki result type "matrix" (only streams are supported)`)
+}
@@ -0,0 +1,129 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// geoIPRecord holds the fields of MaxMind City, Country and ASN databases
+// that are added to events.
+type geoIPRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+		TimeZone  string   `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	ASN   uint32 `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// geoIPEnricher adds the geographical location and the autonomous system of
+// the IP addresses in the given fields to every event. The location of the IP
+// address in field "<field>" is added as field "<field>_geo". Multiple
+// databases can be used, e.g. a City and an ASN database, whose results are
+// merged. Events without an IP address that can be located are kept as is.
+type geoIPEnricher struct {
+	fields []string
+	dbs    []*maxminddb.Reader
+}
+
+func newGeoIPEnricher(fields, filenames []string) (*geoIPEnricher, error) {
+	e := &geoIPEnricher{
+		fields: fields,
+		dbs:    make([]*maxminddb.Reader, 0, len(filenames)),
+	}
+	for _, filename := range filenames {
+		db, err := maxminddb.Open(filename)
+		if err != nil {
+			_ = e.Close()
+			return nil, fmt.Errorf("invalid GeoIP database %q: %w", filename, err)
+		}
+		e.dbs = append(e.dbs, db)
+	}
+	return e, nil
+}
+
+// Process implements processor.
+func (e *geoIPEnricher) Process(event map[string]any) error {
+	for _, field := range e.fields {
+		s, ok := event[field].(string)
+		if !ok {
+			continue
+		}
+		// Addresses might carry a port, e.g. "192.0.2.1:1234".
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			addrPort, err := netip.ParseAddrPort(s)
+			if err != nil {
+				continue
+			}
+			addr = addrPort.Addr()
+		}
+
+		if geo := e.lookup(addr.Unmap()); len(geo) > 0 {
+			event[field+"_geo"] = geo
+		}
+	}
+	return nil
+}
+
+func (e *geoIPEnricher) lookup(addr netip.Addr) map[string]any {
+	geo := make(map[string]any)
+	for _, db := range e.dbs {
+		var rec geoIPRecord
+		if _, ok, err := db.LookupNetwork(net.IP(addr.AsSlice()), &rec); err != nil || !ok {
+			continue
+		}
+
+		setIfNotEmpty(geo, "continent", rec.Continent.Code)
+		setIfNotEmpty(geo, "country", rec.Country.Names["en"])
+		setIfNotEmpty(geo, "country_code", rec.Country.ISOCode)
+		if len(rec.Subdivisions) > 0 {
+			setIfNotEmpty(geo, "region", rec.Subdivisions[0].Names["en"])
+		}
+		setIfNotEmpty(geo, "city", rec.City.Names["en"])
+		if rec.Location.Latitude != nil && rec.Location.Longitude != nil {
+			geo["latitude"] = *rec.Location.Latitude
+			geo["longitude"] = *rec.Location.Longitude
+		}
+		setIfNotEmpty(geo, "timezone", rec.Location.TimeZone)
+		if rec.ASN != 0 {
+			geo["asn"] = rec.ASN
+		}
+		setIfNotEmpty(geo, "as_org", rec.ASOrg)
+	}
+	return geo
+}
+
+// Close the databases.
+func (e *geoIPEnricher) Close() error {
+	var errs []error
+	for _, db := range e.dbs {
+		errs = append(errs, db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func setIfNotEmpty(m map[string]any, key, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		m[key] = value
+	}
+}
@@ -0,0 +1,71 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeoIPEnricher(t *testing.T) {
+	e, err := newGeoIPEnricher([]string{"client_ip", "server_ip"}, []string{"testdata/geoip.mmdb"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = e.Close() })
+
+	event := map[string]any{
+		"client_ip": "81.2.69.142",
+		"server_ip": "10.0.0.1",
+	}
+	require.NoError(t, e.Process(event))
+
+	assert.Equal(t, map[string]any{
+		"continent":    "EU",
+		"country":      "United Kingdom",
+		"country_code": "GB",
+		"region":       "England",
+		"city":         "London",
+		"latitude":     51.5142,
+		"longitude":    -0.0931,
+		"timezone":     "Europe/London",
+		"asn":          uint32(20712),
+		"as_org":       "Andrews & Arnold Ltd",
+	}, event["client_ip_geo"])
+	assert.NotContains(t, event, "server_ip_geo")
+
+	// Addresses with a port and IPv4-mapped IPv6 addresses are located, too.
+	for _, ip := range []string{"81.2.69.142:443", "::ffff:81.2.69.142"} {
+		event = map[string]any{"client_ip": ip}
+		require.NoError(t, e.Process(event))
+		assert.Contains(t, event, "client_ip_geo", ip)
+	}
+
+	// Values that are no IP addresses are ignored.
+	event = map[string]any{"client_ip": "localhost", "server_ip": 42}
+	require.NoError(t, e.Process(event))
+	assert.Len(t, event, 2)
+}
+
+func TestGeoIPEnricher_InvalidDatabase(t *testing.T) {
+	_, err := newGeoIPEnricher([]string{"ip"}, []string{"testdata/missing.mmdb"})
+	assert.ErrorContains(t, err, `invalid GeoIP database "testdata/missing.mmdb"`)
+}
+
+func TestUserAgentEnricher(t *testing.T) {
+	e := &userAgentEnricher{fields: []string{"user_agent"}}
+
+	event := map[string]any{
+		"user_agent": "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+	}
+	require.NoError(t, e.Process(event))
+
+	parsed, ok := event["user_agent_parsed"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "Safari", parsed["browser"])
+	assert.Equal(t, "17.1", parsed["browser_version"])
+	assert.Equal(t, "iPhone", parsed["platform"])
+	assert.Equal(t, deviceMobile, parsed["device"])
+
+	event = map[string]any{"user_agent": "Googlebot/2.1 (+http://www.google.com/bot.html)"}
+	require.NoError(t, e.Process(event))
+	assert.Equal(t, deviceBot, event["user_agent_parsed"].(map[string]any)["device"])
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -31,6 +32,14 @@ var (
 		axiom.CSV.String(),
 	}
 
+	// validInputFormats are formats parsed client-side. They are set through
+	// the content type.
+	validInputFormats = []string{
+		formatJournal,
+		formatDocker,
+		formatCRI,
+	}
+
 	validContentEncodings = []string{
 		axiom.Identity.String(),
 		axiom.Gzip.String(),
@@ -44,7 +53,8 @@ type options struct {
 	// Dataset to ingest into. If not supplied as an argument, which is
 	// optional, the user will be asked for it.
 	Dataset string
-	// Filenames of the files to ingest. If not set, will read from Stdin.
+	// Filenames of the files to ingest. Can also be HTTP(S) URLs or S3
+	// locations. If not set, will read from Stdin.
 	Filenames []string
 	// TimestampField to take the ingestion time from.
 	TimestampField string
@@ -61,6 +71,13 @@ type options struct {
 	// ContentType of the data to ingest.
 	ContentType axiom.ContentType
 	contentType string // for the flag value
+	// InputFormat of the data to ingest, if it is parsed client-side, e.g.
+	// "journal" or "cri". The data is passed on to the server as newline
+	// delimited JSON.
+	InputFormat string
+	// FromFormat is the export format of another log platform the data to
+	// ingest is in. It sets the input format.
+	FromFormat string
 	// ContentEncoding of the data to ingest.
 	ContentEncoding axiom.ContentEncoding
 	contentEncoding string // for the flag value
@@ -71,9 +88,55 @@ type options struct {
 	// to ingest does not have a header row.
 	CSVFields []ingest.Option
 	csvFields []string
+	// CSVInferTypes converts CSV data to newline delimited JSON client-side
+	// and infers the types of the columns from a sample of the first rows.
+	CSVInferTypes bool
+	// CSVTypes are the explicitly given types of CSV columns. Setting them
+	// converts CSV data to newline delimited JSON client-side.
+	CSVTypes map[string]string
+	csvTypes []string // for the flag value
+	// CSVSampleSize is the number of rows to infer the CSV column types from.
+	CSVSampleSize uint
 	// ContinueOnError will continue ingesting, even if an error is returned
 	// from the server.
 	ContinueOnError bool
+	// Schema is the filename of a JSON Schema every event is validated
+	// against, client-side.
+	Schema string
+	// RejectsFile is the filename of the file events rejected client-side are
+	// written to. If not set, rejected events are dropped.
+	RejectsFile string
+	// MaxEventSize is the maximum size of a single event in bytes, when
+	// ingesting batchable data.
+	MaxEventSize int
+	maxEventSize string // for the flag value
+	// OversizePolicy decides what happens to events exceeding the maximum
+	// event size: "fail", "skip", "truncate" or "split".
+	OversizePolicy string
+	// OversizeField is the field to truncate or split for events exceeding the
+	// maximum event size.
+	OversizeField string
+	// Enrich are the enrichment presets whose fields are added to every
+	// event, client-side.
+	Enrich []string
+	// GeoIPFields are the fields holding IP addresses to add the geographical
+	// location of to every event, client-side.
+	GeoIPFields []string
+	// GeoIPDatabases are the filenames of the MaxMind databases to look up
+	// IP addresses in.
+	GeoIPDatabases []string
+	// UserAgentFields are the fields holding user agent strings to parse,
+	// client-side.
+	UserAgentFields []string
+	// Also are additional destinations in the form "<deployment>:<dataset>"
+	// the data is sent to, besides the dataset to ingest into.
+	Also []string
+
+	// destinations are the parsed additional destinations.
+	destinations []*destination
+
+	// processors are applied to every event, client-side.
+	processors []processor
 }
 
 // NewCmd creates and returns the ingest command.
@@ -83,7 +146,7 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:   "ingest <dataset-name> [(-f|--file) <filename> [ ...]] [--timestamp-field <timestamp-field>] [--timestamp-format <timestamp-format>] [(-d|--delimiter <delimiter>] [--flush-every <duration>] [(-b|--batch-size <batch-size>] [(-t|--content-type <content-type>] [(-e|--content-encoding <content-encoding>] [(-l|--label) <key>:<value> [ ...]] [--csv-fields <field> [ ...]] [--continue-on-error <TRUE|FALSE>]",
+		Use:   "ingest <dataset-name> [(-f|--file) <filename> [ ...]] [--timestamp-field <timestamp-field>] [--timestamp-format <timestamp-format>] [(-d|--delimiter <delimiter>] [--flush-every <duration>] [(-b|--batch-size <batch-size>] [(-t|--content-type <content-type>|journal|docker|cri] [--from-format elastic|splunk|cloudwatch|loki] [(-e|--content-encoding <content-encoding>] [(-l|--label) <key>:<value> [ ...]] [--csv-fields <field> [ ...]] [--csv-infer-types] [--csv-types <field>:<type> [ ...]] [--csv-sample-size <rows>] [--continue-on-error <TRUE|FALSE>] [--schema <filename>] [--rejects-file <filename>] [--max-event-size <size>] [--oversized fail|skip|truncate|split] [--oversized-field <field>] [--enrich host|process|git|k8s|ci [ ...]] [--geoip-field <field> [ ...] --geoip-db <filename> [ ...]] [--ua-field <field> [ ...]] [--also [<deployment>:]<dataset> [ ...]]",
 		Short: "Ingest structured data",
 		Long: heredoc.Doc(`
 			Ingest structured data into an Axiom dataset.
@@ -92,7 +155,32 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			JSON objects (JSON) and a newline delimited list of comma separated
 			values (CSV). The first line of CSV content is assumed to be the
 			field names for the values in the following lines. The input format
-			is automatically detected.
+			is automatically detected. Gzip and zstd compressed data is
+			decompressed automatically, unless the content encoding is set.
+
+			Entries of the systemd journal, as exported by "journalctl -o export"
+			or "journalctl -o json", are parsed client-side when the content
+			type is set to "journal". Every journal field, e.g. "_SYSTEMD_UNIT"
+			or "PRIORITY", becomes an event field and the realtime timestamp of
+			an entry is used as the event timestamp.
+
+			Container logs are parsed client-side when the content type is set
+			to "docker" for the Docker json-file logging driver or to "cri" for
+			the format used by Kubernetes nodes. Lines split by the container
+			runtime are joined. Lines that are JSON objects are parsed, their
+			fields become event fields. Other lines are kept in the "message"
+			field. The event timestamp and the "stream" field are set from the
+			container runtime. For log files under /var/log/pods, the namespace,
+			pod and container are added to the "kubernetes" field.
+
+			Besides local files, data can be ingested from HTTP(S) URLs and from
+			S3-compatible object storage. A S3 location in the form
+			"s3://bucket/prefix" ingests every object under the prefix. The S3
+			configuration and credentials are taken from the environment and
+			the shared AWS configuration files. Without credentials, objects
+			are requested anonymously. To use a S3-compatible server like
+			MinIO, set AWS_ENDPOINT_URL_S3 to its URL. Downloads from HTTP(S)
+			URLs fail if the server doesn't send data for 30 seconds.
 
 			Each object is assigned an event timestamp from the configured
 			timestamp field (default "_time"). If there is no timestamp field
@@ -109,6 +197,92 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			For Unix timestamps, leave the timestamp format unspecified and just
 			provide the value as a number. Can be seconds, milliseconds,
 			microseconds or nanoseconds.
+
+			CSV values are strings, unless the server infers otherwise. CSV data
+			can be converted to typed newline delimited JSON client-side. The
+			type of each column (int, float, bool, timestamp or string) is
+			inferred from a sample of the first rows or given explicitly. Empty
+			values and "null" are converted to null. Values that don't match
+			the type of their column are kept as strings. Timestamps are
+			normalized to RFC 3339.
+
+			Exports of other log platforms can be ingested, e.g. to migrate
+			historical data. The export format is given explicitly and the
+			timestamp and message of each record are mapped onto the event
+			timestamp and structured fields:
+
+				elastic:    Elasticsearch and OpenSearch search and scroll
+				            responses, dumped hits and bulk API requests. The
+				            document source becomes the event, "@timestamp" the
+				            event timestamp. Index and ID are kept in "elastic".
+				splunk:     Splunk search results exported as JSON or CSV.
+				            "_time" is kept, "_raw" becomes "message". Other
+				            internal fields are dropped.
+				cloudwatch: CloudWatch Logs events as output by "aws logs
+				            filter-log-events" and "aws logs get-log-events".
+				            Log stream, event ID and ingestion time are kept
+				            in "cloudwatch".
+				loki:       Loki query and query_range API responses and the
+				            output of "logcli query --output=jsonl". Stream
+				            labels are kept in "labels".
+
+			Messages of CloudWatch and Loki that are JSON objects are parsed,
+			their fields become event fields.
+
+			Events can be validated against a JSON Schema before they are sent
+			to Axiom. Events that fail validation are rejected and reported
+			with the source, their index in it, the path of the violating
+			field and the reason. Rejected events are dropped unless a file to
+			write them to is given.
+
+			When ingesting batchable data, a single event is limited to 1 MiB by
+			default. What happens to larger events is configurable: fail the
+			ingestion (the default), skip them, truncate the string value of a
+			field so the event fits or split the string value of a field over
+			multiple events. Split events carry the fields "<field>_part" and
+			"<field>_parts" which hold the index of the part and the number of
+			parts. Truncating and splitting requires JSON input.
+
+			Events can be enriched with metadata of the environment, client-side.
+			Each enrichment preset adds its fields under a field named after
+			it, unless the event already has them:
+
+				host:       "host.name", "host.os" and "host.arch"
+				process:    "process.pid", "process.ppid" and "process.user"
+				git:        "git.repository", "git.branch" and "git.commit" of
+				            the repository of the working directory
+				k8s:        "kubernetes.namespace", "kubernetes.pod",
+				            "kubernetes.pod_uid", "kubernetes.pod_ip",
+				            "kubernetes.node" and "kubernetes.container" from
+				            the POD_NAMESPACE, POD_NAME, POD_UID, POD_IP,
+				            NODE_NAME and K8S_CONTAINER_NAME environment
+				            variables, usually set using the downward API
+				ci:         "ci.provider", "ci.repository", "ci.pipeline",
+				            "ci.run_id", "ci.run_number", "ci.job", "ci.ref",
+				            "ci.commit", "ci.actor" and "ci.url" of the GitHub
+				            Actions or GitLab CI job running
+
+			The IP addresses in the given fields can be located using local
+			MaxMind databases (e.g. GeoLite2 City and ASN). The continent,
+			country, region, city, coordinates, timezone and autonomous system
+			of the IP address in field "<field>" are added as "<field>_geo".
+			The user agent strings in the given fields can be parsed. The
+			browser, operating system, platform and device of the user agent in
+			field "<field>" are added as "<field>_parsed". Both work offline.
+
+			The data can be sent to additional datasets at the same time, e.g.
+			during a migration, on the same or on other configured deployments.
+			Each batch is queued for every destination and sent in the
+			background, once it was ingested into the dataset, so a slow
+			destination never holds up the ingestion. Batches larger than 8
+			MiB, like whole files that are not batchable, are held in
+			temporary files until they are sent, instead of in memory.
+			Batches for a destination with 16 batches queued are dropped and
+			reported. Requests to a destination time out after 30 seconds,
+			which is also how long queued batches are still sent for once the
+			input ends. The status of each destination is reported
+			independently. Failing to ingest into an additional destination
+			doesn't fail the ingestion.
 		`),
 
 		DisableFlagsInUseLine: true,
@@ -128,6 +302,14 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			# newline delimited JSON.
 			$ ./loggen -ndjson | axiom ingest gen-logs
 
+			# Follow the systemd journal of the local host and ship its entries
+			# to a dataset named "hosts":
+			$ journalctl -f -o export | axiom ingest hosts -t=journal
+
+			# Ingest the logs of all containers of the "web" pods, as written by
+			# the kubelet, into a dataset named "k8s-logs":
+			$ axiom ingest k8s-logs -t=cri -f /var/log/pods/default_web-*/*/*.log
+
 			# Send a set of gzip compressed JSON logs to a dataset called
 			# "http-logs". The content type is automatically detected. 
 			$ zcat log*.json.gz | axiom ingest http-logs
@@ -135,6 +317,11 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			# Send a set of gzip compressed JSON logs to a dataset called
 			# "http-logs":
 			$ cat log*.json.gz | axiom ingest http-logs -t=json -e=gzip
+
+			# Ingest all objects under the "2024/01/" prefix of the "exports"
+			# bucket as well as a file served over HTTPS into a dataset called
+			# "http-logs":
+			$ axiom ingest http-logs -f s3://exports/2024/01/ -f https://example.com/logs.ndjson.gz
 			
 			# Send a set of gzip compressed JSON logs to a dataset called
 			# "http-logs" and attach some labels. Labels are added server-side
@@ -142,10 +329,41 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			# locally:
 			$ cat log*.json.gz | axiom ingest http-logs -t=json -e=gzip -l=env:prod -l=app:webserver
 
+			# Migrate an Elasticsearch index, dumped using the scroll API, to a
+			# dataset called "app-logs":
+			$ axiom ingest app-logs -f dump.json --from-format=elastic
+
 			# Send a CSV file to a dataset called "sec-logs". The CSV file does
 			# not have a header row, so the field names are set manually. This
 			# also comes in handy as the file is now automatically batched.
 			$ axiom ingest sec-logs -f sec-logs.csv -t=csv --csv-fields=timestamp,source,severity,message
+
+			# Send a CSV file to a dataset called "sec-logs" and convert the
+			# values to typed JSON, client-side. The type of the "port" column is
+			# set explicitly, the types of the other columns are inferred:
+			$ axiom ingest sec-logs -f sec-logs.csv --csv-infer-types --csv-types=port:int
+
+			# Validate events against a JSON Schema before ingesting them into a
+			# dataset called "app-logs". Events that don't match the schema are
+			# written to "rejected.ndjson", instead:
+			$ axiom ingest app-logs -f app-logs.ndjson --schema=schema.json --rejects-file=rejected.ndjson
+
+			# Ingest events of up to 4 MiB into a dataset called "app-logs" and
+			# truncate the "payload" field of even larger events:
+			$ axiom ingest app-logs -f app-logs.ndjson --max-event-size=4MiB --oversized=truncate --oversized-field=payload
+
+			# Ingest the test results of a CI job into a dataset named
+			# "test-runs" and add the metadata of the job and the commit tested:
+			$ axiom ingest test-runs -f results.ndjson --enrich=ci,git
+
+			# Ingest access logs into a dataset named "http-logs" and add the
+			# location of the client and its parsed user agent:
+			$ axiom ingest http-logs -f access.ndjson --geoip-field=client_ip --geoip-db=GeoLite2-City.mmdb --geoip-db=GeoLite2-ASN.mmdb --ua-field=user_agent
+
+			# Ingest into a dataset named "app-logs" and also into the
+			# "app-logs-staging" dataset of the active deployment and the
+			# "app-logs" dataset of the deployment configured as "eu":
+			$ ./loggen -ndjson | axiom ingest app-logs --also=app-logs-staging --also=eu:app-logs
 		`),
 
 		Annotations: map[string]string{
@@ -166,16 +384,30 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			}
 
 			// If set, parse content type and content encoding from their string
-			// representation.
-			if opts.ContentType, err = contentTypeFromString(opts.contentType); err != nil && cmd.Flag("content-type").Changed {
+			// representation. Input formats parsed client-side are set through
+			// the content type, as well.
+			if contentType := strings.ToLower(opts.contentType); slices.Contains(validInputFormats, contentType) {
+				opts.InputFormat = contentType
+			} else if opts.ContentType, err = contentTypeFromString(opts.contentType); err != nil && cmd.Flag("content-type").Changed {
 				return err
-			} else if opts.ContentEncoding, err = contentEncodingFromString(opts.contentEncoding); err != nil && cmd.Flag("content-encoding").Changed {
+			}
+			if opts.ContentEncoding, err = contentEncodingFromString(opts.contentEncoding); err != nil && cmd.Flag("content-encoding").Changed {
 				return err
 			}
 
+			// The export format of another log platform sets the input format.
+			if opts.FromFormat != "" {
+				if cmd.Flag("content-type").Changed {
+					return cmdutil.NewFlagErrorf("--from-format not valid when content type is set")
+				} else if opts.InputFormat = strings.ToLower(opts.FromFormat); !slices.Contains(validFromFormats, opts.InputFormat) {
+					return cmdutil.NewFlagErrorf("invalid export format %q (valid formats: %s)",
+						opts.FromFormat, strings.Join(validFromFormats, ", "))
+				}
+			}
+
 			// If the content encoding is set to anything else than "identity",
 			// make sure the content type is set, as well.
-			if opts.ContentEncoding != axiom.Identity && opts.ContentType == 0 {
+			if opts.ContentEncoding != axiom.Identity && opts.ContentType == 0 && opts.InputFormat == "" {
 				return fmt.Errorf("content encoding set but content type not set")
 			}
 
@@ -193,9 +425,79 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 				opts.CSVFields = append(opts.CSVFields, ingest.AddCSVField(field))
 			}
 
+			// Parse the CSV column types.
+			if opts.CSVTypes, err = parseCSVTypes(opts.csvTypes); err != nil {
+				return cmdutil.NewFlagError(err)
+			}
+
+			// Setup client-side processing.
+			if opts.Schema != "" {
+				validator, err := newSchemaValidator(opts.Schema)
+				if err != nil {
+					return err
+				}
+				opts.processors = append(opts.processors, validator)
+			}
+			if len(opts.Enrich) > 0 {
+				enricher, empty, err := newEnricher(cmd.Context(), opts.Enrich)
+				if err != nil {
+					return cmdutil.NewFlagError(err)
+				}
+				for _, preset := range empty {
+					fmt.Fprintf(opts.IO.ErrOut(), "%s Enrichment preset %s did not yield any fields\n",
+						opts.IO.ColorScheme().WarningIcon(), opts.IO.ColorScheme().Bold(preset))
+				}
+				opts.processors = append(opts.processors, enricher)
+			}
+			if len(opts.GeoIPFields) > 0 && len(opts.GeoIPDatabases) == 0 {
+				return cmdutil.NewFlagErrorf("--geoip-db required when --geoip-field is set")
+			} else if len(opts.GeoIPDatabases) > 0 && len(opts.GeoIPFields) == 0 {
+				return cmdutil.NewFlagErrorf("--geoip-field required when --geoip-db is set")
+			} else if len(opts.GeoIPFields) > 0 {
+				geoIP, err := newGeoIPEnricher(opts.GeoIPFields, opts.GeoIPDatabases)
+				if err != nil {
+					return err
+				}
+				defer geoIP.Close()
+				opts.processors = append(opts.processors, geoIP)
+			}
+			if len(opts.UserAgentFields) > 0 {
+				opts.processors = append(opts.processors, &userAgentEnricher{fields: opts.UserAgentFields})
+			}
+			if opts.RejectsFile != "" && len(opts.processors) == 0 {
+				return cmdutil.NewFlagErrorf("--rejects-file not valid without client-side processing (e.g. --schema)")
+			}
+			if cmd.Flag("csv-sample-size").Changed && !opts.CSVInferTypes {
+				return cmdutil.NewFlagErrorf("--csv-sample-size not valid without --csv-infer-types")
+			}
+
+			// Parse and sanity check the handling of oversized events.
+			if opts.MaxEventSize, err = parseEventSize(opts.maxEventSize); err != nil {
+				return cmdutil.NewFlagErrorf("invalid --max-event-size: %w", err)
+			} else if opts.OversizePolicy, err = oversizePolicyFromString(opts.OversizePolicy); err != nil {
+				return cmdutil.NewFlagError(err)
+			}
+			switch opts.OversizePolicy {
+			case oversizeTruncate, oversizeSplit:
+				if opts.OversizeField == "" {
+					return cmdutil.NewFlagErrorf("--oversized-field required when oversize policy is %q", opts.OversizePolicy)
+				}
+			default:
+				if opts.OversizeField != "" {
+					return cmdutil.NewFlagErrorf("--oversized-field not valid when oversize policy is %q", opts.OversizePolicy)
+				}
+			}
+
 			if err := complete(cmd.Context(), opts); err != nil {
 				return err
 			}
+
+			// Parse the additional destinations, now that the dataset to
+			// ingest into is known.
+			if opts.destinations, err = parseDestinations(opts.Also, opts.Config, opts.Dataset); err != nil {
+				return cmdutil.NewFlagError(err)
+			}
+
 			return run(
 				cmd.Context(),
 				opts,
@@ -206,17 +508,31 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringSliceVarP(&opts.Filenames, "file", "f", nil, "File(s) to ingest (- to read from stdin). If stdin is a pipe the default value is -, otherwise this is a required parameter")
+	cmd.Flags().StringSliceVarP(&opts.Filenames, "file", "f", nil, "File(s), HTTP(S) URL(s) or S3 location(s) (s3://bucket/prefix) to ingest (- to read from stdin). If stdin is a pipe the default value is -, otherwise this is a required parameter")
 	cmd.Flags().StringVar(&opts.TimestampField, "timestamp-field", "", "Field to take the ingestion time from (defaults to _time)")
 	cmd.Flags().StringVar(&opts.TimestampFormat, "timestamp-format", "", "Format used in the the timestamp field. Default uses a heuristic parser. Must be expressed using the reference time 'Mon Jan 2 15:04:05 -0700 MST 2006'")
 	cmd.Flags().StringVarP(&opts.Delimiter, "delimiter", "d", "", "Delimiter that separates CSV fields (only valid when input is CSV")
 	cmd.Flags().DurationVar(&opts.FlushEvery, "flush-every", time.Second*5, "Buffer flush interval for batchable data")
 	cmd.Flags().UintVarP(&opts.BatchSize, "batch-size", "b", 10_000, "Batch size to aim for")
-	cmd.Flags().StringVarP(&opts.contentType, "content-type", "t", "", "Content type of the data to ingest (will auto-detect if not set, must be set if content encoding is set and content type is not identity)")
+	cmd.Flags().StringVarP(&opts.contentType, "content-type", "t", "", "Content type of the data to ingest, or journal, docker or cri to parse systemd journal entries or container logs (will auto-detect if not set, must be set if content encoding is set and content type is not identity)")
+	cmd.Flags().StringVar(&opts.FromFormat, "from-format", "", "Export format of another log platform to parse: elastic, splunk, cloudwatch or loki")
 	cmd.Flags().StringVarP(&opts.contentEncoding, "content-encoding", "e", axiom.Identity.String(), "Content encoding of the data to ingest")
 	cmd.Flags().StringSliceVarP(&opts.labels, "label", "l", nil, "Labels to attach to the ingested events, server side")
 	cmd.Flags().StringSliceVar(&opts.csvFields, "csv-fields", nil, "CSV header fields to use as event field names, server side (e.g. if there is no header row)")
+	cmd.Flags().BoolVar(&opts.CSVInferTypes, "csv-infer-types", false, "Convert CSV data to typed JSON client-side, inferring the column types from a sample")
+	cmd.Flags().StringSliceVar(&opts.csvTypes, "csv-types", nil, "Types of CSV columns (int, float, bool, timestamp or string), converts CSV data to typed JSON client-side (e.g. status:int)")
+	cmd.Flags().UintVar(&opts.CSVSampleSize, "csv-sample-size", 100, "Number of CSV rows to infer the column types from")
 	cmd.Flags().BoolVar(&opts.ContinueOnError, "continue-on-error", false, "Don't fail on ingest errors (use with care!)")
+	cmd.Flags().StringVar(&opts.Schema, "schema", "", "JSON Schema file to validate events against, client-side")
+	cmd.Flags().StringVar(&opts.RejectsFile, "rejects-file", "", "File to write events rejected client-side to, along with the reasons (defaults to dropping them)")
+	cmd.Flags().StringVar(&opts.maxEventSize, "max-event-size", "1MiB", "Maximum size of a single event when ingesting batchable data")
+	cmd.Flags().StringVar(&opts.OversizePolicy, "oversized", oversizeFail, "What to do with events exceeding the maximum event size: fail, skip, truncate or split")
+	cmd.Flags().StringVar(&opts.OversizeField, "oversized-field", "", "Field to truncate or split for events exceeding the maximum event size")
+	cmd.Flags().StringSliceVar(&opts.Enrich, "enrich", nil, "Enrichment presets whose fields to add to every event, client-side (host, process, git, k8s or ci)")
+	cmd.Flags().StringSliceVar(&opts.GeoIPFields, "geoip-field", nil, "Fields holding IP addresses to add the geographical location of, client-side")
+	cmd.Flags().StringSliceVar(&opts.GeoIPDatabases, "geoip-db", nil, "MaxMind database files (.mmdb) to look up IP addresses in (e.g. GeoLite2 City and ASN)")
+	cmd.Flags().StringSliceVar(&opts.UserAgentFields, "ua-field", nil, "Fields holding user agent strings to parse, client-side")
+	cmd.Flags().StringArrayVar(&opts.Also, "also", nil, "Additional dataset to send the data to, optionally on another configured deployment (<deployment>:<dataset>)")
 
 	_ = cmd.RegisterFlagCompletionFunc("timestamp-field", cmdutil.NoCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("timestamp-format", cmdutil.NoCompletion)
@@ -224,10 +540,23 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 	_ = cmd.RegisterFlagCompletionFunc("flush-every", cmdutil.NoCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("batch-size", cmdutil.NoCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("content-type", contentTypeCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("from-format", fromFormatCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("content-encoding", contentEncodingCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("label", cmdutil.NoCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("csv-fields", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("csv-infer-types", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("csv-types", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("csv-sample-size", cmdutil.NoCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("continue-on-error", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("schema", jsonFileCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("max-event-size", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("oversized", oversizePolicyCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("oversized-field", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("enrich", enrichPresetCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("geoip-field", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("geoip-db", mmdbFileCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("ua-field", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("also", destinationCompletionFunc(f))
 
 	if opts.IO.IsStdinTTY() {
 		_ = cmd.MarkFlagRequired("file")
@@ -236,6 +565,13 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 	return cmd
 }
 
+// processesClientSide returns true if events are processed client-side before
+// they are sent to the server.
+func (opts *options) processesClientSide() bool {
+	return len(opts.processors) > 0 || opts.CSVInferTypes || len(opts.CSVTypes) > 0 ||
+		opts.InputFormat != ""
+}
+
 func complete(ctx context.Context, opts *options) error {
 	if opts.Dataset != "" {
 		return nil
@@ -283,30 +619,66 @@ func run(ctx context.Context, opts *options, flushEverySet, batchSizeSet, csvFie
 		return err
 	}
 
+	var rejects io.WriteCloser
+	if opts.RejectsFile != "" {
+		if rejects, err = os.Create(opts.RejectsFile); err != nil {
+			return err
+		}
+		defer rejects.Close()
+	}
+
 	stop := opts.IO.StartActivityIndicator()
 	defer stop()
 
+	// A destination that can't be ingested into is reported and skipped, it
+	// never fails the ingestion.
+	for _, dest := range opts.destinations {
+		if dest.Deployment == "" {
+			dest.client = client
+		} else if dest.client, dest.err = opts.DeploymentClient(ctx, dest.Deployment); dest.err != nil {
+			dest.client = nil
+			continue
+		}
+		dest.start(ctx, opts)
+		defer dest.stop()
+	}
+
 	var (
-		res     = new(ingest.Status)
-		lastErr error
+		res           = new(ingest.Status)
+		pipelineStats = new(pipelineStats)
+		oversizeStats = new(oversizeStats)
+		lastErr       error
 	)
-	for _, filename := range opts.Filenames {
-		var rc io.ReadCloser
-		if filename == "-" {
-			rc = opts.IO.In()
-			filename = "stdin" // Enhance printed output
-		} else {
-			if rc, err = os.Open(filename); err != nil {
-				lastErr = err
+	sources, err := resolveSources(ctx, opts, opts.Filenames)
+	if err != nil {
+		return err
+	}
+
+	for _, src := range sources {
+		filename := src.Name
+		rc, err := src.Open(ctx)
+		if err != nil {
+			lastErr = fmt.Errorf("could not open %q: %w", filename, err)
+			break
+		}
+
+		// Compressed data is decompressed client-side, unless the content
+		// encoding is explicitly set.
+		if opts.ContentEncoding == axiom.Identity {
+			drc, err := decompress(rc)
+			if err != nil {
+				_ = rc.Close()
+				lastErr = fmt.Errorf("could not decompress %q: %w", filename, err)
 				break
 			}
+			rc = drc
 		}
 
 		var (
 			r   io.Reader
 			typ axiom.ContentType
 		)
-		if opts.ContentEncoding == axiom.Identity && opts.ContentType == 0 {
+		if opts.ContentEncoding == axiom.Identity && opts.ContentType == 0 && opts.InputFormat == "" {
 			if r, typ, err = axiom.DetectContentType(rc); err != nil {
 				_ = rc.Close()
 				lastErr = fmt.Errorf("could not detect %q content type: %w", filename, err)
@@ -321,22 +693,44 @@ func run(ctx context.Context, opts *options, flushEverySet, batchSizeSet, csvFie
 			return cmdutil.NewFlagErrorf("--delimier/-d not valid when content type is not CSV")
 		}
 
+		// Events that are processed client-side are passed on to the server as
+		// newline delimited JSON.
+		stopPipeline := func() {}
+		if opts.processesClientSide() {
+			if opts.ContentEncoding != axiom.Identity {
+				return cmdutil.NewFlagErrorf("client-side processing not valid when content encoding is set")
+			}
+			er, err := newEventReader(r, typ, opts, filename)
+			if err != nil {
+				return err
+			}
+			r, stopPipeline = startPipeline(ctx, filename, er, opts.processors, rejects, pipelineStats)
+			typ = axiom.NDJSON
+		}
+
 		var (
 			batchable = (typ == axiom.NDJSON || (typ == axiom.CSV && csvFieldsSet)) &&
 				opts.ContentEncoding == axiom.Identity
 			ingestRes *ingest.Status
 		)
 		if batchable {
-			ingestRes, err = ingestEvery(ctx, client, r, typ, opts)
+			if typ == axiom.CSV && (opts.OversizePolicy == oversizeTruncate || opts.OversizePolicy == oversizeSplit) {
+				return cmdutil.NewFlagErrorf("--oversized=%s not valid when content type is CSV", opts.OversizePolicy)
+			}
+			ingestRes, err = ingestEvery(ctx, client, r, typ, opts, oversizeStats)
 		} else {
 			if flushEverySet {
 				return cmdutil.NewFlagErrorf("--flush-every not valid when data is not batchable")
 			} else if batchSizeSet {
 				return cmdutil.NewFlagErrorf("--batch-size not valid when data is not batchable")
+			} else if opts.OversizePolicy != oversizeFail {
+				return cmdutil.NewFlagErrorf("--oversized not valid when data is not batchable")
 			}
-			ingestRes, err = ingestReader(ctx, client, r, typ, opts)
+			ingestRes, err = ingestTee(ctx, client, r, typ, opts)
 		}
 
+		stopPipeline()
+
 		// Error handling below, so we need to check for nil.
 		if ingestRes != nil {
 			res.Add(ingestRes)
@@ -354,6 +748,10 @@ func run(ctx context.Context, opts *options, flushEverySet, batchSizeSet, csvFie
 		}
 	}
 
+	for _, dest := range opts.destinations {
+		dest.stop()
+	}
+
 	stop()
 
 	if opts.IO.IsStderrTTY() {
@@ -382,10 +780,77 @@ func run(ctx context.Context, opts *options, flushEverySet, batchSizeSet, csvFie
 		}
 	}
 
+	// Failures of additional destinations are always reported, as they don't
+	// fail the ingestion.
+	for _, dest := range opts.destinations {
+		cs := opts.IO.ColorScheme()
+		switch {
+		case dest.client == nil:
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Did not ingest into %s: %v\n",
+				cs.ErrorIcon(), cs.Bold(dest.String()), dest.err)
+		case dest.failedBatches > 0:
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Failed to ingest into %s (%s): %v\n",
+				cs.ErrorIcon(), cs.Bold(dest.String()),
+				utils.Pluralize(cs, "failed request", int(dest.failedBatches)), dest.err)
+		case dest.res.Failed > 0:
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Failed to ingest %s into %s\n",
+				cs.ErrorIcon(), utils.Pluralize(cs, "event", int(dest.res.Failed)),
+				cs.Bold(dest.String()))
+		case opts.IO.IsStderrTTY():
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Ingested %s into %s\n",
+				cs.SuccessIcon(), utils.Pluralize(cs, "event", int(dest.res.Ingested)),
+				cs.Bold(dest.String()))
+		}
+		if dropped := dest.droppedBatches.Load(); dropped > 0 {
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Dropped %s to %s, which did not keep up\n",
+				cs.WarningIcon(), utils.Pluralize(cs, "request", int(dropped)), cs.Bold(dest.String()))
+		}
+	}
+
+	// Oversized events and rejections are always reported, as they are dealt
+	// with client-side and never seen by the server.
+	if affected := oversizeStats.Affected.Load(); affected > 0 {
+		var (
+			cs     = opts.IO.ColorScheme()
+			events = utils.Pluralize(cs, "oversized event", int(affected))
+		)
+		switch opts.OversizePolicy {
+		case oversizeSkip:
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Skipped %s\n", cs.WarningIcon(), events)
+		case oversizeTruncate:
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Truncated field %s of %s\n",
+				cs.WarningIcon(), cs.Bold(opts.OversizeField), events)
+		case oversizeSplit:
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Split field %s of %s into %s\n",
+				cs.WarningIcon(), cs.Bold(opts.OversizeField), events,
+				utils.Pluralize(cs, "event", int(oversizeStats.Emitted.Load())))
+		}
+	}
+	if rejected, rejections := pipelineStats.Rejected(); rejected > 0 {
+		cs := opts.IO.ColorScheme()
+		fmt.Fprintf(opts.IO.ErrOut(), "%s Rejected %s:\n\n",
+			cs.ErrorIcon(),
+			utils.Pluralize(cs, "event", int(rejected)),
+		)
+		for _, rejection := range rejections {
+			for _, reason := range rejection.Reasons {
+				fmt.Fprintf(opts.IO.ErrOut(), "%s: %s\n",
+					cs.Gray(fmt.Sprintf("%s: event %d", rejection.Source, rejection.Index)), reason,
+				)
+			}
+		}
+		if more := rejected - uint64(len(rejections)); more > 0 {
+			fmt.Fprintf(opts.IO.ErrOut(), "... and %s more\n", utils.Pluralize(cs, "event", int(more)))
+		}
+		if opts.RejectsFile != "" {
+			fmt.Fprintf(opts.IO.ErrOut(), "\nRejected events written to %s\n", cs.Bold(opts.RejectsFile))
+		}
+	}
+
 	return lastErr
 }
 
-func ingestEvery(ctx context.Context, client *axiom.Client, r io.Reader, typ axiom.ContentType, opts *options) (*ingest.Status, error) {
+func ingestEvery(ctx context.Context, client *axiom.Client, r io.Reader, typ axiom.ContentType, opts *options, oversizeStats *oversizeStats) (*ingest.Status, error) {
 	t := time.NewTicker(opts.FlushEvery)
 	defer t.Stop()
 
@@ -397,14 +862,21 @@ func ingestEvery(ctx context.Context, client *axiom.Client, r io.Reader, typ axi
 		pr, pw := io.Pipe()
 		readers <- pr
 
-		// Start with a 1 KB buffer, check up until 1 MB per line.
+		// Start with a 1 KB buffer, check up until the maximum event size per
+		// line. Longer lines are subject to the oversize policy.
+		splitter := &oversizeSplitter{
+			maxSize: opts.MaxEventSize,
+			policy:  opts.OversizePolicy,
+			field:   opts.OversizeField,
+			stats:   oversizeStats,
+		}
 		scanner := bufio.NewScanner(r)
-		scanner.Buffer(make([]byte, 1024), 1024*1024)
-		scanner.Split(splitLinesMulti)
+		scanner.Buffer(make([]byte, min(1024, opts.MaxEventSize)), opts.MaxEventSize)
+		scanner.Split(splitter.Split)
 
 		// We need to scan in a go func to make sure we don't block on
-		// `scanner.Scan()`.
-		done := make(chan struct{})
+		// `scanner.Scan()`. Scan errors are passed on to the last reader.
+		done := make(chan error, 1)
 		lines := make(chan []byte)
 		defer close(lines)
 		go func() {
@@ -418,7 +890,7 @@ func ingestEvery(ctx context.Context, client *axiom.Client, r io.Reader, typ axi
 				}
 
 				if !scanner.Scan() {
-					close(done)
+					done <- scanner.Err()
 					return
 				}
 
@@ -457,8 +929,8 @@ func ingestEvery(ctx context.Context, client *axiom.Client, r io.Reader, typ axi
 					return
 				}
 				lineCount++
-			case <-done:
-				_ = pw.Close()
+			case err := <-done:
+				_ = pw.CloseWithError(err)
 				return
 			default:
 				time.Sleep(time.Millisecond)
@@ -468,7 +940,7 @@ func ingestEvery(ctx context.Context, client *axiom.Client, r io.Reader, typ axi
 
 	var res ingest.Status
 	for r := range readers {
-		ingestRes, err := ingestReader(ctx, client, r, typ, opts)
+		ingestRes, err := ingestTee(ctx, client, r, typ, opts)
 		if err != nil {
 			if opts.ContinueOnError {
 				fmt.Fprintf(opts.IO.ErrOut(), "%s Failed to ingest: %v, continuing...\n",
@@ -483,7 +955,7 @@ func ingestEvery(ctx context.Context, client *axiom.Client, r io.Reader, typ axi
 	return &res, nil
 }
 
-func ingestReader(ctx context.Context, client *axiom.Client, r io.Reader, typ axiom.ContentType, opts *options) (*ingest.Status, error) {
+func ingestReader(ctx context.Context, client *axiom.Client, dataset string, r io.Reader, typ axiom.ContentType, opts *options) (*ingest.Status, error) {
 	// If the data to ingest is not compressed, it gets zstd compressed.
 	enc := opts.ContentEncoding
 	if enc == axiom.Identity {
@@ -503,13 +975,15 @@ func ingestReader(ctx context.Context, client *axiom.Client, r io.Reader, typ ax
 	if v := opts.TimestampFormat; v != "" {
 		ingestOptions = append(ingestOptions, ingest.SetTimestampFormat(v))
 	}
-	if v := opts.Delimiter; v != "" {
+	if v := opts.Delimiter; v != "" && typ == axiom.CSV {
 		ingestOptions = append(ingestOptions, ingest.SetCSVDelimiter(v))
 	}
 	ingestOptions = append(ingestOptions, opts.Labels...)
-	ingestOptions = append(ingestOptions, opts.CSVFields...)
+	if typ == axiom.CSV {
+		ingestOptions = append(ingestOptions, opts.CSVFields...)
+	}
 
-	res, err := client.Ingest(ctx, opts.Dataset, r, typ, enc, ingestOptions...)
+	res, err := client.Ingest(ctx, dataset, r, typ, enc, ingestOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -536,8 +1010,8 @@ func splitLinesMulti(data []byte, atEOF bool) (advance int, token []byte, err er
 }
 
 func contentTypeCompletion(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	res := make([]string, 0, len(validContentTypes))
-	for _, contentType := range validContentTypes {
+	res := make([]string, 0, len(validContentTypes)+len(validInputFormats))
+	for _, contentType := range slices.Concat(validContentTypes, validInputFormats) {
 		if strings.HasPrefix(contentType, toComplete) {
 			res = append(res, contentType)
 		}
@@ -545,6 +1019,48 @@ func contentTypeCompletion(_ *cobra.Command, _ []string, toComplete string) ([]s
 	return res, cobra.ShellCompDirectiveNoFileComp
 }
 
+// destinationCompletionFunc completes the aliases of the configured
+// deployments and the datasets of the active deployment.
+func destinationCompletionFunc(f *cmdutil.Factory) cmdutil.CompletionFunc {
+	datasetCompletion := cmdutil.DatasetCompletionFunc(f)
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if strings.Contains(toComplete, ":") {
+			return cmdutil.NoCompletion(cmd, args, toComplete)
+		}
+
+		// Deployment aliases are completed up to the colon, so the dataset can
+		// be typed right after.
+		res, directive := datasetCompletion(cmd, nil, toComplete)
+		for _, alias := range f.Config.DeploymentAliases() {
+			if strings.HasPrefix(alias, toComplete) {
+				res = append(res, alias+":")
+				directive |= cobra.ShellCompDirectiveNoSpace
+			}
+		}
+		return res, directive
+	}
+}
+
+func enrichPresetCompletion(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	res := make([]string, 0, len(validEnrichPresets))
+	for _, preset := range validEnrichPresets {
+		if strings.HasPrefix(preset, toComplete) {
+			res = append(res, preset)
+		}
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
+func fromFormatCompletion(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	res := make([]string, 0, len(validFromFormats))
+	for _, format := range validFromFormats {
+		if strings.HasPrefix(format, toComplete) {
+			res = append(res, format)
+		}
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
 func contentEncodingCompletion(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	res := make([]string, 0, len(validContentEncodings))
 	for _, contentEncoding := range validContentEncodings {
@@ -555,6 +1071,24 @@ func contentEncodingCompletion(_ *cobra.Command, _ []string, toComplete string)
 	return res, cobra.ShellCompDirectiveNoFileComp
 }
 
+func jsonFileCompletion(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+func mmdbFileCompletion(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{"mmdb"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+func oversizePolicyCompletion(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	res := make([]string, 0, len(validOversizePolicies))
+	for _, policy := range validOversizePolicies {
+		if strings.HasPrefix(policy, toComplete) {
+			res = append(res, policy)
+		}
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
 func contentTypeFromString(s string) (ct axiom.ContentType, err error) {
 	switch strings.ToLower(s) {
 	case "json":
@@ -0,0 +1,247 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+)
+
+// formatJournal is the client-side input format of the systemd journal, as
+// exported by "journalctl -o export" or "journalctl -o json".
+const formatJournal = "journal"
+
+const (
+	// journalRealtimeField is the journal field holding the wallclock time of
+	// an entry, in microseconds since the epoch.
+	journalRealtimeField = "__REALTIME_TIMESTAMP"
+	// maxJournalFieldSize is the maximum size of a single binary field in the
+	// journal export format.
+	maxJournalFieldSize = 1 << 30
+)
+
+// journalEventReader reads events from systemd journal entries in the journal
+// export format or the journal JSON format, which is detected from the first
+// byte of the input. Every journal field becomes an event field. Fields that
+// occur multiple times in an entry become arrays. The realtime timestamp of an
+// entry is used as the event timestamp.
+type journalEventReader struct {
+	r *bufio.Reader
+
+	// dec is set when the input is in the journal JSON format.
+	dec  *json.Decoder
+	init bool
+}
+
+func newJournalEventReader(r io.Reader) *journalEventReader {
+	return &journalEventReader{
+		r: bufio.NewReader(r),
+	}
+}
+
+// ReadEvent implements eventReader.
+func (er *journalEventReader) ReadEvent() (map[string]any, error) {
+	if !er.init {
+		if err := er.initialize(); err != nil {
+			return nil, err
+		}
+		er.init = true
+	}
+
+	var (
+		event map[string]any
+		err   error
+	)
+	if er.dec != nil {
+		event, err = er.readJSONEntry()
+	} else {
+		event, err = er.readExportEntry()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = setJournalTime(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// setJournalTime sets the realtime timestamp of a journal entry as the event
+// timestamp, unless the event already has one.
+func setJournalTime(event map[string]any) error {
+	ts, ok := event[journalRealtimeField].(string)
+	if !ok {
+		return nil
+	} else if _, ok = event["_time"]; ok {
+		return nil
+	}
+
+	usec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid journal field %s: %w", journalRealtimeField, err)
+	}
+	event["_time"] = time.UnixMicro(usec).UTC().Format(time.RFC3339Nano)
+
+	return nil
+}
+
+// initialize detects the format of the input. Entries in the journal JSON
+// format are JSON objects, the journal export format starts with a field name.
+func (er *journalEventReader) initialize() error {
+	for {
+		b, err := er.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{':
+			er.dec = json.NewDecoder(er.r)
+			er.dec.UseNumber()
+		}
+		return er.r.UnreadByte()
+	}
+}
+
+// readExportEntry reads a single entry in the journal export format. Fields
+// are either "<name>=<value>" lines or, for binary-safe values, the field name
+// on its own line followed by the size of the value as 64 bit little endian
+// integer, the value and a newline. Entries are separated by an empty line.
+func (er *journalEventReader) readExportEntry() (map[string]any, error) {
+	event := make(map[string]any)
+	for {
+		line, err := er.r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			if len(event) == 0 {
+				return nil, io.EOF
+			}
+			return event, nil
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+
+		if len(line) == 0 {
+			// Skip superfluous empty lines between entries.
+			if len(event) == 0 {
+				continue
+			}
+			return event, nil
+		}
+
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			addJournalField(event, string(name), string(value))
+			continue
+		}
+
+		value, err := er.readBinaryValue()
+		if err != nil {
+			return nil, fmt.Errorf("read journal field %s: %w", line, err)
+		}
+		addJournalField(event, string(line), value)
+	}
+}
+
+func (er *journalEventReader) readBinaryValue() (string, error) {
+	var size uint64
+	if err := binary.Read(er.r, binary.LittleEndian, &size); err != nil {
+		return "", err
+	} else if size > maxJournalFieldSize {
+		return "", fmt.Errorf("value of %s exceeds the maximum size of %s",
+			humanize.IBytes(size), humanize.IBytes(maxJournalFieldSize))
+	}
+
+	// The value is followed by a newline.
+	value := make([]byte, size+1)
+	if _, err := io.ReadFull(er.r, value); err != nil {
+		return "", err
+	} else if value[size] != '\n' {
+		return "", errors.New("value not terminated by newline")
+	}
+
+	return string(value[:size]), nil
+}
+
+// readJSONEntry reads a single entry in the journal JSON format. Values that
+// are not valid UTF-8 are encoded as arrays of bytes and converted back to
+// strings, if possible.
+func (er *journalEventReader) readJSONEntry() (map[string]any, error) {
+	var event map[string]any
+	if err := er.dec.Decode(&event); err != nil {
+		return nil, err
+	}
+	convertJournalJSONEntry(event)
+	return event, nil
+}
+
+// convertJournalJSONEntry converts the values of an entry in the journal JSON
+// format that are arrays of bytes to strings.
+func convertJournalJSONEntry(event map[string]any) {
+	for name, value := range event {
+		values, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		if s, ok := journalBytesToString(values); ok {
+			event[name] = s
+			continue
+		}
+		// A field that occurs multiple times in an entry is an array of its
+		// values.
+		for i, v := range values {
+			if b, ok := v.([]any); ok {
+				if s, ok := journalBytesToString(b); ok {
+					values[i] = s
+				}
+			}
+		}
+	}
+}
+
+func addJournalField(event map[string]any, name, value string) {
+	switch existing := event[name].(type) {
+	case nil:
+		event[name] = value
+	case []any:
+		event[name] = append(existing, value)
+	default:
+		event[name] = []any{existing, value}
+	}
+}
+
+// journalBytesToString converts an array of bytes, as used by the journal JSON
+// format for binary values, to a string. It returns false if the array doesn't
+// hold bytes or they are not valid UTF-8.
+func journalBytesToString(values []any) (string, bool) {
+	if len(values) == 0 {
+		return "", false
+	}
+
+	b := make([]byte, len(values))
+	for i, v := range values {
+		n, ok := v.(json.Number)
+		if !ok {
+			return "", false
+		}
+		c, err := strconv.ParseUint(n.String(), 10, 8)
+		if err != nil {
+			return "", false
+		}
+		b[i] = byte(c)
+	}
+
+	if !utf8.Valid(b) {
+		return "", false
+	}
+	return string(b), true
+}
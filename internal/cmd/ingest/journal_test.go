@@ -0,0 +1,110 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalEventReader(t *testing.T) {
+	// A binary-safe field is the field name, the size of the value as 64 bit
+	// little endian integer, the value and a newline.
+	binaryField := func(name, value string) string {
+		var buf bytes.Buffer
+		buf.WriteString(name + "\n")
+		_ = binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value + "\n")
+		return buf.String()
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []map[string]any
+	}{
+		{
+			name: "export",
+			input: "__REALTIME_TIMESTAMP=1700000000123456\n" +
+				"_SYSTEMD_UNIT=sshd.service\n" +
+				"PRIORITY=6\n" +
+				binaryField("MESSAGE", "line one\nline two") +
+				"\n" +
+				"__REALTIME_TIMESTAMP=1700000001000000\n" +
+				"TAG=a\n" +
+				"TAG=b\n" +
+				"\n",
+			want: []map[string]any{
+				{
+					"_time":                "2023-11-14T22:13:20.123456Z",
+					"__REALTIME_TIMESTAMP": "1700000000123456",
+					"_SYSTEMD_UNIT":        "sshd.service",
+					"PRIORITY":             "6",
+					"MESSAGE":              "line one\nline two",
+				},
+				{
+					"_time":                "2023-11-14T22:13:21Z",
+					"__REALTIME_TIMESTAMP": "1700000001000000",
+					"TAG":                  []any{"a", "b"},
+				},
+			},
+		},
+		{
+			name:  "export without trailing empty line",
+			input: "MESSAGE=hello\n",
+			want:  []map[string]any{{"MESSAGE": "hello"}},
+		},
+		{
+			name: "json",
+			input: `{"__REALTIME_TIMESTAMP":"1700000000123456","PRIORITY":"3","MESSAGE":[104,105]}` + "\n" +
+				`{"MESSAGE":"x","TAG":["a",[98]],"BLOB":[255]}` + "\n",
+			want: []map[string]any{
+				{
+					"_time":                "2023-11-14T22:13:20.123456Z",
+					"__REALTIME_TIMESTAMP": "1700000000123456",
+					"PRIORITY":             "3",
+					"MESSAGE":              "hi",
+				},
+				{
+					"MESSAGE": "x",
+					"TAG":     []any{"a", "b"},
+					"BLOB":    []any{json.Number("255")},
+				},
+			},
+		},
+		{
+			name:  "empty",
+			input: "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			er := newJournalEventReader(strings.NewReader(tt.input))
+
+			var got []map[string]any
+			for {
+				event, err := er.ReadEvent()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, event)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestJournalEventReader_Truncated(t *testing.T) {
+	er := newJournalEventReader(strings.NewReader("MESSAGE\n\x05\x00\x00\x00\x00\x00\x00\x00ab"))
+
+	_, err := er.ReadEvent()
+	assert.ErrorContains(t, err, "read journal field MESSAGE")
+}
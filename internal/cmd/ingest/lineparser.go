@@ -0,0 +1,93 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Line formats understood by a LineParser, besides the client-side input
+// formats of container logs and the systemd journal.
+const (
+	// formatText keeps every line in the "message" field.
+	formatText = "text"
+	// formatJSON expects every line to be a JSON object.
+	formatJSON = "json"
+)
+
+// LineFormats returns the formats a LineParser can be created for.
+func LineFormats() []string {
+	return []string{formatText, formatJSON, formatDocker, formatCRI, formatJournal}
+}
+
+// A LineParser parses events from the lines of a log, one line at a time. It
+// is used by long-running readers of logs, like the agent, which can't hand
+// over an io.Reader.
+type LineParser interface {
+	// ParseLine parses a single line, without the trailing newline. It returns
+	// a nil event if the line doesn't complete an event, e.g. because it is
+	// empty or the container runtime split it.
+	ParseLine(line []byte) (map[string]any, error)
+}
+
+// NewLineParser returns a LineParser for the given format. The name of the
+// log, e.g. its file path, is used to derive metadata where supported.
+// Container logs read from a kubelet log file get the namespace, pod and
+// container added, for example. Journal entries must be in the journal JSON
+// format, as written by "journalctl -o json".
+func NewLineParser(format, name string) (LineParser, error) {
+	switch format {
+	case formatText:
+		return lineParserFunc(parseTextLine), nil
+	case formatJSON:
+		return lineParserFunc(parseJSONLine), nil
+	case formatDocker, formatCRI:
+		return newContainerLogParser(format, name), nil
+	case formatJournal:
+		return lineParserFunc(parseJournalLine), nil
+	}
+	return nil, fmt.Errorf("invalid line format %q (valid formats: %s)",
+		format, strings.Join(LineFormats(), ", "))
+}
+
+type lineParserFunc func([]byte) (map[string]any, error)
+
+// ParseLine implements LineParser.
+func (f lineParserFunc) ParseLine(line []byte) (map[string]any, error) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil, nil
+	}
+	return f(line)
+}
+
+func parseTextLine(line []byte) (map[string]any, error) {
+	return map[string]any{"message": string(line)}, nil
+}
+
+func parseJSONLine(line []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+
+	var event map[string]any
+	if err := dec.Decode(&event); err != nil {
+		return nil, fmt.Errorf("malformed JSON line: %w", err)
+	} else if event == nil {
+		return nil, errors.New("malformed JSON line: expected a JSON object")
+	}
+	return event, nil
+}
+
+func parseJournalLine(line []byte) (map[string]any, error) {
+	event, err := parseJSONLine(line)
+	if err != nil {
+		return nil, err
+	}
+	convertJournalJSONEntry(event)
+
+	if err = setJournalTime(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,90 @@
+package ingest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLineParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		path   string
+		lines  []string
+		want   []map[string]any
+	}{
+		{
+			name:   "text",
+			format: formatText,
+			lines:  []string{"hello world", "", `{"not":"parsed"}`},
+			want: []map[string]any{
+				{"message": "hello world"},
+				{"message": `{"not":"parsed"}`},
+			},
+		},
+		{
+			name:   "json",
+			format: formatJSON,
+			lines:  []string{`{"level":"info","status":200}`, " "},
+			want: []map[string]any{
+				{"level": "info", "status": json.Number("200")},
+			},
+		},
+		{
+			name:   "cri",
+			format: formatCRI,
+			path:   "/var/log/pods/default_web-7d4b9_0f1e2d3c-aaaa-bbbb-cccc-123456789abc/nginx/0.log",
+			lines: []string{
+				"2024-01-01T00:00:01Z stdout P part one, ",
+				"2024-01-01T00:00:01Z stdout F part two",
+			},
+			want: []map[string]any{
+				{"_time": "2024-01-01T00:00:01Z", "stream": "stdout", "message": "part one, part two", "kubernetes": testPodMetadata},
+			},
+		},
+		{
+			name:   "journal",
+			format: formatJournal,
+			lines: []string{
+				`{"__REALTIME_TIMESTAMP":"1700000000123456","MESSAGE":[104,105],"_SYSTEMD_UNIT":"sshd.service"}`,
+			},
+			want: []map[string]any{
+				{
+					"_time":                "2023-11-14T22:13:20.123456Z",
+					"__REALTIME_TIMESTAMP": "1700000000123456",
+					"MESSAGE":              "hi",
+					"_SYSTEMD_UNIT":        "sshd.service",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewLineParser(tt.format, tt.path)
+			require.NoError(t, err)
+
+			var got []map[string]any
+			for _, line := range tt.lines {
+				event, err := p.ParseLine([]byte(line))
+				require.NoError(t, err)
+				if event != nil {
+					got = append(got, event)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewLineParser_Invalid(t *testing.T) {
+	_, err := NewLineParser("xml", "")
+	assert.EqualError(t, err, "invalid line format \"xml\" (valid formats: text, json, docker, cri, journal)")
+
+	p, err := NewLineParser(formatJSON, "")
+	require.NoError(t, err)
+	_, err = p.ParseLine([]byte(`[1,2]`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,277 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Policies for events that exceed the maximum event size.
+const (
+	oversizeFail     = "fail"
+	oversizeSkip     = "skip"
+	oversizeTruncate = "truncate"
+	oversizeSplit    = "split"
+)
+
+var validOversizePolicies = []string{
+	oversizeFail,
+	oversizeSkip,
+	oversizeTruncate,
+	oversizeSplit,
+}
+
+// oversizeStats keeps track of the events affected by the oversize policy. It
+// is safe for concurrent use.
+type oversizeStats struct {
+	// Affected is the number of oversized events the policy was applied to.
+	Affected atomic.Uint64
+	// Emitted is the number of events the split policy turned the oversized
+	// events into.
+	Emitted atomic.Uint64
+}
+
+// oversizeSplitter is a bufio.SplitFunc like splitLinesMulti that applies an
+// oversize policy to lines exceeding the maximum event size instead of
+// failing with bufio.ErrTooLong. It must be used with a bufio.Scanner whose
+// maximum buffer size is the maximum event size.
+type oversizeSplitter struct {
+	maxSize int
+	policy  string
+	field   string
+	stats   *oversizeStats
+
+	// oversized is true while the remainder of an oversized line is read.
+	oversized bool
+	// line is the oversized line read so far. Only collected by the truncate
+	// and split policies.
+	line []byte
+}
+
+// Split implements bufio.SplitFunc.
+func (s *oversizeSplitter) Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if s.oversized {
+		i := bytes.IndexByte(data, '\n')
+		switch {
+		case i >= 0:
+			s.collect(data[:i])
+			token, err = s.finish()
+			return i + 1, token, err
+		case atEOF:
+			s.collect(data)
+			token, err = s.finish()
+			return len(data), token, err
+		}
+		s.collect(data)
+		return len(data), nil, nil
+	}
+
+	advance, token, err = splitLinesMulti(data, atEOF)
+	if advance > 0 || err != nil || len(data) < s.maxSize {
+		return advance, token, err
+	}
+
+	// The buffer is full but doesn't hold a single complete line, so the
+	// current line exceeds the maximum event size.
+	if s.policy == oversizeFail {
+		return 0, nil, fmt.Errorf("event exceeds the maximum event size of %s (see --max-event-size and --oversized)",
+			humanize.IBytes(uint64(s.maxSize)))
+	}
+	s.oversized = true
+	s.collect(data)
+	return len(data), nil, nil
+}
+
+func (s *oversizeSplitter) collect(data []byte) {
+	if s.policy == oversizeTruncate || s.policy == oversizeSplit {
+		s.line = append(s.line, data...)
+	}
+}
+
+// finish applies the policy to the oversized line read and returns the
+// resulting token, if any.
+func (s *oversizeSplitter) finish() ([]byte, error) {
+	defer func() {
+		s.oversized = false
+		s.line = nil
+	}()
+
+	s.stats.Affected.Add(1)
+
+	if s.policy == oversizeSkip {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(s.line))
+	dec.UseNumber()
+
+	var event map[string]any
+	if err := dec.Decode(&event); err != nil {
+		return nil, fmt.Errorf("%s oversized event: %w", s.policy, err)
+	}
+
+	var (
+		lines [][]byte
+		err   error
+	)
+	if s.policy == oversizeTruncate {
+		var line []byte
+		line, err = truncateEvent(event, s.field, s.maxSize)
+		lines = [][]byte{line}
+	} else {
+		lines, err = splitEvent(event, s.field, s.maxSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.stats.Emitted.Add(uint64(len(lines)))
+
+	return bytes.Join(lines, nil), nil
+}
+
+// truncateEvent truncates the string value of the given field so that the
+// newline terminated JSON encoding of the event fits into maxSize bytes.
+func truncateEvent(event map[string]any, field string, maxSize int) ([]byte, error) {
+	value, budget, err := fieldBudget(event, field, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	event[field] = fitString(value, budget)
+
+	return encodeLine(event)
+}
+
+// splitEvent splits the string value of the given field into parts and
+// returns one newline terminated JSON encoded event per part, each fitting into
+// maxSize bytes. All other fields are copied to every part. The fields
+// "<field>_part" and "<field>_parts" hold the index of the part, starting at
+// one, and the total number of parts.
+func splitEvent(event map[string]any, field string, maxSize int) ([][]byte, error) {
+	// Reserve space for the part fields, assuming the part count is never
+	// larger than the line length.
+	partField, partsField := field+"_part", field+"_parts"
+	event[partField], event[partsField] = maxSize, maxSize
+
+	value, budget, err := fieldBudget(event, field, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []string
+	for value != "" {
+		part := fitString(value, budget)
+		if part == "" {
+			return nil, fmt.Errorf("cannot split field %q of oversized event into parts", field)
+		}
+		parts = append(parts, part)
+		value = value[len(part):]
+	}
+
+	lines := make([][]byte, 0, len(parts))
+	for i, part := range parts {
+		event[field] = part
+		event[partField] = i + 1
+		event[partsField] = len(parts)
+
+		line, err := encodeLine(event)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// fieldBudget returns the string value of the field and the number of bytes
+// its JSON encoding can take up for the event to fit into maxSize bytes.
+func fieldBudget(event map[string]any, field string, maxSize int) (string, int, error) {
+	value, ok := event[field].(string)
+	if !ok {
+		return "", 0, fmt.Errorf("field %q of oversized event is missing or not a string", field)
+	}
+
+	event[field] = ""
+	line, err := encodeLine(event)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// The encoded event already contains the quotes of the empty string.
+	budget := maxSize - len(line) + len(`""`)
+	if budget <= len(`""`) {
+		return "", 0, fmt.Errorf("oversized event exceeds the maximum event size even without field %q", field)
+	}
+
+	return value, budget, nil
+}
+
+// fitString returns the longest prefix of s, cut at a rune boundary, whose
+// JSON encoding takes up at most budget bytes.
+func fitString(s string, budget int) string {
+	for {
+		n := encodedLen(s)
+		if n <= budget {
+			return s
+		}
+
+		// Cut off at least the excess bytes, more if escaping inflates the
+		// encoding.
+		cut := len(s) - (n - budget)
+		if cut <= 0 {
+			return ""
+		}
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut]
+	}
+}
+
+func encodedLen(s string) int {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return buf.Len() - 1 // Trailing newline.
+}
+
+func encodeLine(event map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(event); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func oversizePolicyFromString(s string) (string, error) {
+	s = strings.ToLower(s)
+	for _, policy := range validOversizePolicies {
+		if s == policy {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("invalid oversize policy %q", s)
+}
+
+// parseEventSize parses a human readable size like "1MiB" or "512KB".
+func parseEventSize(s string) (int, error) {
+	size, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, err
+	} else if size == 0 {
+		return 0, errors.New("maximum event size must be greater than zero")
+	} else if size > 1<<30 {
+		return 0, errors.New("maximum event size must not exceed " + humanize.IBytes(1<<30))
+	}
+	return int(size), nil
+}
@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOversizeSplitter(t *testing.T) {
+	const maxSize = 64
+
+	input := strings.Join([]string{
+		`{"n":1,"msg":"short"}`,
+		`{"n":2,"msg":"` + strings.Repeat("x", 150) + `"}`,
+		`{"n":3,"msg":"short"}`,
+	}, "\n") + "\n"
+
+	tests := []struct {
+		policy       string
+		wantLines    int
+		wantAffected uint64
+		wantErr      string
+	}{
+		{policy: oversizeFail, wantErr: "event exceeds the maximum event size of 64 B"},
+		{policy: oversizeSkip, wantLines: 2, wantAffected: 1},
+		{policy: oversizeTruncate, wantLines: 3, wantAffected: 1},
+		{policy: oversizeSplit, wantLines: 11, wantAffected: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			stats := new(oversizeStats)
+			splitter := &oversizeSplitter{
+				maxSize: maxSize,
+				policy:  tt.policy,
+				field:   "msg",
+				stats:   stats,
+			}
+
+			scanner := bufio.NewScanner(strings.NewReader(input))
+			scanner.Buffer(make([]byte, 16), maxSize)
+			scanner.Split(splitter.Split)
+
+			var out strings.Builder
+			for scanner.Scan() {
+				out.Write(scanner.Bytes())
+			}
+			if tt.wantErr != "" {
+				require.ErrorContains(t, scanner.Err(), tt.wantErr)
+				return
+			}
+			require.NoError(t, scanner.Err())
+
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			assert.Len(t, lines, tt.wantLines)
+			for _, line := range lines {
+				assert.LessOrEqual(t, len(line)+1, maxSize)
+			}
+			assert.Equal(t, tt.wantAffected, stats.Affected.Load())
+		})
+	}
+}
+
+func TestFitString(t *testing.T) {
+	assert.Equal(t, "abc", fitString("abc", 5))
+	assert.Equal(t, "ab", fitString("abc", 4))
+	assert.Equal(t, "ä", fitString("äö", 5))
+	assert.Equal(t, `a"`, fitString(`a"b`, 5))
+	assert.Equal(t, `a`, fitString(`a"b`, 4))
+}
@@ -0,0 +1,280 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/axiomhq/axiom-go/axiom"
+
+	"github.com/axiomhq/cli/internal/cmdutil"
+)
+
+// maxReportedRejections is the maximum number of rejected events whose
+// rejection reasons are reported to the user.
+const maxReportedRejections = 10
+
+// An eventReader reads events from an input stream, one at a time. It returns
+// io.EOF when there are no more events to read.
+type eventReader interface {
+	ReadEvent() (map[string]any, error)
+}
+
+// jsonEventReader reads events from newline delimited JSON or from a JSON
+// array of objects.
+type jsonEventReader struct {
+	dec   *json.Decoder
+	array bool
+	open  bool
+}
+
+func newJSONEventReader(r io.Reader, typ axiom.ContentType) *jsonEventReader {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return &jsonEventReader{
+		dec:   dec,
+		array: typ == axiom.JSON,
+	}
+}
+
+// ReadEvent implements eventReader.
+func (er *jsonEventReader) ReadEvent() (map[string]any, error) {
+	if er.array && !er.open {
+		tok, err := er.dec.Token()
+		if err != nil {
+			return nil, err
+		} else if tok != json.Delim('[') {
+			return nil, errors.New("expected a JSON array of objects")
+		}
+		er.open = true
+	}
+
+	if er.array && !er.dec.More() {
+		return nil, io.EOF
+	}
+
+	var event map[string]any
+	if err := er.dec.Decode(&event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// newEventReader returns an eventReader for data of the given content type or
+// the input format set in the options, read from the source with the given
+// name.
+func newEventReader(r io.Reader, typ axiom.ContentType, opts *options, name string) (eventReader, error) {
+	csvConversion := opts.CSVInferTypes || len(opts.CSVTypes) > 0
+	if csvConversion && typ != axiom.CSV {
+		return nil, cmdutil.NewFlagErrorf("CSV type conversion not valid when content type is not CSV")
+	}
+
+	switch opts.InputFormat {
+	case formatJournal:
+		return newJournalEventReader(r), nil
+	case formatDocker, formatCRI:
+		return newContainerLogEventReader(r, opts.InputFormat, name), nil
+	case fromElastic, fromSplunk, fromCloudWatch, fromLoki:
+		return newExportEventReader(r, opts.InputFormat)
+	}
+
+	switch typ {
+	case axiom.JSON, axiom.NDJSON:
+		return newJSONEventReader(r, typ), nil
+	case axiom.CSV:
+		var sampleSize int
+		if opts.CSVInferTypes {
+			sampleSize = int(opts.CSVSampleSize)
+		}
+		return newCSVEventReader(r, opts.Delimiter, opts.csvFields, opts.CSVTypes, sampleSize)
+	}
+	return nil, cmdutil.NewFlagErrorf("client-side processing not valid when content type is %s", typ)
+}
+
+// A processor inspects or modifies a single event before it is ingested. It
+// returns a *rejectError if the event must not be ingested.
+type processor interface {
+	Process(event map[string]any) error
+}
+
+// A rejectError is returned by a processor to reject an event.
+type rejectError struct {
+	// Reasons the event was rejected for.
+	Reasons []string
+}
+
+// Error implements the error interface.
+func (e *rejectError) Error() string {
+	return strings.Join(e.Reasons, "; ")
+}
+
+// rejection is a rejected event as reported to the user.
+type rejection struct {
+	// Source the event was read from.
+	Source string
+	// Index of the event in the source, starting at one.
+	Index uint64
+	// Reasons the event was rejected for.
+	Reasons []string
+}
+
+// pipelineStats keeps track of the events passing the client-side processing
+// pipeline. It is safe for concurrent use.
+type pipelineStats struct {
+	mu sync.Mutex
+
+	processed  uint64
+	rejected   uint64
+	rejections []rejection
+}
+
+func (s *pipelineStats) addProcessed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.processed++
+}
+
+func (s *pipelineStats) addRejected(source string, idx uint64, err *rejectError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rejected++
+	if len(s.rejections) < maxReportedRejections {
+		s.rejections = append(s.rejections, rejection{
+			Source:  source,
+			Index:   idx,
+			Reasons: err.Reasons,
+		})
+	}
+}
+
+// Rejected returns the number of rejected events and the rejections reported
+// to the user.
+func (s *pipelineStats) Rejected() (uint64, []rejection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rejected, s.rejections
+}
+
+// rejectedEvent is how a rejected event is written to the rejects file.
+type rejectedEvent struct {
+	Source  string         `json:"source"`
+	Index   uint64         `json:"index"`
+	Event   map[string]any `json:"event"`
+	Reasons []string       `json:"reasons"`
+}
+
+// runPipeline reads all events from er, which reads from the named source,
+// passes them through the processors in order and writes the accepted ones as
+// newline delimited JSON to w. Rejected events are written to rejects, if not
+// nil.
+func runPipeline(ctx context.Context, source string, er eventReader, w io.Writer, processors []processor, rejects io.Writer, stats *pipelineStats) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	var rejectsEnc *json.Encoder
+	if rejects != nil {
+		rejectsEnc = json.NewEncoder(rejects)
+		rejectsEnc.SetEscapeHTML(false)
+	}
+
+	for idx := uint64(1); ; idx++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		event, err := er.ReadEvent()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("read event %d: %w", idx, err)
+		} else if event == nil {
+			continue
+		}
+
+		if err = processEvent(event, processors); err != nil {
+			rejectErr, ok := errors.AsType[*rejectError](err)
+			if !ok {
+				return fmt.Errorf("process event %d: %w", idx, err)
+			}
+
+			stats.addRejected(source, idx, rejectErr)
+			if rejectsEnc != nil {
+				if err = rejectsEnc.Encode(rejectedEvent{source, idx, event, rejectErr.Reasons}); err != nil {
+					return fmt.Errorf("write rejected event %d: %w", idx, err)
+				}
+			}
+			continue
+		}
+
+		if err = enc.Encode(event); err != nil {
+			return err
+		}
+		stats.addProcessed()
+	}
+}
+
+func processEvent(event map[string]any, processors []processor) error {
+	for _, p := range processors {
+		if err := p.Process(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startPipeline runs the pipeline for the named source in the background and returns a reader for
+// its newline delimited JSON output. The returned function stops the pipeline,
+// so the rejects and statistics are no longer written to once it returns. It
+// doesn't wait for a read of the event reader, which blocks as long as its
+// input does, like a stdin that is still streaming.
+func startPipeline(ctx context.Context, source string, er eventReader, processors []processor, rejects io.Writer, stats *pipelineStats) (io.Reader, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	// The pipeline holds the lock, except while reading an event.
+	ser := &stoppableEventReader{eventReader: er}
+	ser.mu.Lock()
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer ser.mu.Unlock()
+		_ = pw.CloseWithError(runPipeline(ctx, source, ser, pw, processors, rejects, stats))
+	}()
+
+	return pr, func() {
+		cancel()
+		_ = pr.Close()
+
+		// Waits for the event being processed, if any.
+		ser.mu.Lock()
+		ser.stopped = true
+		ser.mu.Unlock()
+	}
+}
+
+// stoppableEventReader releases the lock of the pipeline while reading an
+// event and fails once the pipeline is stopped.
+type stoppableEventReader struct {
+	eventReader
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+// ReadEvent implements eventReader.
+func (er *stoppableEventReader) ReadEvent() (map[string]any, error) {
+	er.mu.Unlock()
+	event, err := er.eventReader.ReadEvent()
+	er.mu.Lock()
+
+	if er.stopped {
+		return nil, context.Canceled
+	}
+	return event, err
+}
@@ -0,0 +1,178 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONEventReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		typ   axiom.ContentType
+		want  int
+	}{
+		{"ndjson", "{\"a\":1}\n{\"a\":2}\n\n{\"a\":3}\n", axiom.NDJSON, 3},
+		{"json array", `[{"a":1},{"a":2}]`, axiom.JSON, 2},
+		{"empty json array", `[]`, axiom.JSON, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				out   strings.Builder
+				stats pipelineStats
+			)
+			er := newJSONEventReader(strings.NewReader(tt.input), tt.typ)
+			require.NoError(t, runPipeline(t.Context(), "test.ndjson", er, &out, nil, nil, &stats))
+
+			assert.Equal(t, tt.want, strings.Count(out.String(), "\n"))
+			assert.EqualValues(t, tt.want, stats.processed)
+		})
+	}
+}
+
+func TestSchemaValidator(t *testing.T) {
+	schemaFile := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(schemaFile, []byte(`{
+		"type": "object",
+		"required": ["level"],
+		"properties": {
+			"level": {"enum": ["info", "error"]},
+			"status": {"type": "integer"}
+		}
+	}`), 0o600)
+	require.NoError(t, err)
+
+	validator, err := newSchemaValidator(schemaFile)
+	require.NoError(t, err)
+
+	input := strings.Join([]string{
+		`{"level":"info","status":200}`,
+		`{"level":"debug","status":200}`,
+		`{"status":1.5}`,
+		`{"level":"error"}`,
+	}, "\n")
+
+	var (
+		out, rejects strings.Builder
+		stats        pipelineStats
+	)
+	er := newJSONEventReader(strings.NewReader(input), axiom.NDJSON)
+	require.NoError(t, runPipeline(t.Context(), "test.ndjson", er, &out, []processor{validator}, &rejects, &stats))
+
+	assert.Equal(t, "{\"level\":\"info\",\"status\":200}\n{\"level\":\"error\"}\n", out.String())
+
+	rejected, rejections := stats.Rejected()
+	assert.EqualValues(t, 2, rejected)
+	if assert.Len(t, rejections, 2) {
+		assert.Equal(t, "test.ndjson", rejections[0].Source)
+		assert.EqualValues(t, 2, rejections[0].Index)
+		assert.Equal(t, []string{"/level: value must be one of 'info', 'error'"}, rejections[0].Reasons)
+		assert.EqualValues(t, 3, rejections[1].Index)
+		assert.Equal(t, []string{"/: missing property 'level'", "/status: got number, want integer"}, rejections[1].Reasons)
+	}
+	assert.Equal(t, 2, strings.Count(rejects.String(), "\n"))
+}
+
+// rejectOdd rejects every other event.
+type rejectOdd struct{ n int }
+
+func (p *rejectOdd) Process(map[string]any) error {
+	if p.n++; p.n%2 == 1 {
+		return &rejectError{Reasons: []string{"odd"}}
+	}
+	return nil
+}
+
+// stopWriter fails the test if it is written to after stop is set.
+type stopWriter struct {
+	t    *testing.T
+	stop atomic.Bool
+}
+
+func (w *stopWriter) Write(p []byte) (int, error) {
+	if w.stop.Load() {
+		w.t.Error("rejects written to after the pipeline was stopped")
+	}
+	return len(p), nil
+}
+
+func TestStartPipeline_Stop(t *testing.T) {
+	input := strings.Repeat("{\"a\":1}\n", 10_000)
+
+	var (
+		rejects = &stopWriter{t: t}
+		stats   pipelineStats
+	)
+	er := newJSONEventReader(strings.NewReader(input), axiom.NDJSON)
+	r, stop := startPipeline(t.Context(), "test.ndjson", er, []processor{&rejectOdd{}}, rejects, &stats)
+
+	// Read only part of the output, like a failed ingest does, then stop.
+	_, err := r.Read(make([]byte, 64))
+	require.NoError(t, err)
+	stop()
+	rejects.stop.Store(true)
+
+	rejected, _ := stats.Rejected()
+	processed := stats.processed
+	assert.Less(t, processed, uint64(5_000))
+
+	// Nothing is processed after the pipeline was stopped.
+	time.Sleep(10 * time.Millisecond)
+	after, _ := stats.Rejected()
+	assert.Equal(t, rejected, after)
+	assert.Equal(t, processed, stats.processed)
+}
+
+// blockingEventReader returns an event and then blocks until unblocked, like
+// a stdin that is still streaming.
+type blockingEventReader struct {
+	read    bool
+	unblock chan struct{}
+}
+
+func (er *blockingEventReader) ReadEvent() (map[string]any, error) {
+	if !er.read {
+		er.read = true
+		return map[string]any{"a": 1}, nil
+	}
+	<-er.unblock
+	return map[string]any{"a": 2}, nil
+}
+
+func TestStartPipeline_StopBlockedReader(t *testing.T) {
+	var (
+		er      = &blockingEventReader{unblock: make(chan struct{})}
+		rejects = &stopWriter{t: t}
+		stats   pipelineStats
+	)
+	r, stop := startPipeline(t.Context(), "test.ndjson", er, []processor{&rejectOdd{n: 1}}, rejects, &stats)
+
+	_, err := r.Read(make([]byte, 64))
+	require.NoError(t, err)
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stopping the pipeline blocked on the reader")
+	}
+	rejects.stop.Store(true)
+
+	// The event read after the pipeline was stopped is not processed.
+	close(er.unblock)
+	time.Sleep(10 * time.Millisecond)
+	assert.EqualValues(t, 1, stats.processed)
+}
@@ -0,0 +1,51 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// schemaValidator rejects events that don't validate against a JSON Schema.
+type schemaValidator struct {
+	schema *jsonschema.Schema
+}
+
+func newSchemaValidator(filename string) (*schemaValidator, error) {
+	schema, err := jsonschema.NewCompiler().Compile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %q: %w", filename, err)
+	}
+	return &schemaValidator{schema: schema}, nil
+}
+
+// Process implements processor.
+func (v *schemaValidator) Process(event map[string]any) error {
+	err := v.schema.Validate(event)
+	validationErr, ok := errors.AsType[*jsonschema.ValidationError](err)
+	if !ok {
+		return err
+	}
+
+	// The basic output is a flat list of all violations. Each one names the
+	// path into the event (as a JSON pointer) and the reason.
+	var reasons []string
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		path := unit.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s", path, unit.Error))
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, validationErr.Error())
+	}
+	slices.Sort(reasons)
+
+	return &rejectError{Reasons: reasons}
+}
@@ -0,0 +1,309 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// downloadTimeout is the time a server has to respond to the request for a
+// HTTP(S) source and, once it did, to send more of it. It doesn't limit the
+// time the download takes.
+var downloadTimeout = 30 * time.Second
+
+// A source is an input to ingest from: stdin, a local file, a HTTP(S) URL or
+// an object in S3-compatible object storage.
+type source struct {
+	// Name of the source, as displayed to the user.
+	Name string
+	// Open the source for reading.
+	Open func(context.Context) (io.ReadCloser, error)
+}
+
+// resolveSources resolves the given filenames to the sources to ingest from.
+// A filename is either "-" for stdin, a path to a local file, a HTTP(S) URL or
+// a S3 location in the form "s3://bucket/prefix". A S3 location resolves to all
+// objects under the prefix.
+func resolveSources(ctx context.Context, opts *options, filenames []string) ([]source, error) {
+	var (
+		sources    []source
+		s3Client   *s3.Client
+		httpClient *http.Client
+	)
+	for _, filename := range filenames {
+		switch {
+		case filename == "-":
+			sources = append(sources, source{
+				Name: "stdin", // Enhance printed output
+				Open: func(context.Context) (io.ReadCloser, error) {
+					return opts.IO.In(), nil
+				},
+			})
+		case strings.HasPrefix(filename, "http://"), strings.HasPrefix(filename, "https://"):
+			if httpClient == nil {
+				httpClient = newDownloadClient()
+			}
+			sources = append(sources, source{
+				Name: filename,
+				Open: func(ctx context.Context) (io.ReadCloser, error) {
+					return openURL(ctx, httpClient, filename)
+				},
+			})
+		case strings.HasPrefix(filename, "s3://"):
+			if s3Client == nil {
+				var err error
+				if s3Client, err = newS3Client(ctx); err != nil {
+					return nil, err
+				}
+			}
+			objectSources, err := listS3Objects(ctx, s3Client, filename)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, objectSources...)
+		default:
+			sources = append(sources, source{
+				Name: filename,
+				Open: func(context.Context) (io.ReadCloser, error) {
+					return os.Open(filename)
+				},
+			})
+		}
+	}
+	return sources, nil
+}
+
+// newDownloadClient creates the HTTP client HTTP(S) sources are downloaded
+// with. Unlike the default client, it gives up on servers that don't respond.
+func newDownloadClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = downloadTimeout
+	return &http.Client{Transport: transport}
+}
+
+func openURL(ctx context.Context, client *http.Client, u string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_ = resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("could not download %q: %s", u, resp.Status)
+	}
+
+	return newIdleTimeoutReader(resp.Body, downloadTimeout, cancel), nil
+}
+
+// idleTimeoutReader fails a read that doesn't return any data within the
+// timeout, by canceling the download. Time spent between reads doesn't count,
+// so a slow consumer doesn't time out.
+type idleTimeoutReader struct {
+	rc       io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	cancel   context.CancelFunc
+	timedOut atomic.Bool
+}
+
+func newIdleTimeoutReader(rc io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleTimeoutReader {
+	r := &idleTimeoutReader{
+		rc:      rc,
+		timeout: timeout,
+		cancel:  cancel,
+	}
+	r.timer = time.AfterFunc(timeout, func() {
+		r.timedOut.Store(true)
+		cancel()
+	})
+	r.timer.Stop()
+	return r
+}
+
+// Read implements io.Reader.
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	r.timer.Reset(r.timeout)
+	n, err := r.rc.Read(p)
+	r.timer.Stop()
+
+	if err != nil && r.timedOut.Load() {
+		return n, fmt.Errorf("download stalled: no data received for %s", r.timeout)
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (r *idleTimeoutReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.rc.Close()
+}
+
+// newS3Client creates a S3 client configured from the environment and the
+// shared AWS configuration files. When a custom endpoint is configured, e.g.
+// by setting AWS_ENDPOINT_URL_S3 to use a S3-compatible server like MinIO,
+// path-style addressing is used. If no credentials are configured, requests
+// are sent anonymously. Credentials that are configured but can't be
+// retrieved, like those of an expired SSO session, are an error.
+func newS3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not load S3 configuration: %w", err)
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if _, err = cfg.Credentials.Retrieve(ctx); err != nil && credentialsConfigured(cfg.Credentials) {
+		return nil, fmt.Errorf("could not retrieve S3 credentials: %w", err)
+	} else if err != nil {
+		cfg.Credentials = aws.AnonymousCredentials{}
+	}
+
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = o.BaseEndpoint != nil
+		// S3-compatible servers don't necessarily return checksums.
+		o.DisableLogOutputChecksumValidationSkipped = true
+	}), nil
+}
+
+// credentialsConfigured returns true if the credentials are configured, rather
+// than looked up from the EC2 instance metadata service as a last resort.
+func credentialsConfigured(provider aws.CredentialsProvider) bool {
+	ps, ok := provider.(aws.CredentialProviderSource)
+	if !ok {
+		return true
+	}
+	sources := ps.ProviderSources()
+	return len(sources) != 1 || sources[0] != aws.CredentialSourceIMDS
+}
+
+// listS3Objects returns a source for every object under the prefix of the
+// given S3 location.
+func listS3Objects(ctx context.Context, client *s3.Client, location string) ([]source, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	} else if u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 location %q: missing bucket", location)
+	}
+
+	var (
+		bucket = u.Host
+		prefix = strings.TrimPrefix(u.Path, "/")
+	)
+
+	var sources []source
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not list objects at %q: %w", location, err)
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			// Skip "directory" placeholder objects.
+			if strings.HasSuffix(key, "/") {
+				continue
+			}
+
+			sources = append(sources, source{
+				Name: fmt.Sprintf("s3://%s/%s", bucket, key),
+				Open: func(ctx context.Context) (io.ReadCloser, error) {
+					res, err := client.GetObject(ctx, &s3.GetObjectInput{
+						Bucket: aws.String(bucket),
+						Key:    aws.String(key),
+					})
+					if err != nil {
+						return nil, err
+					}
+					return res.Body, nil
+				},
+			})
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no objects found at %q", location)
+	}
+
+	return sources, nil
+}
+
+// decompress detects gzip and zstd compressed data by its magic number and
+// returns a reader for the decompressed data. Uncompressed data is passed
+// through. Closing the returned reader closes the given one, which is left
+// open if an error is returned.
+func decompress(rc io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(rc)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &decompressReader{Reader: gr, closers: []io.Closer{gr, rc}}, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		closer := zr.IOReadCloser()
+		return &decompressReader{Reader: closer, closers: []io.Closer{closer, rc}}, nil
+	}
+
+	return &decompressReader{Reader: br, closers: []io.Closer{rc}}, nil
+}
+
+// decompressReader reads decompressed data and closes the decompressor as well
+// as the underlying reader.
+type decompressReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close implements io.Closer.
+func (r *decompressReader) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,229 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompress(t *testing.T) {
+	const data = `{"a":1}` + "\n"
+
+	var gzipBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzipBuf)
+	_, err := gw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	var zstdBuf bytes.Buffer
+	zw, err := zstd.NewWriter(&zstdBuf)
+	require.NoError(t, err)
+	_, err = zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"plain", []byte(data), data},
+		{"gzip", gzipBuf.Bytes(), data},
+		{"zstd", zstdBuf.Bytes(), data},
+		{"short", []byte("a"), "a"},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := decompress(io.NopCloser(bytes.NewReader(tt.input)))
+			require.NoError(t, err)
+
+			got, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestResolveSources_URL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logs.ndjson" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"a":1}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+
+	sources, err := resolveSources(ctx, &options{}, []string{srv.URL + "/logs.ndjson", srv.URL + "/missing"})
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+
+	rc, err := sources[0].Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, `{"a":1}`, string(got))
+
+	_, err = sources[1].Open(ctx)
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestResolveSources_URLTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-done
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(done) })
+
+	setDownloadTimeout(t, 50*time.Millisecond)
+
+	ctx := context.Background()
+
+	sources, err := resolveSources(ctx, &options{}, []string{srv.URL + "/logs.ndjson"})
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+
+	_, err = sources[0].Open(ctx)
+	assert.ErrorContains(t, err, "timeout awaiting response headers")
+}
+
+func TestResolveSources_URLStalled(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"a":1}`))
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(done) })
+
+	setDownloadTimeout(t, 50*time.Millisecond)
+
+	ctx := context.Background()
+
+	sources, err := resolveSources(ctx, &options{}, []string{srv.URL + "/logs.ndjson"})
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+
+	rc, err := sources[0].Open(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = rc.Close() })
+
+	got, err := io.ReadAll(rc)
+	assert.ErrorContains(t, err, "download stalled")
+	assert.Equal(t, `{"a":1}`, string(got))
+}
+
+func TestIdleTimeoutReader_SlowConsumer(t *testing.T) {
+	r := newIdleTimeoutReader(io.NopCloser(bytes.NewReader([]byte("ab"))), 20*time.Millisecond, func() {})
+	t.Cleanup(func() { _ = r.Close() })
+
+	// Time between reads doesn't count.
+	buf := make([]byte, 1)
+	for range 2 {
+		_, err := r.Read(buf)
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+	}
+	_, err := r.Read(buf)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func setDownloadTimeout(t *testing.T, d time.Duration) {
+	t.Helper()
+
+	timeout := downloadTimeout
+	downloadTimeout = d
+	t.Cleanup(func() { downloadTimeout = timeout })
+}
+
+func TestResolveSources_S3(t *testing.T) {
+	// A minimal S3-compatible server, addressed path-style like MinIO.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/bucket" && r.URL.Query().Get("list-type") == "2":
+			assert.Equal(t, "logs/", r.URL.Query().Get("prefix"))
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+	<Name>bucket</Name>
+	<Prefix>logs/</Prefix>
+	<KeyCount>3</KeyCount>
+	<IsTruncated>false</IsTruncated>
+	<Contents><Key>logs/</Key></Contents>
+	<Contents><Key>logs/a.ndjson</Key></Contents>
+	<Contents><Key>logs/b.ndjson</Key></Contents>
+</ListBucketResult>`))
+		case r.URL.Path == "/bucket/logs/a.ndjson":
+			_, _ = w.Write([]byte(`{"a":1}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL_S3", srv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio123")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	ctx := context.Background()
+
+	sources, err := resolveSources(ctx, &options{}, []string{"s3://bucket/logs/"})
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	assert.Equal(t, "s3://bucket/logs/a.ndjson", sources[0].Name)
+	assert.Equal(t, "s3://bucket/logs/b.ndjson", sources[1].Name)
+
+	rc, err := sources[0].Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, `{"a":1}`, string(got))
+}
+
+func TestNewS3Client_Credentials(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	ctx := context.Background()
+
+	// Without configured credentials, requests are sent anonymously.
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	client, err := newS3Client(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, client.Options().Credentials)
+
+	// Configured credentials that fail are an error.
+	configFile := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(configFile, []byte("[default]\ncredential_process = false\n"), 0o600))
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	_, err = newS3Client(ctx)
+	assert.ErrorContains(t, err, "could not retrieve S3 credentials")
+}
@@ -0,0 +1,283 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/axiomhq/axiom-go/axiom/ingest"
+
+	"github.com/axiomhq/cli/internal/config"
+)
+
+const (
+	// destinationQueueSize is the number of batches queued for a destination.
+	// Batches for a destination whose queue is full are dropped.
+	destinationQueueSize = 16
+)
+
+var (
+	// destinationTimeout is the time a request to a destination may take. It
+	// is also the time waited for the queued batches of a destination to be
+	// sent, once the ingestion is done.
+	destinationTimeout = 30 * time.Second
+	// spoolMemoryLimit is the size up to which a batch queued for the
+	// destinations is held in memory. Larger batches, like whole files that
+	// are not batchable, are spooled to a temporary file.
+	spoolMemoryLimit = 8 << 20 // 8 MiB
+)
+
+// A destination is an additional deployment and dataset the ingested data is
+// sent to, besides the primary dataset. Failing to ingest into a destination
+// doesn't fail the ingestion.
+type destination struct {
+	// Deployment is the alias of the deployment to ingest into. If empty, the
+	// active deployment is used.
+	Deployment string
+	// Dataset to ingest into.
+	Dataset string
+
+	client *axiom.Client
+
+	// queue holds the batches to send, which are sent one at a time in the
+	// background, so a slow destination never holds up the ingestion.
+	queue  chan destinationBatch
+	done   chan struct{}
+	cancel context.CancelFunc
+
+	// res is the accumulated ingestion status.
+	res ingest.Status
+	// failedBatches is the number of batches that failed to ingest.
+	failedBatches uint64
+	// droppedBatches is the number of batches that were not sent because the
+	// queue was full or the ingestion was done before they were sent.
+	droppedBatches atomic.Uint64
+	// err is the last error that occurred.
+	err error
+}
+
+// destinationBatch is a batch of data queued for a destination.
+type destinationBatch struct {
+	spool *spool
+	typ   axiom.ContentType
+}
+
+// String implements fmt.Stringer.
+func (d *destination) String() string {
+	if d.Deployment == "" {
+		return d.Dataset
+	}
+	return d.Deployment + ":" + d.Dataset
+}
+
+// start sending the batches queued for the destination in the background.
+func (d *destination) start(ctx context.Context, opts *options) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	queue, done := make(chan destinationBatch, destinationQueueSize), make(chan struct{})
+	d.queue, d.done = queue, done
+
+	go func() {
+		defer close(done)
+		for batch := range queue {
+			if ctx.Err() != nil {
+				d.droppedBatches.Add(1)
+			} else {
+				d.ingest(ctx, batch, opts)
+			}
+			batch.spool.release()
+		}
+	}()
+}
+
+// send queues the spooled batch for the destination without blocking. If the
+// queue is full, the batch is dropped.
+func (d *destination) send(s *spool, typ axiom.ContentType) {
+	s.retain()
+	select {
+	case d.queue <- destinationBatch{spool: s, typ: typ}:
+	default:
+		d.droppedBatches.Add(1)
+		s.release()
+	}
+}
+
+// stop waits for the queued batches to be sent, at most for the destination
+// timeout. Batches not sent by then are dropped. It is a no-op if the
+// destination was not started or is already stopped.
+func (d *destination) stop() {
+	if d.queue == nil {
+		return
+	}
+	close(d.queue)
+	d.queue = nil
+
+	select {
+	case <-d.done:
+	case <-time.After(destinationTimeout):
+		d.cancel()
+		<-d.done
+	}
+	d.cancel()
+}
+
+func (d *destination) ingest(ctx context.Context, batch destinationBatch, opts *options) {
+	ctx, cancel := context.WithTimeout(ctx, destinationTimeout)
+	defer cancel()
+
+	res, err := ingestReader(ctx, d.client, d.Dataset, batch.spool.reader(), batch.typ, opts)
+	if err != nil {
+		d.failedBatches++
+		d.err = err
+		return
+	}
+	d.res.Add(res)
+}
+
+// parseDestinations parses destinations in the form "<deployment>:<dataset>".
+// If the deployment is omitted, the active deployment is used.
+func parseDestinations(specs []string, cfg *config.Config, primaryDataset string) ([]*destination, error) {
+	res := make([]*destination, 0, len(specs))
+	for _, spec := range specs {
+		dest := &destination{Dataset: spec}
+		if deployment, dataset, ok := strings.Cut(spec, ":"); ok {
+			if _, ok = cfg.Deployments[deployment]; !ok {
+				return nil, fmt.Errorf("deployment %q of destination %q not configured", deployment, spec)
+			}
+			dest.Deployment, dest.Dataset = deployment, dataset
+		}
+
+		if dest.Dataset == "" {
+			return nil, fmt.Errorf("malformed destination %q: missing dataset", spec)
+		} else if dest.Dataset == primaryDataset && (dest.Deployment == "" || dest.Deployment == cfg.ActiveDeployment) {
+			return nil, fmt.Errorf("destination %q is the dataset to ingest into", spec)
+		}
+
+		res = append(res, dest)
+	}
+	return res, nil
+}
+
+// ingestTee ingests the data read from r into the primary dataset and queues it
+// for all started destinations, which send it in the background. Only the
+// status of ingesting into the primary dataset is returned. The data is
+// spooled while it is ingested and queued once the primary dataset took it.
+func ingestTee(ctx context.Context, client *axiom.Client, r io.Reader, typ axiom.ContentType, opts *options) (*ingest.Status, error) {
+	if len(opts.destinations) == 0 {
+		return ingestReader(ctx, client, opts.Dataset, r, typ, opts)
+	}
+
+	s := newSpool()
+	defer s.release()
+
+	res, err := ingestReader(ctx, client, opts.Dataset, io.TeeReader(r, s), typ, opts)
+	if err != nil {
+		return res, err
+	}
+	// Spool what was not read for the primary dataset, if anything.
+	if _, err = io.Copy(s, r); err != nil {
+		return res, err
+	}
+
+	for _, dest := range opts.destinations {
+		if dest.queue != nil {
+			dest.send(s, typ)
+		}
+	}
+
+	return res, nil
+}
+
+// A spool holds a batch of data queued for the destinations. It is held in
+// memory up to spoolMemoryLimit and in a temporary file beyond, which is
+// removed once the spool is released by all its holders.
+type spool struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	file *os.File
+	size int64
+	refs int
+}
+
+// newSpool returns an empty spool held by the caller.
+func newSpool() *spool {
+	return &spool{refs: 1}
+}
+
+// Write implements io.Writer.
+func (s *spool) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refs == 0 {
+		return 0, os.ErrClosed
+	}
+
+	if s.file == nil && s.buf.Len()+len(p) > spoolMemoryLimit {
+		f, err := os.CreateTemp("", "axiom-ingest-*")
+		if err != nil {
+			return 0, err
+		}
+		if _, err = f.Write(s.buf.Bytes()); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return 0, err
+		}
+		s.file = f
+		s.buf = bytes.Buffer{}
+	}
+
+	var (
+		n   int
+		err error
+	)
+	if s.file != nil {
+		n, err = s.file.Write(p)
+	} else {
+		n, err = s.buf.Write(p)
+	}
+	s.size += int64(n)
+	return n, err
+}
+
+// reader returns a reader for the spooled data. Readers can be used
+// concurrently, once the data is spooled.
+func (s *spool) reader() io.Reader {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != nil {
+		return io.NewSectionReader(s.file, 0, s.size)
+	}
+	return bytes.NewReader(s.buf.Bytes())
+}
+
+func (s *spool) retain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs++
+}
+
+// release the spool. The temporary file is removed once the spool is released
+// by all its holders.
+func (s *spool) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refs--; s.refs > 0 {
+		return
+	}
+	if s.file != nil {
+		_ = s.file.Close()
+		_ = os.Remove(s.file.Name())
+		s.file = nil
+	}
+	s.buf = bytes.Buffer{}
+}
@@ -0,0 +1,182 @@
+package ingest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/cli/internal/config"
+)
+
+func TestParseDestinations(t *testing.T) {
+	cfg := &config.Config{
+		ActiveDeployment: "prod",
+		Deployments: map[string]config.Deployment{
+			"prod": {},
+			"eu":   {},
+		},
+	}
+
+	dests, err := parseDestinations([]string{"staging", "eu:logs", "prod:other"}, cfg, "logs")
+	require.NoError(t, err)
+	require.Len(t, dests, 3)
+	assert.Equal(t, "staging", dests[0].String())
+	assert.Empty(t, dests[0].Deployment)
+	assert.Equal(t, "eu", dests[1].Deployment)
+	assert.Equal(t, "logs", dests[1].Dataset)
+	assert.Equal(t, "prod:other", dests[2].String())
+
+	for spec, wantErr := range map[string]string{
+		"us:logs":   `deployment "us" of destination "us:logs" not configured`,
+		"eu:":       `malformed destination "eu:": missing dataset`,
+		"logs":      `destination "logs" is the dataset to ingest into`,
+		"prod:logs": `destination "prod:logs" is the dataset to ingest into`,
+	} {
+		_, err = parseDestinations([]string{spec}, cfg, "logs")
+		assert.EqualError(t, err, wantErr, spec)
+	}
+}
+
+func TestDestination_Slow(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-done
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(done) })
+
+	timeout := destinationTimeout
+	destinationTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { destinationTimeout = timeout })
+
+	client, err := axiom.NewClient(
+		axiom.SetNoEnv(),
+		axiom.SetNoRetry(),
+		axiom.SetURL(srv.URL),
+		axiom.SetToken("xaat-00000000-0000-0000-0000-000000000000"),
+	)
+	require.NoError(t, err)
+
+	opts := &options{ContentEncoding: axiom.Identity}
+	dest := &destination{Dataset: "logs", client: client}
+	dest.start(t.Context(), opts)
+
+	// Sending never blocks, even if the destination doesn't respond.
+	start := time.Now()
+	for range 2 * destinationQueueSize {
+		s := newSpool()
+		_, _ = s.Write([]byte(`{"a":1}`))
+		dest.send(s, axiom.NDJSON)
+		s.release()
+	}
+	assert.Less(t, time.Since(start), destinationTimeout)
+
+	// Stopping waits for the queued batches at most for the timeout.
+	start = time.Now()
+	dest.stop()
+	assert.Less(t, time.Since(start), 5*destinationTimeout)
+
+	assert.GreaterOrEqual(t, dest.droppedBatches.Load(), uint64(destinationQueueSize-1))
+	assert.EqualValues(t, 2*destinationQueueSize, dest.failedBatches+dest.droppedBatches.Load())
+	assert.Zero(t, dest.res.Ingested)
+
+	// Stopping again is a no-op.
+	dest.stop()
+}
+
+func TestSpool(t *testing.T) {
+	limit := spoolMemoryLimit
+	spoolMemoryLimit = 4
+	t.Cleanup(func() { spoolMemoryLimit = limit })
+
+	s := newSpool()
+	for _, p := range []string{"ab", "cde", "f"} {
+		_, err := s.Write([]byte(p))
+		require.NoError(t, err)
+	}
+
+	// Data beyond the memory limit is spooled to a temporary file.
+	require.NotNil(t, s.file)
+	name := s.file.Name()
+
+	for range 2 {
+		got, err := io.ReadAll(s.reader())
+		require.NoError(t, err)
+		assert.Equal(t, "abcdef", string(got))
+	}
+
+	// The file is removed once the spool is released by all holders.
+	s.retain()
+	s.release()
+	assert.FileExists(t, name)
+	s.release()
+	assert.NoFileExists(t, name)
+}
+
+func TestIngestTee(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies = make(map[string]string)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := zstd.NewReader(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer zr.Close()
+		body, err := io.ReadAll(zr)
+		assert.NoError(t, err)
+
+		mu.Lock()
+		bodies[r.URL.Path] = string(body)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ingested":2}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	limit := spoolMemoryLimit
+	spoolMemoryLimit = 8
+	t.Cleanup(func() { spoolMemoryLimit = limit })
+
+	client, err := axiom.NewClient(
+		axiom.SetNoEnv(),
+		axiom.SetNoRetry(),
+		axiom.SetURL(srv.URL),
+		axiom.SetToken("xaat-00000000-0000-0000-0000-000000000000"),
+	)
+	require.NoError(t, err)
+
+	dest := &destination{Dataset: "other", client: client}
+	opts := &options{
+		Dataset:         "logs",
+		ContentEncoding: axiom.Identity,
+		destinations:    []*destination{dest},
+	}
+	dest.start(t.Context(), opts)
+
+	const data = `{"a":1}` + "\n" + `{"a":2}` + "\n"
+	res, err := ingestTee(t.Context(), client, strings.NewReader(data), axiom.NDJSON, opts)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, res.Ingested)
+
+	dest.stop()
+	require.NoError(t, dest.err)
+	assert.EqualValues(t, 2, dest.res.Ingested)
+
+	// The data is spooled to a file and sent to both datasets.
+	assert.Equal(t, map[string]string{
+		"/v1/datasets/logs/ingest":  data,
+		"/v1/datasets/other/ingest": data,
+	}, bodies)
+}
@@ -0,0 +1,58 @@
+package ingest
+
+import (
+	"github.com/mssola/useragent"
+)
+
+// Device types of user agents.
+const (
+	deviceBot     = "bot"
+	deviceMobile  = "mobile"
+	deviceDesktop = "desktop"
+)
+
+// userAgentEnricher adds the browser, operating system and device parsed from
+// the user agent strings in the given fields to every event. The parsed user
+// agent in field "<field>" is added as field "<field>_parsed".
+type userAgentEnricher struct {
+	fields []string
+}
+
+// Process implements processor.
+func (e *userAgentEnricher) Process(event map[string]any) error {
+	for _, field := range e.fields {
+		s, ok := event[field].(string)
+		if !ok || s == "" {
+			continue
+		}
+		event[field+"_parsed"] = parseUserAgent(s)
+	}
+	return nil
+}
+
+func parseUserAgent(s string) map[string]any {
+	var (
+		ua                      = useragent.New(s)
+		browser, browserVersion = ua.Browser()
+		os                      = ua.OSInfo()
+		parsed                  = make(map[string]any)
+	)
+
+	setIfNotEmpty(parsed, "browser", browser)
+	setIfNotEmpty(parsed, "browser_version", browserVersion)
+	setIfNotEmpty(parsed, "os", os.Name)
+	setIfNotEmpty(parsed, "os_version", os.Version)
+	setIfNotEmpty(parsed, "platform", ua.Platform())
+	setIfNotEmpty(parsed, "device_model", ua.Model())
+
+	switch {
+	case ua.Bot():
+		parsed["device"] = deviceBot
+	case ua.Mobile():
+		parsed["device"] = deviceMobile
+	default:
+		parsed["device"] = deviceDesktop
+	}
+
+	return parsed
+}
@@ -0,0 +1,137 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+
+	"github.com/axiomhq/cli/pkg/iofmt"
+)
+
+// tableChart turns an aggregated table into the data of a chart. Every
+// combination of a group and an aggregation is a series. If the table has a
+// "_time" field, the series hold one value per time, otherwise a single one.
+func tableChart(table query.Table) (iofmt.ChartData, error) {
+	var (
+		timeIdx  = -1
+		aggIdx   []int
+		groupIdx []int
+	)
+	for i, field := range table.Fields {
+		switch {
+		case field.Name == "_time":
+			timeIdx = i
+		case field.Aggregation != nil:
+			aggIdx = append(aggIdx, i)
+		default:
+			groupIdx = append(groupIdx, i)
+		}
+	}
+	if len(aggIdx) == 0 {
+		return iofmt.ChartData{}, errors.New("the chart format requires an aggregated result, e.g. using summarize")
+	}
+
+	var (
+		rows  = len(table.Columns[0])
+		times = make([]time.Time, rows)
+		chart iofmt.ChartData
+	)
+	if timeIdx >= 0 {
+		for k := range rows {
+			t, err := chartTime(table.Columns[timeIdx][k])
+			if err != nil {
+				return iofmt.ChartData{}, err
+			}
+			times[k] = t
+		}
+
+		chart.Times = slices.Clone(times)
+		slices.SortFunc(chart.Times, time.Time.Compare)
+		chart.Times = slices.CompactFunc(chart.Times, time.Time.Equal)
+	}
+
+	seriesIdx := make(map[string]int)
+	for k := range rows {
+		groups := make([]string, len(groupIdx))
+		for j, i := range groupIdx {
+			groups[j] = fmt.Sprint(table.Columns[i][k])
+		}
+		group := strings.Join(groups, ", ")
+
+		for _, i := range aggIdx {
+			name := group
+			switch {
+			case name == "":
+				name = table.Fields[i].Name
+			case len(aggIdx) > 1:
+				name += " " + table.Fields[i].Name
+			}
+
+			idx, ok := seriesIdx[name]
+			if !ok {
+				values := make([]float64, max(len(chart.Times), 1))
+				for j := range values {
+					values[j] = math.NaN()
+				}
+				idx = len(chart.Series)
+				seriesIdx[name] = idx
+				chart.Series = append(chart.Series, iofmt.ChartSeries{Name: name, Values: values})
+			}
+
+			pos := 0
+			if timeIdx >= 0 {
+				pos, _ = slices.BinarySearchFunc(chart.Times, times[k], time.Time.Compare)
+			}
+			chart.Series[idx].Values[pos] = floatValue(table.Columns[i][k])
+		}
+	}
+
+	return chart, nil
+}
+
+func chartTime(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q in result", v)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %v in result", v)
+}
+
+// floatValue converts a value of a result to a number. Values that are not
+// numbers are NaN.
+func floatValue(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return math.NaN()
+}
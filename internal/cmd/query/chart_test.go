@@ -0,0 +1,75 @@
+package query
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestTableChart(t *testing.T) {
+	count := &query.Aggregation{Op: query.OpCount}
+
+	table := query.Table{
+		Fields: []query.Field{{Name: "_time"}, {Name: "service"}, {Name: "count_", Aggregation: count}},
+		Columns: []query.Column{
+			{"2024-01-01T00:01:00Z", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"},
+			{"api", "api", "web"},
+			{float64(3), float64(1), "n/a"},
+		},
+	}
+
+	chart, err := tableChart(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Times are sorted and unique.
+	wantTimes := []time.Time{
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+	}
+	if len(chart.Times) != 2 || !chart.Times[0].Equal(wantTimes[0]) || !chart.Times[1].Equal(wantTimes[1]) {
+		t.Errorf("times = %v, want %v", chart.Times, wantTimes)
+	}
+
+	if len(chart.Series) != 2 {
+		t.Fatalf("got %d series, want 2", len(chart.Series))
+	}
+	if s := chart.Series[0]; s.Name != "api" || s.Values[0] != 1 || s.Values[1] != 3 {
+		t.Errorf("series 0 = %+v, want api with values [1 3]", s)
+	}
+	// Values which are not numbers and times without a value are missing.
+	if s := chart.Series[1]; s.Name != "web" || !math.IsNaN(s.Values[0]) || !math.IsNaN(s.Values[1]) {
+		t.Errorf("series 1 = %+v, want web without values", s)
+	}
+}
+
+func TestTableChart_NoTime(t *testing.T) {
+	table := query.Table{
+		Fields: []query.Field{
+			{Name: "count_", Aggregation: &query.Aggregation{Op: query.OpCount}},
+			{Name: "avg_duration", Aggregation: &query.Aggregation{Op: query.OpAvg}},
+		},
+		Columns: []query.Column{{float64(42)}, {1.5}},
+	}
+
+	chart, err := tableChart(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chart.Times) != 0 {
+		t.Errorf("times = %v, want none", chart.Times)
+	}
+	if len(chart.Series) != 2 || chart.Series[0].Name != "count_" || chart.Series[1].Values[0] != 1.5 {
+		t.Errorf("series = %+v, want one per aggregation", chart.Series)
+	}
+
+	if _, err = tableChart(query.Table{
+		Fields:  []query.Field{{Name: "_time"}, {Name: "msg"}},
+		Columns: []query.Column{{"2024-01-01T00:00:00Z"}, {"a"}},
+	}); err == nil {
+		t.Error("chart of result without aggregation did not fail")
+	}
+}
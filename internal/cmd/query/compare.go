@@ -0,0 +1,211 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+
+	"github.com/axiomhq/cli/pkg/terminal"
+)
+
+var errCompareNotAggregated = errors.New("--compare requires an aggregated query, e.g. using summarize")
+
+// compareColumns is the number of columns of a compared result per
+// aggregation: the current and previous value, the delta and the change in
+// percent.
+const compareColumns = 4
+
+// execCompare runs the query for the query range shifted by the compare offset
+// and joins its result with the given result of the current range.
+func execCompare(ctx context.Context, opts *options, fn queryFunc, cur *query.Result) (*query.Result, error) {
+	if table, ok := compareTable(cur); ok && !tableHasAggregation(table) {
+		return nil, errCompareNotAggregated
+	}
+
+	prev, err := execRange(ctx, opts, fn, opts.startTime.Add(opts.compare), opts.endTime.Add(opts.compare))
+	if err != nil {
+		return nil, fmt.Errorf("query previous range: %w", err)
+	}
+	return compareResults(cur, prev, opts.compare)
+}
+
+// compareResults joins the aggregated results of the current and a previous
+// range by their groups. Every aggregation is followed by its previous value,
+// the delta and the change in percent. Times of the previous result are
+// shifted back by the offset between the ranges, so time bins match. Groups
+// only present in one of the results have no previous or current value. The
+// status counts the rows of both ranges.
+func compareResults(cur, prev *query.Result, offset time.Duration) (*query.Result, error) {
+	res := &query.Result{
+		Status:  cur.Status,
+		TraceID: cur.TraceID,
+	}
+	res.Status.RowsExamined += prev.Status.RowsExamined
+	res.Status.RowsMatched += prev.Status.RowsMatched
+	res.Status.IsPartial = res.Status.IsPartial || prev.Status.IsPartial
+	res.Status.IsEstimate = res.Status.IsEstimate || prev.Status.IsEstimate
+
+	// The fields are those of the current result, or of the previous one if
+	// the current range has no result.
+	curTable, hasCur := compareTable(cur)
+	prevTable, hasPrev := compareTable(prev)
+	fieldTable := curTable
+	if !hasCur {
+		if !hasPrev {
+			return res, nil
+		}
+		fieldTable = prevTable
+	}
+	if !tableHasAggregation(fieldTable) {
+		return nil, errCompareNotAggregated
+	}
+
+	var groupIdx, aggIdx []int
+	for i, field := range fieldTable.Fields {
+		if field.Aggregation != nil {
+			aggIdx = append(aggIdx, i)
+		} else {
+			groupIdx = append(groupIdx, i)
+		}
+	}
+
+	table := query.Table{
+		Name:    fieldTable.Name,
+		Sources: fieldTable.Sources,
+		Groups:  fieldTable.Groups,
+	}
+	for _, i := range groupIdx {
+		table.Fields = append(table.Fields, fieldTable.Fields[i])
+	}
+	for _, i := range aggIdx {
+		field := fieldTable.Fields[i]
+		for _, suffix := range []string{"", "_prev", "_delta", "_change_pct"} {
+			table.Fields = append(table.Fields, query.Field{
+				Name:        field.Name + suffix,
+				Type:        field.Type,
+				Aggregation: field.Aggregation,
+			})
+		}
+	}
+	table.Columns = make([]query.Column, len(table.Fields))
+
+	// The rows of the previous result by their group, in order. Rows are
+	// matched by field name, so the order of the fields doesn't matter.
+	var (
+		prevKeys []string
+		prevRows = make(map[string]map[string]any)
+	)
+	if hasPrev {
+		for k := range tableLen(prevTable) {
+			row := tableRowAtIndex(prevTable, k)
+			if t, err := chartTime(row["_time"]); err == nil {
+				row["_time"] = t.Add(-offset).Format(time.RFC3339Nano)
+			}
+			key := compareGroupKey(fieldTable, groupIdx, row)
+			if _, ok := prevRows[key]; !ok {
+				prevKeys = append(prevKeys, key)
+			}
+			prevRows[key] = row
+		}
+	}
+
+	addRow := func(cur, prev map[string]any) {
+		groups := cur
+		if groups == nil {
+			groups = prev
+		}
+		for j, i := range groupIdx {
+			table.Columns[j] = append(table.Columns[j], groups[fieldTable.Fields[i].Name])
+		}
+		for j, i := range aggIdx {
+			name := fieldTable.Fields[i].Name
+			c, p := cur[name], prev[name]
+			x, y := floatValue(c), floatValue(p)
+
+			var delta, change any
+			if !math.IsNaN(x) && !math.IsNaN(y) {
+				delta = x - y
+				if y != 0 {
+					change = math.Round((x-y)/math.Abs(y)*1000) / 10
+				}
+			}
+
+			col := len(groupIdx) + j*compareColumns
+			table.Columns[col] = append(table.Columns[col], c)
+			table.Columns[col+1] = append(table.Columns[col+1], p)
+			table.Columns[col+2] = append(table.Columns[col+2], delta)
+			table.Columns[col+3] = append(table.Columns[col+3], change)
+		}
+	}
+
+	seen := make(map[string]bool, len(prevRows))
+	for k := range tableLen(curTable) {
+		row := tableRowAtIndex(curTable, k)
+		key := compareGroupKey(fieldTable, groupIdx, row)
+		seen[key] = true
+		addRow(row, prevRows[key])
+	}
+
+	// Groups only the previous result holds are added last.
+	for _, key := range prevKeys {
+		if !seen[key] {
+			addRow(nil, prevRows[key])
+		}
+	}
+
+	res.Tables = []query.Table{table}
+	return res, nil
+}
+
+// compareTable returns the table of the result that is compared and whether
+// it has one.
+func compareTable(res *query.Result) (query.Table, bool) {
+	tables := resultTables(res)
+	if len(tables) == 0 || len(tables[0].Fields) == 0 {
+		return query.Table{}, false
+	}
+	return tables[0].Table, true
+}
+
+// compareGroupKey returns the values of the group-by fields of the row as a
+// key.
+func compareGroupKey(table query.Table, groupIdx []int, row map[string]any) string {
+	var sb strings.Builder
+	for _, i := range groupIdx {
+		sb.WriteString(fmt.Sprint(row[table.Fields[i].Name]))
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+// compareStyle returns the color function a cell of a compared result is
+// rendered with: deltas and changes of values that increased are red and of
+// values that decreased are green. It returns nil for all other cells.
+func compareStyle(cs *terminal.ColorScheme, table query.Table, row, col int) terminal.ColorFunc {
+	var j int
+	for i, field := range table.Fields {
+		if field.Aggregation == nil {
+			continue
+		}
+		if i == col {
+			break
+		}
+		j++
+	}
+	if table.Fields[col].Aggregation == nil || j%compareColumns < 2 {
+		return nil
+	}
+
+	switch v := floatValue(table.Columns[col][row]); {
+	case v > 0:
+		return cs.Red
+	case v < 0:
+		return cs.Green
+	}
+	return nil
+}
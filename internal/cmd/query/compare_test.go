@@ -0,0 +1,105 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestCompareResults(t *testing.T) {
+	count := &query.Aggregation{Op: query.OpCount}
+	fields := []query.Field{{Name: "_time"}, {Name: "service"}, {Name: "count_", Aggregation: count}}
+
+	cur := &query.Result{Tables: []query.Table{{
+		Fields: fields,
+		Columns: []query.Column{
+			{"2024-01-02T00:00:00Z", "2024-01-02T00:00:00Z", "2024-01-02T00:00:00Z"},
+			{"api", "web", "db"},
+			{150.0, 50.0, 3.0},
+		},
+	}}}
+	// The fields of the previous result are in a different order and its
+	// times are a day earlier.
+	prev := &query.Result{Tables: []query.Table{{
+		Fields: []query.Field{fields[1], fields[0], fields[2]},
+		Columns: []query.Column{
+			{"api", "web", "cron", "db"},
+			{"2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"},
+			{100.0, 80.0, 7.0, 0.0},
+		},
+	}}}
+
+	res, err := compareResults(cur, prev, -24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := res.Tables[0]
+	if got := fmt.Sprint(tableFieldNames(table)); got != "[_time service count_ count__prev count__delta count__change_pct]" {
+		t.Errorf("fields = %s", got)
+	}
+	want := "[[2024-01-02T00:00:00Z 2024-01-02T00:00:00Z 2024-01-02T00:00:00Z 2024-01-02T00:00:00Z] " +
+		"[api web db cron] [150 50 3 <nil>] [100 80 0 7] [50 -30 3 <nil>] [50 -37.5 <nil> <nil>]]"
+	if got := fmt.Sprint(table.Columns); got != want {
+		t.Errorf("columns = %s, want %s", got, want)
+	}
+
+	if _, err = compareResults(&query.Result{Tables: []query.Table{{
+		Fields:  []query.Field{{Name: "msg"}},
+		Columns: []query.Column{{"a"}},
+	}}}, prev, -time.Hour); err == nil {
+		t.Error("comparing a result that is not aggregated did not fail")
+	}
+}
+
+func TestCompareResults_EmptyRange(t *testing.T) {
+	fields := []query.Field{{Name: "service"}, {Name: "count_", Aggregation: &query.Aggregation{Op: query.OpCount}}}
+
+	// Groups of the previous range are kept if the current range has no rows
+	// or no result at all.
+	for _, cur := range []*query.Result{
+		{Tables: []query.Table{{Fields: fields}}},
+		{},
+	} {
+		prev := &query.Result{
+			Status: query.Status{RowsMatched: 12},
+			Tables: []query.Table{{
+				Fields:  fields,
+				Columns: []query.Column{{"api"}, {12.0}},
+			}},
+		}
+
+		res, err := compareResults(cur, prev, -time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resultIsEmpty(res) {
+			t.Errorf("result is empty, status = %+v", res.Status)
+		}
+		if got := fmt.Sprint(res.Tables[0].Columns); got != "[[api] [<nil>] [12] [<nil>] [<nil>]]" {
+			t.Errorf("columns = %s", got)
+		}
+	}
+}
+
+func TestExecCompare_NotAggregated(t *testing.T) {
+	var queries int
+	fn := func(context.Context, time.Time, time.Time) (*query.Result, error) {
+		queries++
+		return &query.Result{}, nil
+	}
+
+	cur := &query.Result{Tables: []query.Table{{
+		Fields:  []query.Field{{Name: "msg"}},
+		Columns: []query.Column{{"a"}},
+	}}}
+	if _, err := execCompare(context.Background(), &options{compare: -time.Hour}, fn, cur); err == nil {
+		t.Error("comparing a result that is not aggregated did not fail")
+	}
+	if queries != 0 {
+		t.Errorf("queried the previous range %d times, want 0", queries)
+	}
+}
@@ -0,0 +1,286 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+var (
+	expectNumberRe  = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?`)
+	expectIdentRe   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*`)
+	expectStringRe  = regexp.MustCompile(`^(?:'([^']*)'|"([^"]*)")`)
+	expectBracketRe = regexp.MustCompile(`^\[\s*(?:'([^']*)'|"([^"]*)")\s*\]`)
+	expectQuotedRe  = regexp.MustCompile("^`([^`]*)`")
+	expectOpRe      = regexp.MustCompile(`^(<=|>=|==|!=|<|>|=)`)
+)
+
+// maxFailedRows is the number of failed rows reported per expectation.
+const maxFailedRows = 5
+
+// expectation is a condition every row of a query result is expected to meet,
+// like "count_ < 10". It compares two operands, which are either the value of
+// a field or a literal.
+type expectation struct {
+	expr        string
+	op          string
+	left, right expectOperand
+}
+
+// expectOperand is an operand of an expectation. It either refers to a field
+// or is a literal number, string, boolean or null.
+type expectOperand struct {
+	field string
+	value any
+}
+
+// parseExpectation parses an expectation of the form "<operand> <op>
+// <operand>", where op is one of "<", "<=", ">", ">=", "==" (or "=") and "!=".
+// An operand is a number, a string in single or double quotes, true, false,
+// null or a field name. Field names which are not identifiers are given in
+// backticks or like in APL, as in ['name'].
+func parseExpectation(s string) (expectation, error) {
+	e := expectation{expr: strings.TrimSpace(s)}
+
+	left, rest, err := parseOperand(s)
+	if err != nil {
+		return e, fmt.Errorf("invalid expectation %q: %w", s, err)
+	}
+
+	rest = strings.TrimSpace(rest)
+	op := expectOpRe.FindString(rest)
+	if op == "" {
+		return e, fmt.Errorf("invalid expectation %q: expected one of <, <=, >, >=, == or != after %q", s, strings.TrimSpace(strings.TrimSuffix(s, rest)))
+	} else if op == "=" {
+		op = "=="
+	}
+
+	right, rest, err := parseOperand(rest[len(op):])
+	if err != nil {
+		return e, fmt.Errorf("invalid expectation %q: %w", s, err)
+	} else if rest = strings.TrimSpace(rest); rest != "" {
+		return e, fmt.Errorf("invalid expectation %q: unexpected %q", s, rest)
+	}
+
+	e.op, e.left, e.right = op, left, right
+	return e, nil
+}
+
+func parseOperand(s string) (expectOperand, string, error) {
+	s = strings.TrimSpace(s)
+
+	if m := expectBracketRe.FindStringSubmatch(s); m != nil {
+		return expectOperand{field: m[1] + m[2]}, s[len(m[0]):], nil
+	} else if m := expectQuotedRe.FindStringSubmatch(s); m != nil {
+		return expectOperand{field: m[1]}, s[len(m[0]):], nil
+	} else if m := expectStringRe.FindStringSubmatch(s); m != nil {
+		return expectOperand{value: m[1] + m[2]}, s[len(m[0]):], nil
+	} else if m := expectNumberRe.FindString(s); m != "" {
+		f, err := strconv.ParseFloat(m, 64)
+		if err != nil {
+			return expectOperand{}, "", fmt.Errorf("invalid number %q", m)
+		}
+		return expectOperand{value: f}, s[len(m):], nil
+	} else if m := expectIdentRe.FindString(s); m != "" {
+		switch m {
+		case "true":
+			return expectOperand{value: true}, s[len(m):], nil
+		case "false":
+			return expectOperand{value: false}, s[len(m):], nil
+		case "null":
+			return expectOperand{}, s[len(m):], nil
+		}
+		return expectOperand{field: m}, s[len(m):], nil
+	} else if s == "" {
+		return expectOperand{}, "", errors.New("missing operand")
+	}
+	return expectOperand{}, "", fmt.Errorf("unexpected %q", s)
+}
+
+// String returns the expression of the expectation.
+func (e expectation) String() string {
+	return e.expr
+}
+
+// fields returns the names of the fields the expectation refers to.
+func (e expectation) fields() []string {
+	var fields []string
+	for _, o := range []expectOperand{e.left, e.right} {
+		if o.field != "" && !slices.Contains(fields, o.field) {
+			fields = append(fields, o.field)
+		}
+	}
+	return fields
+}
+
+// expectationResult is the result of checking an expectation against the rows
+// of a query result.
+type expectationResult struct {
+	expectation
+	// Rows is the number of rows checked.
+	Rows int
+	// Failed is the number of rows the expectation doesn't hold for.
+	Failed int
+	// FailedRows describe the first rows the expectation doesn't hold for.
+	FailedRows []string
+	// Err is set if the expectation can't be checked.
+	Err error
+}
+
+// Passed returns true if the expectation holds for all rows.
+func (r expectationResult) Passed() bool {
+	return r.Err == nil && r.Failed == 0
+}
+
+// Message returns a short description of why the expectation failed.
+func (r expectationResult) Message() string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	return fmt.Sprintf("%d of %d rows failed", r.Failed, r.Rows)
+}
+
+// checkExpectation checks the expectation against the rows of all tables that
+// hold the fields it refers to. A field which is not found is also looked up
+// with an underscore appended, the name APL gives aggregations which are not
+// named explicitly, so "count" refers to the "count_" field of "count()". An
+// expectation holds for a result without rows.
+func checkExpectation(e expectation, tables []resultTable) expectationResult {
+	res := expectationResult{expectation: e}
+
+	var checked bool
+	for _, table := range tables {
+		names := tableFieldNames(table.Table)
+
+		fields := make(map[string]string, 2)
+		for _, field := range e.fields() {
+			if slices.Contains(names, field) {
+				fields[field] = field
+			} else if slices.Contains(names, field+"_") {
+				fields[field] = field + "_"
+			}
+		}
+		if len(fields) < len(e.fields()) {
+			continue
+		}
+		checked = true
+
+		for k := range tableLen(table.Table) {
+			row := tableRowAtIndex(table.Table, k)
+			value := func(o expectOperand) any {
+				if o.field != "" {
+					return row[fields[o.field]]
+				}
+				return o.value
+			}
+
+			res.Rows++
+			if compareValues(value(e.left), e.op, value(e.right)) {
+				continue
+			}
+			res.Failed++
+			if len(res.FailedRows) < maxFailedRows {
+				res.FailedRows = append(res.FailedRows, describeRow(table.Table, row, fields))
+			}
+		}
+	}
+
+	if !checked && len(tables) > 0 {
+		var missing []string
+		for _, field := range e.fields() {
+			found := slices.ContainsFunc(tables, func(table resultTable) bool {
+				names := tableFieldNames(table.Table)
+				return slices.Contains(names, field) || slices.Contains(names, field+"_")
+			})
+			if !found {
+				missing = append(missing, strconv.Quote(field))
+			}
+		}
+		if len(missing) > 0 {
+			res.Err = fmt.Errorf("no field %s in the result", strings.Join(missing, ", "))
+		} else {
+			res.Err = errors.New("the fields are not in the same table of the result")
+		}
+	}
+
+	return res
+}
+
+// compareValues compares two values of a result. Values that are both numbers
+// are compared numerically, null is only equal to null and everything else is
+// compared as text.
+func compareValues(a any, op string, b any) bool {
+	var cmp int
+	if x, y := floatValue(a), floatValue(b); !math.IsNaN(x) && !math.IsNaN(y) {
+		switch {
+		case x < y:
+			cmp = -1
+		case x > y:
+			cmp = 1
+		}
+	} else if a == nil || b == nil {
+		switch op {
+		case "==":
+			return a == nil && b == nil
+		case "!=":
+			return a != nil || b != nil
+		}
+		return false
+	} else {
+		cmp = strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	}
+	return false
+}
+
+// describeRow describes a row that failed an expectation by the values of its
+// groups, or its time if it is not aggregated, and of the fields the
+// expectation refers to.
+func describeRow(table query.Table, row map[string]any, fields map[string]string) string {
+	var (
+		names      []string
+		aggregated = tableHasAggregation(table)
+	)
+	for _, field := range table.Fields {
+		if (aggregated && field.Aggregation == nil) || (!aggregated && field.Name == "_time") {
+			names = append(names, field.Name)
+		}
+	}
+	for _, field := range table.Fields {
+		if slices.Contains(names, field.Name) {
+			continue
+		}
+		for _, name := range fields {
+			if name == field.Name {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%v", name, row[name])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,145 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestParseExpectation(t *testing.T) {
+	tests := []struct {
+		input       string
+		op          string
+		left, right expectOperand
+	}{
+		{"count < 10", "<", expectOperand{field: "count"}, expectOperand{value: 10.0}},
+		{"max_latency<=500.5", "<=", expectOperand{field: "max_latency"}, expectOperand{value: 500.5}},
+		{"['status.code'] = 'ok'", "==", expectOperand{field: "status.code"}, expectOperand{value: "ok"}},
+		{"`error rate` != null", "!=", expectOperand{field: "error rate"}, expectOperand{}},
+		{`-1e3 >= ["min"]`, ">=", expectOperand{value: -1000.0}, expectOperand{field: "min"}},
+		{"healthy == true", "==", expectOperand{field: "healthy"}, expectOperand{value: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, err := parseExpectation(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.op != tt.op || e.left != tt.left || e.right != tt.right {
+				t.Errorf("parseExpectation(%q) = %v %s %v, want %v %s %v", tt.input, e.left, e.op, e.right, tt.left, tt.op, tt.right)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "count", "count <", "count ~ 10", "count < 10 10", "'a < 1"} {
+		if _, err := parseExpectation(input); err == nil {
+			t.Errorf("parseExpectation(%q) did not fail", input)
+		}
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		a    any
+		op   string
+		b    any
+		want bool
+	}{
+		{9.0, "<", 10.0, true},
+		{10.0, "<", 10.0, false},
+		{10.0, "<=", 10.0, true},
+		{"500", "==", 500.0, true},
+		{"api", "==", "api", true},
+		{"api", ">", "web", false},
+		{nil, "==", nil, true},
+		{nil, "<", 10.0, false},
+		{nil, "!=", 10.0, true},
+		{true, "==", true, true},
+	}
+	for _, tt := range tests {
+		if got := compareValues(tt.a, tt.op, tt.b); got != tt.want {
+			t.Errorf("compareValues(%v, %q, %v) = %t, want %t", tt.a, tt.op, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckExpectation(t *testing.T) {
+	tables := resultTables(&query.Result{Tables: []query.Table{{
+		Fields: []query.Field{
+			{Name: "service"},
+			{Name: "count_", Aggregation: &query.Aggregation{Op: query.OpCount}},
+			{Name: "max_latency", Aggregation: &query.Aggregation{Op: query.OpMax}},
+		},
+		Columns: []query.Column{{"api", "web", "db"}, {3.0, 12.0, 1.0}, {734.0, 120.0, 612.0}},
+	}}})
+
+	check := func(s string) expectationResult {
+		return checkExpectation(mustParseExpectation(t, s), tables)
+	}
+
+	// "count" refers to the "count_" field.
+	if r := check("count < 20"); !r.Passed() || r.Rows != 3 {
+		t.Errorf("count < 20: %s, want pass on 3 rows", r.Message())
+	}
+
+	r := check("max_latency <= 500")
+	if r.Passed() || r.Failed != 2 {
+		t.Fatalf("max_latency <= 500: %s, want 2 rows to fail", r.Message())
+	}
+	want := []string{"service=api max_latency=734", "service=db max_latency=612"}
+	if strings.Join(r.FailedRows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("failed rows = %q, want %q", r.FailedRows, want)
+	}
+
+	if r = check("p99 < 100"); r.Err == nil || r.Err.Error() != `no field "p99" in the result` {
+		t.Errorf("p99 < 100: err = %v", r.Err)
+	}
+
+	// A result without rows meets any expectation.
+	if r = checkExpectation(mustParseExpectation(t, "count < 1"), nil); !r.Passed() {
+		t.Errorf("empty result: %s, want pass", r.Message())
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.xml")
+	opts := &assertOptions{
+		options: options{Query: "['logs'] | summarize count()"},
+		JUnit:   path,
+	}
+
+	results := []expectationResult{
+		{expectation: mustParseExpectation(t, "count < 10"), Rows: 1},
+		{expectation: mustParseExpectation(t, "max < 5"), Rows: 2, Failed: 1, FailedRows: []string{"service=api max=7"}},
+	}
+	if err := writeJUnitReport(opts, results); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<testsuite name="axiom query assert" tests="2" failures="1"`,
+		`<property name="query" value="[&#39;logs&#39;] | summarize count()"></property>`,
+		`<testcase name="count &lt; 10" classname="axiom.query.assert"></testcase>`,
+		`<failure message="1 of 2 rows failed">service=api max=7</failure>`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("report does not contain %s:\n%s", s, b)
+		}
+	}
+}
+
+func mustParseExpectation(t *testing.T, s string) expectation {
+	t.Helper()
+	e, err := parseExpectation(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
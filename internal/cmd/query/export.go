@@ -0,0 +1,328 @@
+package query
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/dustin/go-humanize"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/axiomhq/cli/pkg/iofmt"
+	"github.com/axiomhq/cli/pkg/utils"
+)
+
+const (
+	// minWindow is the smallest time window a query range is split into. A
+	// window that is still truncated is exported as is.
+	minWindow = time.Millisecond
+	// maxWindowSplits is the maximum number of windows a single window is
+	// split into at once.
+	maxWindowSplits = 64
+	// maxExportQueries is the maximum number of queries an export runs. It
+	// stops exports which never complete, because the server reports more
+	// rows as matched than it returns for ever smaller windows.
+	maxExportQueries = 10_000
+)
+
+// limitRe matches the APL operators that limit the number of rows a query
+// returns on purpose. Such queries are not paged through.
+var limitRe = regexp.MustCompile(`\|\s*(?:limit|take|top)\b`)
+
+// queryFunc runs the query over the given time range.
+type queryFunc func(ctx context.Context, start, end time.Time) (*query.Result, error)
+
+// exporter pages through the complete result of a query. The server truncates
+// large results, which shows as fewer rows returned than matched. The time
+// range of a truncated result is split into smaller windows, which are queried
+// again, until every window is returned in full. Windows are exported from the
+// newest to the oldest, which matches the default order of APL results. Rows
+// are written in the given format, with the fields of the first result as
+// columns.
+type exporter struct {
+	query  queryFunc
+	w      io.Writer
+	format iofmt.Format
+	enc    iofmt.Encoder
+
+	// rows is the number of rows written.
+	rows uint64
+	// queries is the number of queries run.
+	queries int
+	// maxQueries is the maximum number of queries to run.
+	maxQueries int
+	// truncated is the number of windows that couldn't be split any further
+	// but are still truncated.
+	truncated int
+	// messages are the distinct messages of the exported windows.
+	messages []query.Message
+	seen     map[string]bool
+}
+
+func newExporter(w io.Writer, format iofmt.Format, fn queryFunc) *exporter {
+	return &exporter{
+		query:  fn,
+		w:      w,
+		format: format,
+		seen:   make(map[string]bool),
+
+		maxQueries: maxExportQueries,
+	}
+}
+
+// Export the result of the query over the given time range. If paginate is
+// false, the result of a single query is exported.
+func (e *exporter) Export(ctx context.Context, start, end time.Time, paginate bool) error {
+	if e.queries >= e.maxQueries {
+		return fmt.Errorf("export stopped after %s queries: the server keeps reporting more matched rows than it returns, narrow the query range or the query",
+			humanize.Comma(int64(e.queries)))
+	}
+
+	res, err := e.query(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	e.queries++
+
+	if len(res.Tables) == 0 {
+		return nil
+	}
+	table := res.Tables[0]
+
+	var returned int
+	if len(table.Columns) > 0 {
+		returned = len(table.Columns[0])
+	}
+
+	// Aggregated results are not truncated by the server the way raw rows
+	// are and can't be merged across windows.
+	complete := !paginate || tableHasAggregation(table) || uint64(returned) >= res.Status.RowsMatched
+	if complete || end.Sub(start) <= minWindow {
+		if !complete {
+			e.truncated++
+		}
+		e.addMessages(res.Status.Messages)
+		return e.write(table, returned)
+	}
+
+	// Split into as many windows as needed for each to be returned in full,
+	// assuming rows are evenly distributed, plus one to spare.
+	splits := maxWindowSplits
+	if returned > 0 {
+		splits = min(int(math.Ceil(float64(res.Status.RowsMatched)/float64(returned)))+1, maxWindowSplits)
+	}
+	step := max(end.Sub(start)/time.Duration(splits), minWindow)
+
+	for windowEnd := end; windowEnd.After(start); {
+		// The remainder of the range is added to the oldest window.
+		windowStart := windowEnd.Add(-step)
+		if windowStart.Sub(start) < step {
+			windowStart = start
+		}
+		if err = e.Export(ctx, windowStart, windowEnd, true); err != nil {
+			return err
+		}
+		windowEnd = windowStart
+	}
+
+	return nil
+}
+
+func (e *exporter) write(table query.Table, rows int) error {
+	if e.enc == nil {
+		var err error
+		if e.enc, err = iofmt.NewEncoder(e.w, e.format, tableFieldNames(table), false); err != nil {
+			return err
+		}
+	}
+
+	for i := range rows {
+		if err := e.enc.Encode(tableRowAtIndex(table, i)); err != nil {
+			return err
+		}
+		e.rows++
+	}
+	return nil
+}
+
+// Close completes the output of the exported rows.
+func (e *exporter) Close() error {
+	if e.enc == nil {
+		var err error
+		if e.enc, err = iofmt.NewEncoder(e.w, e.format, nil, false); err != nil {
+			return err
+		}
+	}
+	return e.enc.Close()
+}
+
+func (e *exporter) addMessages(msgs []query.Message) {
+	for _, msg := range msgs {
+		if !e.seen[msg.Msg] {
+			e.seen[msg.Msg] = true
+			e.messages = append(e.messages, msg)
+		}
+	}
+}
+
+// runExport writes the result of the query to the output, paging through the
+// complete result if requested.
+func runExport(ctx context.Context, opts *options) error {
+	client, err := opts.Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = "-"
+	}
+	w, err := createOutput(output, opts.IO.Out())
+	if err != nil {
+		return err
+	}
+
+	e := newExporter(w, opts.format, func(ctx context.Context, start, end time.Time) (*query.Result, error) {
+		res, err := client.Query(ctx, opts.Query,
+			query.SetStartTime(start),
+			query.SetEndTime(end),
+		)
+		if err != nil {
+			return nil, err
+		}
+		// The history records the trace of the first query only.
+		if opts.history.TraceID == "" {
+			opts.history.TraceID = res.TraceID
+		}
+		opts.stats.add(res.Status, res.TraceID)
+		return res, nil
+	})
+
+	// Relative to the time the export started, so the windows don't move.
+	endTime := opts.endTime
+	if opts.All && endTime.IsZero() {
+		endTime = time.Now()
+	}
+
+	progStop := opts.IO.StartActivityIndicator()
+	defer progStop()
+
+	opts.history.Time = time.Now()
+	err = e.Export(ctx, opts.startTime, endTime, opts.All && !isLimited(opts.Query))
+	opts.history.DurationMS = time.Since(opts.history.Time).Milliseconds()
+	opts.history.Rows = e.rows
+	if err == nil {
+		err = e.Close()
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	progStop()
+
+	cs := opts.IO.ColorScheme()
+
+	printMessages(opts.IO.ErrOut(), cs, e.messages)
+	if e.truncated > 0 {
+		fmt.Fprintf(opts.IO.ErrOut(), "%s Results of %s could not be split any further and are incomplete\n",
+			cs.WarningIcon(), utils.Pluralize(cs, "time window", e.truncated))
+	}
+
+	if opts.Stats {
+		if err = printStats(opts, opts.stats); err != nil {
+			return err
+		}
+	}
+
+	if e.rows == 0 && opts.FailOnEmpty {
+		return errors.New("query returned no results")
+	}
+
+	if opts.IO.IsStderrTTY() {
+		dest := output
+		if dest == "-" {
+			dest = "stdout"
+		}
+		fmt.Fprintf(opts.IO.ErrOut(), "%s Wrote %s rows to %s using %s\n", cs.SuccessIcon(),
+			cs.Bold(humanize.Comma(int64(e.rows))), cs.Bold(dest), utils.Pluralize(cs, "request", e.queries))
+	}
+
+	return nil
+}
+
+// isLimited returns true if the query limits the number of rows it returns.
+func isLimited(apl string) bool {
+	return limitRe.MatchString(apl)
+}
+
+// createOutput creates the file at the given path to write results to. The
+// data is compressed if the file extension is ".gz" or ".zst". If the path is
+// "-", stdout is used.
+func createOutput(path string, stdout io.Writer) (io.WriteCloser, error) {
+	if path == "-" {
+		return flushCloser{bufio.NewWriter(stdout)}, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	bw := bufio.NewWriter(f)
+
+	var w io.WriteCloser
+	switch filepath.Ext(path) {
+	case ".gz":
+		w = gzip.NewWriter(bw)
+	case ".zst":
+		if w, err = zstd.NewWriter(bw); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	default:
+		w = flushCloser{bw}
+	}
+
+	return &outputFile{WriteCloser: w, bw: bw, f: f}, nil
+}
+
+// outputFile is a file written through a buffer and, optionally, a
+// compressor. Closing it flushes all of them.
+type outputFile struct {
+	io.WriteCloser
+	bw *bufio.Writer
+	f  *os.File
+}
+
+// Close implements io.Closer.
+func (o *outputFile) Close() error {
+	if err := o.WriteCloser.Close(); err != nil {
+		_ = o.f.Close()
+		return err
+	} else if err = o.bw.Flush(); err != nil {
+		_ = o.f.Close()
+		return err
+	}
+	return o.f.Close()
+}
+
+// flushCloser flushes the buffered writer it wraps on close.
+type flushCloser struct {
+	*bufio.Writer
+}
+
+// Close implements io.Closer.
+func (w flushCloser) Close() error {
+	return w.Flush()
+}
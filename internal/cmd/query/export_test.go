@@ -0,0 +1,265 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/axiomhq/cli/pkg/iofmt"
+)
+
+// fakeQuery returns a queryFunc serving one event per second, starting at the
+// given time, of which at most limit are returned per query, newest first.
+func fakeQuery(from time.Time, events, limit int) queryFunc {
+	return func(_ context.Context, start, end time.Time) (*query.Result, error) {
+		var times []any
+		for i := events - 1; i >= 0; i-- {
+			ts := from.Add(time.Duration(i) * time.Second)
+			if !ts.Before(start) && ts.Before(end) {
+				times = append(times, ts.Format(time.RFC3339Nano))
+			}
+		}
+		matched := len(times)
+		if len(times) > limit {
+			times = times[:limit]
+		}
+
+		res := &query.Result{
+			Tables: []query.Table{{
+				Fields:  []query.Field{{Name: "_time"}},
+				Columns: []query.Column{times},
+			}},
+		}
+		res.Status.RowsMatched = uint64(matched)
+		if matched > limit {
+			res.Status.Messages = []query.Message{{Msg: "query result truncated"}}
+		}
+		return res, nil
+	}
+}
+
+func readTimes(t *testing.T, r io.Reader) []string {
+	t.Helper()
+
+	var times []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var row struct {
+			Time string `json:"_time"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+			t.Fatalf("invalid row %q: %v", sc.Text(), err)
+		}
+		times = append(times, row.Time)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return times
+}
+
+func TestExporterPaginates(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	e := newExporter(&buf, iofmt.NDJSON, fakeQuery(from, 1000, 100))
+
+	if err := e.Export(t.Context(), from, from.Add(time.Hour), true); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.rows != 1000 {
+		t.Errorf("rows = %d, want 1000", e.rows)
+	}
+	if e.truncated != 0 {
+		t.Errorf("truncated = %d, want 0", e.truncated)
+	}
+	if e.queries < 2 {
+		t.Errorf("queries = %d, want more than one", e.queries)
+	}
+	// The truncation messages of split windows don't apply to the export.
+	if len(e.messages) != 0 {
+		t.Errorf("messages = %v, want none", e.messages)
+	}
+
+	// All events are exported exactly once, newest first.
+	times := readTimes(t, &buf)
+	if len(times) != 1000 {
+		t.Fatalf("got %d rows, want 1000", len(times))
+	}
+	for i, ts := range times {
+		want := from.Add(time.Duration(999-i) * time.Second).Format(time.RFC3339Nano)
+		if ts != want {
+			t.Fatalf("row %d = %s, want %s", i, ts, want)
+		}
+	}
+}
+
+func TestExporterNoPagination(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	e := newExporter(&buf, iofmt.NDJSON, fakeQuery(from, 1000, 100))
+
+	if err := e.Export(t.Context(), from, from.Add(time.Hour), false); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.rows != 100 || e.queries != 1 {
+		t.Errorf("rows = %d, queries = %d, want 100 rows from one query", e.rows, e.queries)
+	}
+}
+
+func TestExporterTruncatedWindow(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// All events share the same timestamp, so no window is ever complete.
+	fn := func(context.Context, time.Time, time.Time) (*query.Result, error) {
+		res := &query.Result{
+			Tables: []query.Table{{
+				Fields:  []query.Field{{Name: "_time"}},
+				Columns: []query.Column{{from.Format(time.RFC3339Nano)}},
+			}},
+		}
+		res.Status.RowsMatched = 10
+		return res, nil
+	}
+
+	e := newExporter(io.Discard, iofmt.NDJSON, fn)
+	if err := e.Export(t.Context(), from, from.Add(10*time.Millisecond), true); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.truncated == 0 {
+		t.Error("expected truncated windows")
+	}
+}
+
+func TestExporterMaxQueries(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// More rows are matched than returned, no matter how small the window.
+	fn := func(_ context.Context, start, _ time.Time) (*query.Result, error) {
+		res := &query.Result{
+			Tables: []query.Table{{
+				Fields:  []query.Field{{Name: "_time"}},
+				Columns: []query.Column{{start.Format(time.RFC3339Nano)}},
+			}},
+		}
+		res.Status.RowsMatched = 2
+		return res, nil
+	}
+
+	e := newExporter(io.Discard, iofmt.NDJSON, fn)
+	err := e.Export(t.Context(), from, from.Add(time.Hour), true)
+	if err == nil || !strings.Contains(err.Error(), "export stopped after 10,000 queries") {
+		t.Fatalf("Export() = %v, want the query limit to be hit", err)
+	}
+	if e.queries != maxExportQueries {
+		t.Errorf("queries = %d, want %d", e.queries, maxExportQueries)
+	}
+}
+
+func TestExporterAggregation(t *testing.T) {
+	fn := func(context.Context, time.Time, time.Time) (*query.Result, error) {
+		res := &query.Result{
+			Tables: []query.Table{{
+				Fields: []query.Field{
+					{Name: "count_", Aggregation: &query.Aggregation{Op: query.OpCount}},
+				},
+				Columns: []query.Column{{42}},
+			}},
+		}
+		res.Status.RowsMatched = 1000
+		return res, nil
+	}
+
+	var buf bytes.Buffer
+	e := newExporter(&buf, iofmt.NDJSON, fn)
+	if err := e.Export(t.Context(), time.Now().Add(-time.Hour), time.Now(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.queries != 1 {
+		t.Errorf("queries = %d, want 1", e.queries)
+	}
+	if got, want := buf.String(), "{\"count_\":42}\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestIsLimited(t *testing.T) {
+	tests := []struct {
+		apl  string
+		want bool
+	}{
+		{"['logs']", false},
+		{"['logs'] | where limitless == true", false},
+		{"['logs'] | limit 10", true},
+		{"['logs'] | take 10", true},
+		{"['logs']|top 5 by size", true},
+	}
+
+	for _, tt := range tests {
+		if got := isLimited(tt.apl); got != tt.want {
+			t.Errorf("isLimited(%q) = %v, want %v", tt.apl, got, tt.want)
+		}
+	}
+}
+
+func TestCreateOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader func(io.Reader) (io.Reader, error)
+	}{
+		{"results.ndjson", func(r io.Reader) (io.Reader, error) { return r, nil }},
+		{"results.ndjson.gz", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"results.ndjson.zst", func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+
+			w, err := createOutput(path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = io.WriteString(w, "{\"a\":1}\n"); err != nil {
+				t.Fatal(err)
+			}
+			if err = w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			r, err := tt.reader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != "{\"a\":1}\n" {
+				t.Errorf("got %q", got)
+			}
+		})
+	}
+}
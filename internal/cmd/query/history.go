@@ -0,0 +1,173 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/axiomhq/cli/internal/config"
+)
+
+const (
+	// historyFile is the name of the file the query history is kept in, in the
+	// data directory of the CLI. It holds one JSON object per line.
+	historyFile = "history.ndjson"
+	// historyMaxSize is the size the history file may grow to before the
+	// oldest entries are dropped.
+	historyMaxSize = 4 << 20
+	// historyKeepEntries is the number of entries kept when the history file
+	// is trimmed.
+	historyKeepEntries = 1000
+)
+
+// historyEntry is a query run recorded in the history.
+type historyEntry struct {
+	// Time the query was run at.
+	Time time.Time `json:"time"`
+	// Deployment the query was run against, see deploymentKey.
+	Deployment string `json:"deployment"`
+	// Query is the APL query, with all placeholders replaced.
+	Query string `json:"query"`
+	// StartTime and EndTime are the resolved time range of the query, if any.
+	StartTime time.Time `json:"start_time,omitzero"`
+	EndTime   time.Time `json:"end_time,omitzero"`
+	// DurationMS is how long it took to run the query, in milliseconds.
+	DurationMS int64 `json:"duration_ms"`
+	// Rows is the number of rows returned.
+	Rows uint64 `json:"rows"`
+	// TraceID of the query, if the server returned one.
+	TraceID string `json:"trace_id,omitempty"`
+	// Error the query failed with, if any.
+	Error string `json:"error,omitempty"`
+}
+
+func historyPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyFile), nil
+}
+
+// appendHistory appends the entry to the history. If the history grows too
+// large, the oldest entries are dropped.
+func appendHistory(entry historyEntry) error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	// Appending a single line keeps concurrently running queries from
+	// overwriting each others entries.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	stat, err := f.Stat()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || stat.Size() <= historyMaxSize {
+		return err
+	}
+	return trimHistory(path)
+}
+
+// trimHistory drops all but the newest entries of the history file.
+func trimHistory(path string) error {
+	entries, err := readHistoryFile(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) > historyKeepEntries {
+		entries = entries[len(entries)-historyKeepEntries:]
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err = enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), historyFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	} else if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// loadHistory loads the history, oldest entry first. If no queries were run
+// yet, the history is empty.
+func loadHistory() ([]historyEntry, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	return readHistoryFile(path)
+}
+
+func readHistoryFile(path string) ([]historyEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		entries []historyEntry
+		sc      = bufio.NewScanner(f)
+	)
+	sc.Buffer(nil, historyMaxSize)
+	for sc.Scan() {
+		var entry historyEntry
+		// Skip lines that can't be decoded, like a line cut short by a crash,
+		// instead of making the whole history unreadable.
+		if err = json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, sc.Err()
+}
+
+// clearHistory removes all entries from the history.
+func clearHistory() error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AXIOM_DATA_DIR", dir)
+
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entries) != 0 {
+		t.Fatalf("got %d entries, want none", len(entries))
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	want := []historyEntry{
+		{Time: now, Deployment: "prod", Query: "['http-logs'] | count", StartTime: now.Add(-time.Hour), EndTime: now, DurationMS: 120, Rows: 1, TraceID: "abc"},
+		{Time: now.Add(time.Minute), Deployment: "eu", Query: "['eu-logs']", Error: "boom"},
+	}
+	for _, entry := range want {
+		if err = appendHistory(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A line cut short must not make the history unreadable.
+	f, err := os.OpenFile(filepath.Join(dir, historyFile), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"time":"2024-`)
+	_ = f.Close()
+
+	if entries, err = loadHistory(); err != nil {
+		t.Fatal(err)
+	} else if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i := range want {
+		if !entries[i].Time.Equal(want[i].Time) || entries[i].Query != want[i].Query ||
+			!entries[i].StartTime.Equal(want[i].StartTime) || entries[i].TraceID != want[i].TraceID ||
+			entries[i].Error != want[i].Error {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	if err = clearHistory(); err != nil {
+		t.Fatal(err)
+	} else if entries, err = loadHistory(); err != nil || len(entries) != 0 {
+		t.Errorf("loadHistory() after clear = %d entries, %v, want none", len(entries), err)
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AXIOM_DATA_DIR", dir)
+
+	for i := range historyKeepEntries + 10 {
+		if err := appendHistory(historyEntry{Query: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := trimHistory(filepath.Join(dir, historyFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entries) != historyKeepEntries {
+		t.Fatalf("got %d entries, want %d", len(entries), historyKeepEntries)
+	}
+	if entries[0].Query != "10" {
+		t.Errorf("oldest entry = %s, want 10", entries[0].Query)
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	records := []historyRecord{
+		{N: 1, historyEntry: historyEntry{Query: "['HTTP-logs'] | count"}},
+		{N: 3, historyEntry: historyEntry{Query: "['eu-logs']"}},
+		{N: 4, historyEntry: historyEntry{Query: "['http-logs'] | where status >= 500"}},
+	}
+
+	tests := []struct {
+		search string
+		limit  int
+		want   []int
+	}{
+		{"", 0, []int{1, 3, 4}},
+		{"", 2, []int{3, 4}},
+		{"http-logs", 0, []int{1, 4}},
+		{"http-logs", 1, []int{4}},
+		{"traces", 0, nil},
+	}
+
+	for _, tt := range tests {
+		var got []int
+		for _, record := range searchHistory(records, tt.search, tt.limit) {
+			got = append(got, record.N)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("searchHistory(%q, %d) = %v, want %v", tt.search, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the built-in placeholders.
+const (
+	paramStartTime = "start_time"
+	paramEndTime   = "end_time"
+)
+
+// Types a parameter value can be given as.
+const (
+	paramTypeString   = "string"
+	paramTypeLong     = "long"
+	paramTypeReal     = "real"
+	paramTypeBool     = "bool"
+	paramTypeDatetime = "datetime"
+)
+
+var validParamTypes = []string{
+	paramTypeString,
+	paramTypeLong,
+	paramTypeReal,
+	paramTypeBool,
+	paramTypeDatetime,
+}
+
+var (
+	paramNameRe   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// aplStringEscaper escapes a string for use in a double quoted APL string
+// literal.
+var aplStringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// parseParams parses parameters in the form "<name>[:<type>]=<value>" into a
+// map of names to APL literals. Without a type, the type is inferred from the
+// value: integers are longs, other numbers are reals, "true" and "false" are
+// bools and everything else is a string.
+func parseParams(params []string, timestampFormat string) (map[string]string, error) {
+	res := make(map[string]string, len(params))
+	for _, p := range params {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter %q: must be in the form <name>=<value>", p)
+		}
+		name, typ, _ := strings.Cut(key, ":")
+
+		if !paramNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid parameter name %q", name)
+		} else if name == paramStartTime || name == paramEndTime {
+			return nil, fmt.Errorf("parameter name %q is reserved", name)
+		} else if _, ok := res[name]; ok {
+			return nil, fmt.Errorf("parameter %q given more than once", name)
+		}
+
+		literal, err := aplLiteral(value, typ, timestampFormat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %q: %w", name, err)
+		}
+		res[name] = literal
+	}
+	return res, nil
+}
+
+// aplLiteral returns the APL literal of the value of the given type. If the
+// type is empty, it is inferred from the value.
+func aplLiteral(value, typ, timestampFormat string) (string, error) {
+	if typ == "" {
+		typ = inferParamType(value)
+	}
+
+	switch typ {
+	case paramTypeString:
+		return `"` + aplStringEscaper.Replace(value) + `"`, nil
+	case paramTypeLong:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a long", value)
+		}
+		return strconv.FormatInt(i, 10), nil
+	case paramTypeReal:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("%q is not a real", value)
+		}
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	case paramTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a bool", value)
+		}
+		return strconv.FormatBool(b), nil
+	case paramTypeDatetime:
+		t, err := timeStrToTime(value, timestampFormat)
+		if err != nil {
+			return "", err
+		}
+		return aplDatetime(t), nil
+	}
+	return "", fmt.Errorf("invalid type %q (valid types: %s)", typ, strings.Join(validParamTypes, ", "))
+}
+
+func inferParamType(value string) string {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return paramTypeLong
+	} else if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return paramTypeReal
+	} else if value == "true" || value == "false" {
+		return paramTypeBool
+	}
+	return paramTypeString
+}
+
+func aplDatetime(t time.Time) string {
+	return "datetime(" + t.UTC().Format(time.RFC3339Nano) + ")"
+}
+
+// renderQuery replaces the placeholders in the form "{{<name>}}" in the query
+// with the APL literals of the parameters of the same name. The placeholders
+// "{{start_time}}" and "{{end_time}}" are replaced by the start and end time
+// of the query. It returns the names of the parameters not used by the query.
+func renderQuery(apl string, params map[string]string, startTime, endTime time.Time) (string, []string, error) {
+	var (
+		err  error
+		used = make(map[string]bool, len(params))
+	)
+	res := placeholderRe.ReplaceAllStringFunc(apl, func(placeholder string) string {
+		name := placeholderRe.FindStringSubmatch(placeholder)[1]
+
+		switch {
+		case name == paramStartTime && !startTime.IsZero():
+			return aplDatetime(startTime)
+		case name == paramEndTime && !endTime.IsZero():
+			return aplDatetime(endTime)
+		case name == paramStartTime || name == paramEndTime:
+			if err == nil {
+				err = fmt.Errorf("query uses {{%s}} but --%s is not given", name, strings.ReplaceAll(name, "_", "-"))
+			}
+			return placeholder
+		}
+
+		literal, ok := params[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("query uses {{%s}} but no parameter %q is given", name, name)
+			}
+			return placeholder
+		}
+		used[name] = true
+		return literal
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	var unused []string
+	for name := range params {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	slices.Sort(unused)
+
+	return res, unused, nil
+}
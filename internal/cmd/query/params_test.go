@@ -0,0 +1,107 @@
+package query
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseParams(t *testing.T) {
+	got, err := parseParams([]string{
+		"service=checkout",
+		"threshold=500",
+		"ratio=0.5",
+		"enabled=true",
+		`quoted=say "hi" \ bye` + "\n",
+		"code:string=404",
+		"limit:real=3",
+		"since:datetime=2024-01-02T03:04:05Z",
+		"empty=",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"service":   `"checkout"`,
+		"threshold": `500`,
+		"ratio":     `0.5`,
+		"enabled":   `true`,
+		"quoted":    `"say \"hi\" \\ bye\n"`,
+		"code":      `"404"`,
+		"limit":     `3.0`,
+		"since":     `datetime(2024-01-02T03:04:05Z)`,
+		"empty":     `""`,
+	}
+	for name, literal := range want {
+		if got[name] != literal {
+			t.Errorf("param %s = %s, want %s", name, got[name], literal)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d params, want %d", len(got), len(want))
+	}
+}
+
+func TestParseParamsErrors(t *testing.T) {
+	tests := []struct {
+		params []string
+		err    string
+	}{
+		{[]string{"service"}, "must be in the form"},
+		{[]string{"1x=a"}, "invalid parameter name"},
+		{[]string{"a-b=a"}, "invalid parameter name"},
+		{[]string{"start_time=now"}, "reserved"},
+		{[]string{"a=1", "a=2"}, "more than once"},
+		{[]string{"a:long=x"}, "not a long"},
+		{[]string{"a:bool=yes"}, "not a bool"},
+		{[]string{"a:real=inf"}, "not a real"},
+		{[]string{"a:timespan=1h"}, "invalid type"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseParams(tt.params, "")
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseParams(%q) error = %v, want error containing %q", tt.params, err, tt.err)
+		}
+	}
+}
+
+func TestRenderQuery(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	params := map[string]string{
+		"service":   `"check\"out"`,
+		"threshold": `500`,
+		"unused":    `1`,
+	}
+
+	got, unused, err := renderQuery(
+		"['logs'] | where service == {{service}} and duration > {{ threshold }} and _time between ({{start_time}} .. {{end_time}})",
+		params, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `['logs'] | where service == "check\"out" and duration > 500 and _time between (datetime(2024-01-01T00:00:00Z) .. datetime(2024-01-01T01:00:00Z))`
+	if got != want {
+		t.Errorf("renderQuery() = %s, want %s", got, want)
+	}
+	if !slices.Equal(unused, []string{"unused"}) {
+		t.Errorf("unused = %v, want [unused]", unused)
+	}
+}
+
+func TestRenderQueryErrors(t *testing.T) {
+	if _, _, err := renderQuery("['logs'] | where a == {{missing}}", nil, time.Time{}, time.Time{}); err == nil {
+		t.Error("expected error for missing parameter")
+	}
+	if _, _, err := renderQuery("['logs'] | where _time > {{start_time}}", nil, time.Time{}, time.Time{}); err == nil {
+		t.Error("expected error for missing start time")
+	}
+	if got, _, err := renderQuery("['logs'] | where a == 1", nil, time.Time{}, time.Time{}); err != nil || got != "['logs'] | where a == 1" {
+		t.Errorf("renderQuery() = %q, %v, want query unchanged", got, err)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +29,11 @@ type options struct {
 	// Query to run. If not supplied as an argument, which is optional, the user
 	// will be asked for it.
 	Query string
+	// File to read the query from. If "-", the query is read from stdin.
+	File string
+	// Params are the values of the placeholders in the query, in the form
+	// "<name>[:<type>]=<value>".
+	Params []string
 	// StartTime of the query.
 	StartTime string
 	// EndTime of the query.
@@ -35,11 +42,44 @@ type options struct {
 	TimestampFormat string
 	// Format to output data in. Defaults to tabular output.
 	Format string
+	// Transform shapes the output using a template or jq expression.
+	Transform iofmt.Transform
 	// FailOnEmpty exits with an error if the query returns no results.
 	FailOnEmpty bool
+	// All pages through the complete result of the query, instead of
+	// returning what the server returns for a single query.
+	All bool
+	// Output is the path of the file to write the result to, in the output
+	// format. Compressed if the path ends in ".gz" or ".zst". If "-", the
+	// result is written to stdout.
+	Output string
+	// Interactive runs queries read from the terminal in an interactive
+	// session.
+	Interactive bool
+	// Watch is the interval to run the query on repeatedly. Zero runs it once.
+	Watch time.Duration
+	// Stats prints the execution details of the query to stderr.
+	Stats bool
+	// Split is the size of the time windows the query range is split into,
+	// which are run concurrently. Empty runs the query at once.
+	Split string
+	// Concurrency is the maximum number of windows of a split query run at
+	// once.
+	Concurrency int
+	// Compare is the offset of the range to compare the result of the query
+	// with, eg: -1d. Empty doesn't compare the result.
+	Compare string
 
+	format    iofmt.Format
 	startTime time.Time
 	endTime   time.Time
+	split     time.Duration
+	compare   time.Duration
+	// stats are the execution details of the requests the query took.
+	stats queryStats
+	// history is the entry recorded for the query run. Running the query fills
+	// in the details of the result.
+	history historyEntry
 }
 
 // NewCmd creates and returns the query command.
@@ -49,7 +89,7 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:   "query [<apl-query>] [(-f|--format)=json|table] [--start-time <start-time>] [--end-time <end-time>] [--timestamp-format <timestamp-format>]",
+		Use:   "query [<apl-query>] [(-f|--format) <format> | (-t|--template) <template> | (-q|--jq) <expression>] [--start-time <start-time>] [--end-time <end-time>] [--timestamp-format <timestamp-format>] [(-F|--file) <filename>] [--param <name>=<value>...] [--all] [(-o|--output) <filename>] [(-w|--watch) <interval>] [--split <window> [--concurrency <n>]] [--compare <offset>] [--stats] [(-i|--interactive)]",
 		Short: "Query data using APL",
 		Long: heredoc.Doc(`
 			Query data from an Axiom dataset using APL, the Axiom Processing
@@ -64,11 +104,135 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			Omitted elements in the pattern are treated as zero or one as
 			applicable. See the Go reference documentation for examples:
 			https://pkg.go.dev/time#pkg-constants
+
+			Use "--file" to read the query from a file, or from stdin if "-" is
+			given. Placeholders in the form "{{name}}" are replaced by the value
+			of the parameter of the same name, given with "--param name=value".
+			Values are inserted as APL literals: strings are quoted and escaped,
+			so placeholders must not be quoted themselves. The type of a value
+			is inferred: integers are longs, other numbers are reals, "true" and
+			"false" are bools and everything else is a string. Give the type
+			explicitly with "--param name:type=value", using one of the types
+			string, long, real, bool or datetime. The placeholders
+			"{{start_time}}" and "{{end_time}}" are replaced by the start and
+			end time of the query, as datetime literals.
+
+			Rows are written as JSON objects by the "json" format, which writes
+			all of them as a single JSON array, and the "ndjson" format, which
+			writes one object per line. The "csv", "tsv" and "markdown" formats
+			write the fields of the result as columns, in the order of the
+			result.
+
+			The "chart" format draws aggregated results in the terminal, sized
+			to its width. Results with a "_time" field, like those of queries
+			that summarize by "bin_auto(_time)", are drawn as a line chart of
+			their aggregation or, if they are grouped by other fields or hold
+			multiple aggregations, as one sparkline per group and aggregation.
+			Results without a "_time" field are drawn as bars. Unicode braille
+			and block characters are used, unless color output is disabled or
+			the locale doesn't support Unicode, in which case the chart is
+			drawn in plain ASCII.
+
+			Use "--template" or "--jq" to shape the output with a Go template or
+			a jq expression instead. Both are given the rows of the result as
+			an array of JSON objects.
+
+			Queries like those using "fork" return multiple tables. In table
+			and chart output, every table is preceded by its name. The "json"
+			and "yaml" formats, as well as templates and jq expressions, are
+			given an object holding the rows of every table by its name. All
+			other formats write the tables one after another, separated by an
+			empty line. Totals of aggregations are only shown in table output.
+
+			The server limits the number of rows a single query returns. Use
+			"--all" to export the complete result: the query range is split
+			into smaller time windows until no window exceeds the limit. This
+			requires a start time. Windows are exported from the newest to the
+			oldest. Queries that aggregate or limit their rows on purpose are
+			run once. An export fails after 10,000 queries.
+
+			Use "--output" to write the result to a file instead of printing
+			it. With "--all" or "--output", the result is written as newline
+			delimited JSON, unless another format is given. The table format
+			is not supported. Files ending in ".gz" or ".zst" are compressed
+			using gzip or zstd, respectively. The number of rows written is
+			reported once done.
+
+			Queries can be saved under a name using "axiom query save" and run
+			by name using "axiom query run". Saved queries are kept per
+			deployment and can be shared using "axiom query export-saved" and
+			"axiom query import-saved".
+
+			Every query run is recorded in a local history, which is listed and
+			searched using "axiom query history". Run a query of the history
+			again using "axiom query history run".
+
+			Use "--interactive" to start an interactive session, which runs
+			queries as they are entered. Queries may span multiple lines and
+			dataset and field names are completed by pressing tab. The input
+			history is kept across sessions. The time range and format given
+			as flags apply to all queries of the session and are changed using
+			the ".since", ".range" and ".format" commands. Type ".help" in the
+			session for all commands.
+
+			Use "--watch" to run the query repeatedly on the given interval. On
+			a terminal, the result is redrawn in place and rows and values that
+			changed since the previous run are highlighted: new rows in green,
+			changed values in yellow. Otherwise, the result of every run is
+			appended to the output, preceded by a line with the time of the
+			run. Relative start and end times are resolved again on every run,
+			so the query range moves along.
+
+			Use "--split" to split the query range into time windows of the
+			given size, which are queried concurrently, for queries over long
+			ranges that would otherwise time out or hit limits. This requires a
+			start time. At most as many windows as given by "--concurrency"
+			are queried at once. The tables of the windows are merged by their
+			name and their rows are concatenated, newest first, so queries
+			that limit or sort their rows can't be split. Aggregated results
+			are aggregated again by their groups, which is only possible for
+			count, sum, min and max.
+			Fields computed from the aggregations, like using "extend" after
+			"summarize", can't be aggregated again and fail the query. So do
+			automatic bins, which differ between windows: bin times
+			explicitly, like using "bin(_time, 1h)". Windows that fail are
+			reported and left out of the result, which is then marked as
+			partial.
+
+			Use "--compare" to compare the result of an aggregated query with
+			the result for the query range shifted by the given offset, like
+			"-1d" for the day before or "-1w" for the week before. This
+			requires a start time. The rows of both results are joined by
+			their groups, with times of the previous result shifted onto
+			those of the current one. Every aggregation is followed by its
+			previous value, the delta and the change in percent, suffixed
+			"_prev", "_delta" and "_change_pct". In a table, increases are
+			highlighted in red and decreases in green.
+
+			Use "--stats" to print the execution details the server reports
+			for the query to stderr: the time it took, the number of rows
+			examined and matched, the cursors of the oldest and newest row
+			seen, whether the result is partial or estimated and the trace ID.
+			With the "json" format, they are printed as a JSON object. Queries
+			taking multiple requests, like those using "--all", report the
+			details of all requests summed up.
 		`),
 
 		DisableFlagsInUseLine: true,
 
-		Args: cmdutil.PopulateFromArgs(f, &opts.Query),
+		Args: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case opts.Interactive && len(args) > 0:
+				return cmdutil.NewFlagErrorf("a query can't be given together with --interactive")
+			case opts.Interactive:
+				return nil
+			case !cmd.Flags().Changed("file"):
+				return cmdutil.PopulateFromArgs(f, &opts.Query)(cmd, args)
+			case len(args) > 0:
+				return cmdutil.NewFlagErrorf("a query can't be given together with --file")
+			}
+			return nil
+		},
 
 		Example: heredoc.Doc(`
 			# Query the "http-logs" dataset for logs with a 304 status code:
@@ -76,6 +240,42 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			
 			# Count all events in the "http-logs" dataset with a 404 status code:
 			$ axiom query "['http-logs'] | where response == 404 | count"
+
+			# Run the query in "errors.apl", which contains placeholders like
+			# "where service == {{service}} and duration > {{threshold}}":
+			$ axiom query -F errors.apl --param service=checkout --param threshold=500
+
+			# Run a query read from stdin, for the last day:
+			$ cat errors.apl | axiom query -F - --start-time -1d --param service=checkout
+
+			# Export all events of the "http-logs" dataset of the last week to a
+			# zstd compressed file:
+			$ axiom query "['http-logs']" --start-time -7d --all --output http-logs.ndjson.zst
+
+			# Chart the requests of the "http-logs" dataset of the last day:
+			$ axiom query "['http-logs'] | summarize count() by bin_auto(_time)" --start-time -1d --format chart
+
+			# Print the URIs of all failed requests of the "http-logs" dataset:
+			$ axiom query "['http-logs']" --jq '.[] | select(.status >= 500) | .uri'
+
+			# Save the query in "errors.apl" as "errors" and run it:
+			$ axiom query save errors -F errors.apl --start-time -1h
+			$ axiom query run errors --param service=checkout
+
+			# Count the errors of the last 15 minutes by service, every 30 seconds:
+			$ axiom query "['http-logs'] | where status >= 500 | summarize count() by service" --start-time -15m --watch 30s
+
+			# Count the requests of the last four weeks by status, a day at a time:
+			$ axiom query "['http-logs'] | summarize count() by status" --start-time -4w --split 1d --concurrency 4
+
+			# Compare the errors by service with the same hour a day ago:
+			$ axiom query "['http-logs'] | where status >= 500 | summarize count() by service" --start-time -1h --compare -1d
+
+			# Print how many rows a query examines, to tune it:
+			$ axiom query "['http-logs'] | where uri contains 'api'" --start-time -1d --stats
+
+			# Explore data interactively, querying the last two hours:
+			$ axiom query --interactive --start-time -2h
 		`),
 
 		Annotations: map[string]string{
@@ -89,25 +289,132 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 		),
 
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			if err := complete(opts); err != nil {
-				return err
+			if opts.Interactive {
+				return runInteractive(cmd.Context(), opts)
 			}
-			return run(cmd.Context(), opts)
+			return runQuery(cmd, opts)
 		},
 	}
 
+	addQueryFlags(cmd, opts)
+	cmd.Flags().StringVarP(&opts.File, "file", "F", "", "File to read the query from (- for stdin)")
+	cmd.Flags().BoolVarP(&opts.Interactive, "interactive", "i", false, "Run queries in an interactive session")
+
+	_ = cmd.RegisterFlagCompletionFunc("file", aplFileCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("interactive", cmdutil.NoCompletion)
+
+	cmd.MarkFlagsMutuallyExclusive("interactive", "file")
+	cmd.MarkFlagsMutuallyExclusive("interactive", "all")
+	cmd.MarkFlagsMutuallyExclusive("interactive", "output")
+	cmd.MarkFlagsMutuallyExclusive("interactive", "watch")
+
+	cmd.AddCommand(newSaveCmd(f))
+	cmd.AddCommand(newListSavedCmd(f))
+	cmd.AddCommand(newRunCmd(f))
+	cmd.AddCommand(newDeleteSavedCmd(f))
+	cmd.AddCommand(newImportSavedCmd(f))
+	cmd.AddCommand(newExportSavedCmd(f))
+	cmd.AddCommand(newHistoryCmd(f))
+	cmd.AddCommand(newAssertCmd(f))
+
+	return cmd
+}
+
+// addQueryFlags adds the flags that control how a query is run and how its
+// result is output to the given command.
+func addQueryFlags(cmd *cobra.Command, opts *options) {
 	cmd.Flags().StringVarP(&opts.Format, "format", "f", iofmt.Table.String(), "Format to output data in")
 	cmd.Flags().StringVar(&opts.StartTime, "start-time", "", "Start time of the query - may also be a relative time eg: -2w, -7d, -24h, -20m")
 	cmd.Flags().StringVar(&opts.EndTime, "end-time", "", "End time of the query - may also be a relative time eg: -2w, -7d, -24h, -20m")
 	cmd.Flags().StringVar(&opts.TimestampFormat, "timestamp-format", "", "Format used in the the timestamp field. Default uses a heuristic parser. Must be expressed using the reference time 'Mon Jan 2 15:04:05 -0700 MST 2006'")
 	cmd.Flags().BoolVar(&opts.FailOnEmpty, "fail-on-empty", false, "Exit with error code 1 if query returns no results")
+	cmd.Flags().BoolVar(&opts.All, "all", false, "Page through the complete result by splitting the query range into time windows")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "File to write the result to, compressed if ending in .gz or .zst (- for stdout)")
+	cmd.Flags().StringArrayVar(&opts.Params, "param", nil, "Value of a query placeholder in the form name[:type]=value")
 
-	_ = cmd.RegisterFlagCompletionFunc("format", cmdutil.FormatCompletion)
+	cmd.Flags().DurationVarP(&opts.Watch, "watch", "w", 0, "Run the query repeatedly on the given interval, eg: 30s, 1m")
+	cmd.Flags().BoolVar(&opts.Stats, "stats", false, "Print execution statistics of the query to stderr")
+	cmd.Flags().StringVar(&opts.Split, "split", "", "Split the query range into time windows of the given size and run them concurrently, eg: 6h, 1d")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", 4, "Maximum number of time windows to query at once with --split")
+	cmd.Flags().StringVar(&opts.Compare, "compare", "", "Compare the result with the query range shifted by the given offset, eg: -1d, -1w")
+
+	cmdutil.AddTransformFlags(cmd, &opts.Transform)
+
+	cmd.MarkFlagsMutuallyExclusive("watch", "all")
+	cmd.MarkFlagsMutuallyExclusive("watch", "output")
+	cmd.MarkFlagsMutuallyExclusive("split", "all")
+	cmd.MarkFlagsMutuallyExclusive("split", "output")
+	cmd.MarkFlagsMutuallyExclusive("compare", "all")
+	cmd.MarkFlagsMutuallyExclusive("compare", "output")
+
+	_ = cmd.RegisterFlagCompletionFunc("format", cmdutil.FormatCompletionFunc(iofmt.Chart))
 	_ = cmd.RegisterFlagCompletionFunc("start-time", cmdutil.NoCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("end-time", cmdutil.NoCompletion)
 	_ = cmd.RegisterFlagCompletionFunc("timestamp-format", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("all", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("param", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("watch", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("stats", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("split", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("concurrency", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("compare", cmdutil.NoCompletion)
+}
 
-	return cmd
+// runQuery runs the query of the options and outputs or exports its result.
+func runQuery(cmd *cobra.Command, opts *options) error {
+	if opts.Watch < 0 {
+		return cmdutil.NewFlagErrorf("--watch must be a positive interval")
+	} else if opts.Watch > 0 {
+		return runWatch(cmd.Context(), opts)
+	}
+
+	if err := complete(opts); err != nil {
+		return err
+	}
+	if opts.All || opts.Output != "" {
+		if opts.Transform.IsSet() {
+			return cmdutil.NewFlagErrorf("--template and --jq are not supported with --all and --output")
+		}
+		if !cmd.Flag("format").Changed {
+			opts.format = iofmt.NDJSON
+		}
+		if opts.format == iofmt.Table || opts.format == iofmt.Chart {
+			return cmdutil.NewFlagErrorf("--all and --output don't support the %s format", opts.format)
+		} else if opts.All && opts.startTime.IsZero() {
+			return cmdutil.NewFlagErrorf("--start-time is required with --all")
+		}
+		return recordHistory(opts, runExport(cmd.Context(), opts))
+	}
+	return recordHistory(opts, run(cmd.Context(), opts))
+}
+
+// recordHistory records the query run in the history and returns the error the
+// run returned. Failing to record it does not fail the query.
+func recordHistory(opts *options, runErr error) error {
+	entry := opts.history
+	if entry.Time.IsZero() {
+		// The query was not run.
+		return runErr
+	}
+	entry.Deployment = deploymentKey(opts.Factory)
+	entry.Query = opts.Query
+	entry.StartTime = opts.startTime
+	entry.EndTime = opts.endTime
+	// Record the end time the query was effectively run with, so running it
+	// again from the history returns the same result.
+	if entry.EndTime.IsZero() && !entry.StartTime.IsZero() {
+		entry.EndTime = entry.Time
+	}
+	if runErr != nil {
+		entry.Error = runErr.Error()
+	}
+
+	if err := appendHistory(entry); err != nil && opts.IO.IsStderrTTY() {
+		fmt.Fprintf(opts.IO.ErrOut(), "%s Failed to record query in history: %v\n",
+			opts.IO.ColorScheme().WarningIcon(), err)
+	}
+
+	return runErr
 }
 
 func timeStrToTime(timeStr string, timestampFormat string) (time.Time, error) {
@@ -204,7 +511,38 @@ func parseDuration(s string) (time.Duration, error) {
 	return d, nil
 }
 
-func complete(opts *options) (err error) {
+// complete reads the query, if not given as an argument, and resolves it.
+func complete(opts *options) error {
+	if err := completeQuery(opts); err != nil {
+		return err
+	}
+	return resolveQuery(opts)
+}
+
+// completeQuery reads the query from the file, if given, or asks for it, if
+// not given at all.
+func completeQuery(opts *options) (err error) {
+	if opts.File != "" {
+		opts.Query, err = readQueryFile(opts.File, opts.IO.In())
+		return err
+	} else if opts.Query != "" {
+		return nil
+	}
+	return survey.AskOne(&survey.Input{
+		Message: "Which query to run?",
+	}, &opts.Query, opts.IO.SurveyIO())
+}
+
+// resolveQuery parses the format, transform and time range of the query and
+// replaces the placeholders in it. Relative times are resolved against the
+// current time.
+func resolveQuery(opts *options) (err error) {
+	if opts.format, err = cmdutil.ParseFormat(opts.Format, iofmt.Chart); err != nil {
+		return err
+	} else if err = cmdutil.CompileTransform(opts.IO, &opts.Transform); err != nil {
+		return err
+	}
+
 	if ts := opts.StartTime; ts != "" {
 		opts.startTime, err = timeStrToTime(ts, opts.TimestampFormat)
 		if err != nil {
@@ -219,13 +557,77 @@ func complete(opts *options) (err error) {
 		}
 	}
 
-	if opts.Query != "" {
-		return nil
+	if opts.Split != "" {
+		if opts.split, err = parseDuration(opts.Split); err != nil || opts.split <= 0 {
+			return cmdutil.NewFlagErrorf("invalid --split %q: must be a positive duration, eg: 6h, 1d", opts.Split)
+		} else if opts.startTime.IsZero() {
+			return cmdutil.NewFlagErrorf("--start-time is required with --split")
+		}
+	}
+	if opts.Compare != "" {
+		if opts.compare, err = parseDuration(opts.Compare); err != nil || opts.compare >= 0 {
+			return cmdutil.NewFlagErrorf("invalid --compare %q: must be a negative offset, eg: -1d, -1w", opts.Compare)
+		} else if opts.startTime.IsZero() {
+			return cmdutil.NewFlagErrorf("--start-time is required with --compare")
+		}
+		// The previous range is relative to the end of the current one, so
+		// it must not be left to the server.
+		if opts.endTime.IsZero() {
+			opts.endTime = time.Now()
+		}
+	}
+	if opts.Concurrency < 1 {
+		return cmdutil.NewFlagErrorf("--concurrency must be at least 1")
 	}
 
-	return survey.AskOne(&survey.Input{
-		Message: "Which query to run?",
-	}, &opts.Query, opts.IO.SurveyIO())
+	params, err := parseParams(opts.Params, opts.TimestampFormat)
+	if err != nil {
+		return cmdutil.NewFlagError(err)
+	}
+
+	var unused []string
+	if opts.Query, unused, err = renderQuery(opts.Query, params, opts.startTime, opts.endTime); err != nil {
+		return err
+	}
+	for _, name := range unused {
+		fmt.Fprintf(opts.IO.ErrOut(), "%s Parameter %s is not used by the query\n",
+			opts.IO.ColorScheme().WarningIcon(), opts.IO.ColorScheme().Bold(name))
+	}
+
+	// Every window would be limited or sorted on its own.
+	if opts.split > 0 && (isLimited(opts.Query) || isSorted(opts.Query)) {
+		return cmdutil.NewFlagErrorf("--split not valid when the query limits or sorts its rows, eg: using take, limit, top, sort or order")
+	}
+
+	return nil
+}
+
+// readQueryFile reads the query from the file at the given path or from stdin,
+// if the path is "-".
+func readQueryFile(path string, stdin io.Reader) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if path == "-" {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if apl := strings.TrimSpace(string(b)); apl != "" {
+		return apl, nil
+	} else if path == "-" {
+		return "", errors.New("no query read from stdin")
+	}
+	return "", fmt.Errorf("no query in %s", path)
+}
+
+func aplFileCompletion(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return []string{"apl", "kql"}, cobra.ShellCompDirectiveFilterFileExt
 }
 
 // printMessages writes the messages the server emitted for a query to w. It
@@ -245,17 +647,13 @@ func printMessages(w io.Writer, cs *terminal.ColorScheme, msgs []query.Message)
 	}
 }
 
+// resultIsEmpty returns true if none of the tables of the result has rows.
 func resultIsEmpty(res *query.Result) bool {
-	if res.Status.RowsMatched == 0 || len(res.Tables) == 0 {
+	if res.Status.RowsMatched == 0 {
 		return true
 	}
-	table := res.Tables[0]
-	if len(table.Columns) == 0 {
-		return true
-	}
-	// Check if all columns are empty (no rows).
-	for _, col := range table.Columns {
-		if len(col) > 0 {
+	for _, table := range resultTables(res) {
+		if tableLen(table.Table) > 0 {
 			return false
 		}
 	}
@@ -263,24 +661,74 @@ func resultIsEmpty(res *query.Result) bool {
 }
 
 func run(ctx context.Context, opts *options) error {
-	client, err := opts.Client(ctx)
+	res, err := execQuery(ctx, opts)
 	if err != nil {
 		return err
 	}
 
+	err = printResult(ctx, opts, res, nil)
+	if opts.Stats {
+		if statsErr := printStats(opts, opts.stats); err == nil {
+			err = statsErr
+		}
+	}
+	return err
+}
+
+// execQuery runs the query and fills in the details of the result the history
+// records.
+func execQuery(ctx context.Context, opts *options) (*query.Result, error) {
+	client, err := opts.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	progStop := opts.IO.StartActivityIndicator()
 	defer progStop()
 
-	res, err := client.Query(ctx, opts.Query,
-		query.SetStartTime(opts.startTime),
-		query.SetEndTime(opts.endTime),
-	)
+	fn := func(ctx context.Context, start, end time.Time) (*query.Result, error) {
+		return client.Query(ctx, opts.Query,
+			query.SetStartTime(start),
+			query.SetEndTime(end),
+		)
+	}
+
+	opts.history.Time = time.Now()
+	res, err := execRange(ctx, opts, fn, opts.startTime, opts.endTime)
+	if err == nil && opts.compare != 0 {
+		res, err = execCompare(ctx, opts, fn, res)
+	}
+	opts.history.DurationMS = time.Since(opts.history.Time).Milliseconds()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	opts.history.TraceID = res.TraceID
+	for _, table := range resultTables(res) {
+		opts.history.Rows += uint64(tableLen(table.Table))
 	}
 
-	progStop()
+	return res, nil
+}
+
+// execRange runs the query for the given range, split into windows if the
+// options ask for it.
+func execRange(ctx context.Context, opts *options, fn queryFunc, start, end time.Time) (*query.Result, error) {
+	if opts.split > 0 {
+		return execSplit(ctx, opts, fn, start, end)
+	}
+	res, err := fn(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	opts.stats.add(res.Status, res.TraceID)
+	return res, nil
+}
 
+// printResult writes the result of the query in the requested format. If diff
+// is not nil, the rows and values which changed since the previous result are
+// highlighted in table output.
+func printResult(ctx context.Context, opts *options, res *query.Result, diff *resultDiff) error {
 	cs := opts.IO.ColorScheme()
 
 	// Not TTY-gated, unlike the warnings in the root command: a truncated
@@ -300,19 +748,28 @@ func run(ctx context.Context, opts *options) error {
 		if opts.FailOnEmpty {
 			return errors.New("query returned no results")
 		}
-		if opts.Format == iofmt.JSON.String() {
-			fmt.Fprintln(opts.IO.Out(), "[]")
+		if opts.Transform.IsSet() {
+			return opts.Transform.Write(opts.IO.Out(), []any{}, opts.IO.ColorEnabled())
+		} else if opts.format != iofmt.Table && opts.format != iofmt.Chart {
+			var fields []string
+			if len(res.Tables) > 0 {
+				fields = tableFieldNames(res.Tables[0])
+			}
+			return iofmt.FormatRecords(opts.IO.Out(), opts.format, fields, []any(nil), opts.IO.ColorEnabled())
 		} else if opts.IO.IsStdoutTTY() {
 			fmt.Fprintln(opts.IO.Out(), "No results")
 		}
 		return nil
 	}
 
-	pagerStop, err := opts.IO.StartPager(ctx)
-	if err != nil {
-		return err
+	// Watching redraws the result on every run, which a pager would prevent.
+	if opts.Watch == 0 {
+		pagerStop, err := opts.IO.StartPager(ctx)
+		if err != nil {
+			return err
+		}
+		defer pagerStop()
 	}
-	defer pagerStop()
 
 	headerText := cs.Bold(opts.Query)
 	headerText += fmt.Sprintf(" processed in %s", cs.Gray(res.Status.ElapsedTime.String()))
@@ -321,31 +778,88 @@ func run(ctx context.Context, opts *options) error {
 	}
 	headerText = fmt.Sprintf("Result of query %s:\n\n", headerText)
 
-	table := res.Tables[0]
+	tables := resultTables(res)
+
+	// Deal with templates, jq expressions and all formats but table and
+	// chart. Every row is a record, regardless of whether the result is
+	// aggregated.
+	if opts.Transform.IsSet() || (opts.format != iofmt.Table && opts.format != iofmt.Chart) {
+		if opts.Transform.IsSet() {
+			return opts.Transform.Write(opts.IO.Out(), resultRecords(tables), opts.IO.ColorEnabled())
+		}
 
-	// Deal with JSON output format. Outputs NDJSON (one JSON object per row)
-	// for all results, regardless of whether they are aggregated.
-	if opts.Format == iofmt.JSON.String() {
 		if opts.IO.IsStdoutTTY() {
 			fmt.Fprint(opts.IO.Out(), headerText)
 		}
 
-		for i := range len(table.Columns[0]) {
-			if err = iofmt.FormatToJSON(opts.IO.Out(), tableRowAtIndex(table, i), opts.IO.ColorEnabled()); err != nil {
-				return err
+		return formatResultRecords(opts, tables)
+	}
+
+	if opts.IO.IsStdoutTTY() {
+		fmt.Fprint(opts.IO.Out(), headerText)
+	}
+
+	// If the result has multiple tables, every table is preceded by its name.
+	for i, table := range tables {
+		if len(tables) > 1 {
+			if i > 0 {
+				fmt.Fprintln(opts.IO.Out())
 			}
+			fmt.Fprintln(opts.IO.Out(), cs.Bold("Table "+table.name))
+		}
+
+		// Only the first table is compared to the previous result.
+		var tableDiff *resultDiff
+		if i == 0 {
+			tableDiff = diff
+		}
+
+		var err error
+		if opts.format == iofmt.Chart {
+			err = printChart(opts, table)
+		} else {
+			err = printTable(opts, table, tableDiff)
+		}
+		if err != nil {
+			return err
 		}
+	}
 
+	return nil
+}
+
+// printChart draws the aggregations of the table as a chart.
+func printChart(opts *options, table resultTable) error {
+	if tableLen(table.Table) == 0 {
+		if opts.IO.IsStdoutTTY() {
+			fmt.Fprintln(opts.IO.Out(), "No results")
+		}
 		return nil
 	}
 
-	// Deal with table output for a result with more than ten fields as it
-	// wouldn't be readable in a table.
-	if !tableHasAggregation(table) && len(table.Fields) > 10 {
+	chart, err := tableChart(table.Table)
+	if err != nil {
+		return err
+	}
+	return iofmt.FormatToChart(opts.IO, chart)
+}
+
+// printTable writes the table in table format. If diff is not nil, the rows
+// and values which changed since the previous result are highlighted.
+func printTable(opts *options, rt resultTable, diff *resultDiff) error {
+	cs := opts.IO.ColorScheme()
+	table := rt.Table
+
+	if tableLen(table) == 0 {
 		if opts.IO.IsStdoutTTY() {
-			fmt.Fprint(opts.IO.Out(), headerText)
+			fmt.Fprintln(opts.IO.Out(), "No results")
 		}
+		return nil
+	}
 
+	// Deal with table output for a result with more than ten fields as it
+	// wouldn't be readable in a table.
+	if !tableHasAggregation(table) && len(table.Fields) > 10 {
 		hasTimeField := slices.ContainsFunc(table.Fields, func(field query.Field) bool {
 			return field.Name == "_time"
 		})
@@ -366,7 +880,7 @@ func run(ctx context.Context, opts *options) error {
 			if hasSysTimeField {
 				delete(row, "_sysTime")
 			}
-			if err = iofmt.FormatToJSON(opts.IO.Out(), row, opts.IO.ColorEnabled()); err != nil {
+			if err := iofmt.FormatToJSON(opts.IO.Out(), row, opts.IO.ColorEnabled()); err != nil {
 				return err
 			}
 		}
@@ -374,32 +888,9 @@ func run(ctx context.Context, opts *options) error {
 		return nil
 	}
 
-	// Deal with table output format for non-aggregated results.
-	if !tableHasAggregation(table) {
-		var header iofmt.HeaderBuilderFunc
-		if opts.IO.IsStdoutTTY() {
-			header = func(_ io.Writer, trb iofmt.TableRowBuilder) {
-				fmt.Fprint(opts.IO.Out(), headerText)
-				for _, field := range table.Fields {
-					trb.AddField(field.Name, cs.Bold)
-				}
-			}
-		}
-
-		contentRow := func(trb iofmt.TableRowBuilder, k int) {
-			for _, column := range table.Columns {
-				trb.AddField(fmt.Sprint(column[k]), nil)
-			}
-		}
-
-		return iofmt.FormatToTable(opts.IO, len(table.Columns[0]), header, nil, contentRow)
-	}
-
-	// Deal with table output format for aggregated results.
 	var header iofmt.HeaderBuilderFunc
 	if opts.IO.IsStdoutTTY() {
 		header = func(_ io.Writer, trb iofmt.TableRowBuilder) {
-			fmt.Fprint(opts.IO.Out(), headerText)
 			for _, field := range table.Fields {
 				trb.AddField(field.Name, cs.Bold)
 			}
@@ -407,14 +898,21 @@ func run(ctx context.Context, opts *options) error {
 	}
 
 	contentRow := func(trb iofmt.TableRowBuilder, k int) {
-		for _, column := range table.Columns {
-			trb.AddField(fmt.Sprint(column[k]), nil)
+		for i, column := range table.Columns {
+			style := diff.style(cs, k, i)
+			if opts.compare != 0 {
+				if compared := compareStyle(cs, table, k, i); compared != nil {
+					style = compared
+				}
+			}
+			trb.AddField(fmt.Sprint(column[k]), style)
 		}
 	}
 
+	// Deal with the totals of aggregated results.
 	var footer iofmt.HeaderBuilderFunc
-	if opts.IO.IsStdoutTTY() && len(res.Tables) > 1 && res.Tables[1].Name == "_totals" {
-		totalsTable := res.Tables[1]
+	if opts.IO.IsStdoutTTY() && tableHasAggregation(table) && rt.totals != nil {
+		totalsTable := *rt.totals
 		footer = func(_ io.Writer, trb iofmt.TableRowBuilder) {
 			trb.AddField("Total", cs.Bold) // Account for the _time field present in the former table.
 			for i, field := range totalsTable.Fields {
@@ -430,6 +928,102 @@ func run(ctx context.Context, opts *options) error {
 	return iofmt.FormatToTable(opts.IO, len(table.Columns[0]), header, footer, contentRow)
 }
 
+// resultTable is a table of a query result, along with its totals.
+type resultTable struct {
+	query.Table
+
+	// name of the table, which is its position in the result if the server
+	// didn't name it.
+	name string
+	// totals of the table, if the result holds them.
+	totals *query.Table
+}
+
+// resultTables returns the tables of the result. The server returns the totals
+// of a table as a table named "_totals" following it, which is attached to the
+// table it belongs to.
+func resultTables(res *query.Result) []resultTable {
+	tables := make([]resultTable, 0, len(res.Tables))
+	for i, table := range res.Tables {
+		if table.Name == "_totals" && len(tables) > 0 {
+			tables[len(tables)-1].totals = &res.Tables[i]
+			continue
+		}
+
+		name := table.Name
+		if name == "" {
+			name = strconv.Itoa(len(tables))
+		}
+		tables = append(tables, resultTable{Table: table, name: name})
+	}
+	return tables
+}
+
+// resultRecords returns the rows of the tables as records: the rows of a
+// single table or, if there are multiple tables, the rows of every table by
+// its name.
+func resultRecords(tables []resultTable) any {
+	if len(tables) == 1 {
+		return tableRows(tables[0].Table)
+	}
+
+	records := make(map[string][]map[string]any, len(tables))
+	for _, table := range tables {
+		records[table.name] = tableRows(table.Table)
+	}
+	return records
+}
+
+// formatResultRecords writes the rows of the tables in the format of the
+// options, which must be neither table nor chart. The tables of a result with
+// multiple tables are written as sections: JSON and YAML hold the rows of every
+// table by its name, Markdown precedes every table by a heading and all other
+// formats separate the tables by an empty line.
+func formatResultRecords(opts *options, tables []resultTable) error {
+	var (
+		out   = opts.IO.Out()
+		color = opts.IO.ColorEnabled()
+	)
+
+	switch {
+	case len(tables) == 1:
+		return iofmt.FormatRecords(out, opts.format, tableFieldNames(tables[0].Table), tableRows(tables[0].Table), color)
+	case opts.format == iofmt.JSON:
+		return iofmt.FormatToJSON(out, resultRecords(tables), color)
+	case opts.format == iofmt.YAML:
+		return iofmt.FormatToYAML(out, resultRecords(tables))
+	}
+
+	for i, table := range tables {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if opts.format == iofmt.Markdown {
+			fmt.Fprintf(out, "### %s\n\n", table.name)
+		}
+		if err := iofmt.FormatRecords(out, opts.format, tableFieldNames(table.Table), tableRows(table.Table), color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableLen returns the number of rows of the table.
+func tableLen(table query.Table) int {
+	if len(table.Columns) == 0 {
+		return 0
+	}
+	return len(table.Columns[0])
+}
+
+func tableRows(table query.Table) []map[string]any {
+	rows := make([]map[string]any, tableLen(table))
+	for i := range rows {
+		rows[i] = tableRowAtIndex(table, i)
+	}
+	return rows
+}
+
 func tableHasAggregation(table query.Table) bool {
 	for _, field := range table.Fields {
 		if field.Aggregation != nil {
@@ -439,6 +1033,14 @@ func tableHasAggregation(table query.Table) bool {
 	return false
 }
 
+func tableFieldNames(table query.Table) []string {
+	names := make([]string, len(table.Fields))
+	for i, field := range table.Fields {
+		names[i] = field.Name
+	}
+	return names
+}
+
 func tableRowAtIndex(table query.Table, rowIdx int) map[string]any {
 	row := make(map[string]any, len(table.Fields))
 	for i, field := range table.Fields {
@@ -0,0 +1,303 @@
+package query
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmd/auth"
+	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/pkg/iofmt"
+	"github.com/axiomhq/cli/pkg/utils"
+)
+
+// assertFailedExitCode is the exit code of "axiom query assert" if an
+// expectation fails. Errors running the query exit with 1, like any other
+// error.
+const assertFailedExitCode = 3
+
+type assertOptions struct {
+	options
+
+	// Expect are the expectations the rows of the result must meet.
+	Expect []string
+	// JUnit is the file to write a JUnit XML report to (- for stdout).
+	JUnit string
+
+	expectations []expectation
+}
+
+func newAssertCmd(f *cmdutil.Factory) *cobra.Command {
+	opts := &assertOptions{
+		options: options{
+			Factory:     f,
+			Format:      iofmt.Table.String(),
+			Concurrency: 1,
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "assert [<apl-query>] --expect <expression>... [(-F|--file) <filename>] [--start-time <start-time>] [--end-time <end-time>] [--timestamp-format <timestamp-format>] [--param <name>=<value>...] [--fail-on-empty] [--junit <filename>]",
+		Short: "Check the result of a query against expectations",
+		Long: heredoc.Doc(`
+			Run a query and check its result against expectations, for
+			example to gate a deployment on the error rate.
+
+			Every expectation given using "--expect" compares two operands
+			using one of "<", "<=", ">", ">=", "==" and "!=". An operand is a
+			number, a string in single or double quotes, true, false, null
+			or the name of a field of the result. Names of fields that are
+			not identifiers are given like in APL, as in ['name'], or in
+			backticks. A field which is not in the result is looked up with
+			an underscore appended, the name APL gives aggregations that are
+			not named explicitly, so "count" refers to the field of
+			"count()".
+
+			An expectation must hold for every row of the result that has
+			the fields it refers to, so aggregate by a group to check every
+			group. Without rows, expectations hold. Use "--fail-on-empty" to
+			also expect rows.
+
+			A report of the expectations and the rows that failed them is
+			printed. Use "--junit" to also write the report as JUnit XML, for
+			CI systems to pick up.
+
+			The command exits with code 0 if all expectations hold, with code
+			3 if any of them fails and with code 1 if the query can't be
+			run.
+		`),
+
+		DisableFlagsInUseLine: true,
+
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("file") && len(args) > 0 {
+				return cmdutil.NewFlagErrorf("a query can't be given together with --file")
+			} else if cmd.Flags().Changed("file") {
+				return nil
+			}
+			return cmdutil.PopulateFromArgs(f, &opts.Query)(cmd, args)
+		},
+
+		Example: heredoc.Doc(`
+			# Expect less than 10 errors in the last 15 minutes:
+			$ axiom query assert "['http-logs'] | where status >= 500 | summarize count()" --start-time -15m --expect 'count < 10'
+
+			# Expect every service to have an error rate below 1%:
+			$ axiom query assert "['http-logs'] | summarize errors = countif(status >= 500), total = count() by service | extend rate = 100.0 * errors / total" --start-time -1h --expect 'rate < 1'
+
+			# Check the latency and write a JUnit report for the CI system:
+			$ axiom query assert -F latency.apl --start-time -1h --expect 'max_latency <= 500' --expect 'count > 0' --junit report.xml
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+			cmdutil.NeedsDatasets(f),
+		),
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := completeAssert(opts); err != nil {
+				return err
+			}
+			return recordHistory(&opts.options, runAssert(cmd.Context(), opts))
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&opts.Expect, "expect", nil, "Expectation every row of the result must meet, eg: 'count < 10'")
+	cmd.Flags().StringVar(&opts.JUnit, "junit", "", "File to write a JUnit XML report to (- for stdout)")
+	cmd.Flags().StringVarP(&opts.File, "file", "F", "", "File to read the query from (- for stdin)")
+	cmd.Flags().StringVar(&opts.StartTime, "start-time", "", "Start time of the query - may also be a relative time eg: -2w, -7d, -24h, -20m")
+	cmd.Flags().StringVar(&opts.EndTime, "end-time", "", "End time of the query - may also be a relative time eg: -2w, -7d, -24h, -20m")
+	cmd.Flags().StringVar(&opts.TimestampFormat, "timestamp-format", "", "Format used in the the timestamp field. Default uses a heuristic parser. Must be expressed using the reference time 'Mon Jan 2 15:04:05 -0700 MST 2006'")
+	cmd.Flags().StringArrayVar(&opts.Params, "param", nil, "Value of a query placeholder in the form name[:type]=value")
+	cmd.Flags().BoolVar(&opts.FailOnEmpty, "fail-on-empty", false, "Fail if the query returns no results")
+
+	_ = cmd.MarkFlagRequired("expect")
+
+	_ = cmd.RegisterFlagCompletionFunc("expect", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("junit", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("file", aplFileCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("start-time", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("end-time", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("timestamp-format", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("param", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("fail-on-empty", cmdutil.NoCompletion)
+
+	return cmd
+}
+
+func completeAssert(opts *assertOptions) error {
+	for _, s := range opts.Expect {
+		e, err := parseExpectation(s)
+		if err != nil {
+			return cmdutil.NewFlagError(err)
+		}
+		opts.expectations = append(opts.expectations, e)
+	}
+	return complete(&opts.options)
+}
+
+func runAssert(ctx context.Context, opts *assertOptions) error {
+	res, err := execQuery(ctx, &opts.options)
+	if err != nil {
+		return err
+	}
+
+	cs := opts.IO.ColorScheme()
+	printMessages(opts.IO.ErrOut(), cs, res.Status.Messages)
+
+	tables := resultTables(res)
+	results := make([]expectationResult, 0, len(opts.expectations)+1)
+	if opts.FailOnEmpty {
+		e := expectationResult{expectation: expectation{expr: "result is not empty"}, Rows: 1}
+		if resultIsEmpty(res) {
+			e.Failed = 1
+		}
+		results = append(results, e)
+	}
+	for _, e := range opts.expectations {
+		results = append(results, checkExpectation(e, tables))
+	}
+
+	// The report goes to stderr if the JUnit report is written to stdout.
+	w := opts.IO.Out()
+	if opts.JUnit == "-" {
+		w = opts.IO.ErrOut()
+	}
+
+	var failed int
+	for _, r := range results {
+		if r.Passed() {
+			fmt.Fprintf(w, "%s %s\n", cs.SuccessIcon(), r)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "%s %s: %s\n", cs.ErrorIcon(), cs.Bold(r.String()), r.Message())
+		for _, row := range r.FailedRows {
+			fmt.Fprintf(w, "    %s\n", cs.Gray(row))
+		}
+		if more := r.Failed - len(r.FailedRows); more > 0 {
+			fmt.Fprintf(w, "    %s\n", cs.Gray(fmt.Sprintf("and %d more", more)))
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(w, "\n%s of %s failed\n", cs.Red(fmt.Sprint(failed)), utils.Pluralize(cs, "expectation", len(results)))
+	} else {
+		fmt.Fprintf(w, "\n%s passed\n", utils.Pluralize(cs, "expectation", len(results)))
+	}
+
+	if opts.JUnit != "" {
+		if err = writeJUnitReport(opts, results); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return cmdutil.NewExitError(assertFailedExitCode, cmdutil.ErrSilent)
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnitReport writes the results of the expectations as a JUnit XML
+// report with one test case per expectation.
+func writeJUnitReport(opts *assertOptions, results []expectationResult) (err error) {
+	suite := junitTestSuite{
+		Name:      "axiom query assert",
+		Tests:     len(results),
+		Time:      fmt.Sprintf("%.3f", time.Duration(opts.history.DurationMS*int64(time.Millisecond)).Seconds()),
+		Timestamp: opts.history.Time.UTC().Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "query", Value: opts.Query},
+		},
+	}
+	if !opts.startTime.IsZero() {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "start_time", Value: opts.startTime.UTC().Format(time.RFC3339)})
+	}
+	if !opts.endTime.IsZero() {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "end_time", Value: opts.endTime.UTC().Format(time.RFC3339)})
+	}
+	for _, r := range results {
+		tc := junitTestCase{
+			Name:      r.String(),
+			ClassName: "axiom.query.assert",
+		}
+		if !r.Passed() {
+			suite.Failures++
+			text := r.FailedRows
+			if more := r.Failed - len(r.FailedRows); more > 0 {
+				text = append(text, fmt.Sprintf("and %d more", more))
+			}
+			tc.Failure = &junitFailure{
+				Message: r.Message(),
+				Text:    strings.Join(text, "\n"),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	var w io.Writer
+	if opts.JUnit == "-" {
+		w = opts.IO.Out()
+	} else {
+		var f *os.File
+		if f, err = os.Create(opts.JUnit); err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err = enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
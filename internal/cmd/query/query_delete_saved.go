@@ -0,0 +1,55 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmd/auth"
+	"github.com/axiomhq/cli/internal/cmdutil"
+)
+
+func newDeleteSavedCmd(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete-saved <name>",
+		Short: "Delete a saved query",
+
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: savedQueryCompletionFunc(f),
+
+		Example: heredoc.Doc(`
+			# Delete the saved query "errors":
+			$ axiom query delete-saved errors
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+		),
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			name := args[0]
+
+			sqs, err := loadSavedQueries()
+			if err != nil {
+				return err
+			}
+
+			if !sqs.Delete(deploymentKey(f), name) {
+				return fmt.Errorf("no saved query %q", name)
+			} else if err = sqs.Write(); err != nil {
+				return err
+			}
+
+			if f.IO.IsStderrTTY() {
+				cs := f.IO.ColorScheme()
+				fmt.Fprintf(f.IO.ErrOut(), "%s Deleted saved query %s\n", cs.Red("✓"), cs.Bold(name))
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
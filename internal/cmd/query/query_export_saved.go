@@ -0,0 +1,65 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmd/auth"
+	"github.com/axiomhq/cli/internal/cmdutil"
+)
+
+func newExportSavedCmd(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-saved [<name>...]",
+		Short: "Export saved queries to share them",
+		Long: heredoc.Doc(`
+			Export saved queries of the active deployment to stdout, in a
+			format "axiom query import-saved" reads. Without names, all saved
+			queries are exported.
+		`),
+
+		ValidArgsFunction: func(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return savedQueryCompletionFunc(f)(cmd, nil, toComplete)
+		},
+
+		Example: heredoc.Doc(`
+			# Export all saved queries to "queries.toml":
+			$ axiom query export-saved > queries.toml
+
+			# Export the saved queries "errors" and "latency":
+			$ axiom query export-saved errors latency > queries.toml
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+		),
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			sqs, err := loadSavedQueries()
+			if err != nil {
+				return err
+			}
+
+			key := deploymentKey(f)
+			if len(args) == 0 {
+				args = sqs.Names(key)
+			}
+
+			queries := make(map[string]savedQuery, len(args))
+			for _, name := range args {
+				sq, ok := sqs.Get(key, name)
+				if !ok {
+					return fmt.Errorf("no saved query %q", name)
+				}
+				queries[name] = sq
+			}
+
+			return writeSharedQueries(f.IO.Out(), queries)
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,337 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmd/auth"
+	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/pkg/iofmt"
+)
+
+type historyOptions struct {
+	*cmdutil.Factory
+
+	// Search term to filter the history by. Optional.
+	Search string
+	// Limit is the maximum number of entries to list. Zero lists all.
+	Limit int
+	// Format to output data in. Defaults to tabular output.
+	Format string
+	// Transform shapes the output using a template or jq expression.
+	Transform iofmt.Transform
+}
+
+// historyRecord is an entry of the history, as it is output.
+type historyRecord struct {
+	// N is the number of the entry, used to run it again.
+	N int `json:"n"`
+	historyEntry
+}
+
+// historyFields are the fields of a history entry in tabular output formats.
+var historyFields = []string{"n", "time", "deployment", "query", "start_time", "end_time", "duration_ms", "rows", "trace_id", "error"}
+
+func newHistoryCmd(f *cmdutil.Factory) *cobra.Command {
+	opts := &historyOptions{
+		Factory: f,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "history [<search>] [(-n|--limit) <limit>] [(-f|--format) <format> | (-t|--template) <template> | (-q|--jq) <expression>]",
+		Short: "List the history of queries",
+		Long: heredoc.Doc(`
+			List the queries run against the active deployment, oldest first.
+
+			Every query run using "axiom query" is recorded in the history,
+			along with its time range, how long it took, the number of rows it
+			returned and its trace ID. The history is kept in the data
+			directory of the CLI. When it grows too large, the oldest entries
+			are dropped.
+
+			If a search term is given, only queries containing it are listed,
+			ignoring case. Run a query of the history again using
+			"axiom query history run" and the number of the entry.
+		`),
+
+		DisableFlagsInUseLine: true,
+
+		Args: cobra.MaximumNArgs(1),
+
+		Example: heredoc.Doc(`
+			# List the last 20 queries:
+			$ axiom query history
+
+			# List all queries of the "http-logs" dataset:
+			$ axiom query history http-logs --limit 0
+
+			# Run the query with the number 42 again:
+			$ axiom query history run 42
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+		),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				opts.Search = args[0]
+			}
+			return runHistory(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "n", 20, "Maximum number of queries to list (0 for all)")
+	cmd.Flags().StringVarP(&opts.Format, "format", "f", iofmt.Table.String(), "Format to output data in")
+
+	_ = cmd.RegisterFlagCompletionFunc("limit", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("format", cmdutil.FormatCompletion)
+
+	cmdutil.AddTransformFlags(cmd, &opts.Transform)
+
+	cmd.AddCommand(newHistoryRunCmd(f))
+	cmd.AddCommand(newHistoryClearCmd(f))
+
+	return cmd
+}
+
+// deploymentHistory returns the entries of the history of the active
+// deployment, numbered by their position in the history.
+func deploymentHistory(f *cmdutil.Factory) ([]historyRecord, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	key := deploymentKey(f)
+	records := make([]historyRecord, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Deployment == key {
+			records = append(records, historyRecord{N: i + 1, historyEntry: entry})
+		}
+	}
+	return records, nil
+}
+
+// searchHistory returns the records whose query contains the search term,
+// ignoring case. At most limit records are returned, the newest ones.
+func searchHistory(records []historyRecord, search string, limit int) []historyRecord {
+	if search != "" {
+		search = strings.ToLower(search)
+
+		matches := make([]historyRecord, 0, len(records))
+		for _, record := range records {
+			if strings.Contains(strings.ToLower(record.Query), search) {
+				matches = append(matches, record)
+			}
+		}
+		records = matches
+	}
+
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	return records
+}
+
+func runHistory(ctx context.Context, opts *historyOptions) error {
+	format, err := cmdutil.ParseFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	if err = cmdutil.CompileTransform(opts.IO, &opts.Transform); err != nil {
+		return err
+	}
+	if opts.Limit < 0 {
+		return cmdutil.NewFlagErrorf("--limit must not be negative")
+	}
+
+	records, err := deploymentHistory(opts.Factory)
+	if err != nil {
+		return err
+	}
+	records = searchHistory(records, opts.Search, opts.Limit)
+
+	pagerStop, err := opts.IO.StartPager(ctx)
+	if err != nil {
+		return err
+	}
+	defer pagerStop()
+
+	if opts.Transform.IsSet() {
+		return opts.Transform.Write(opts.IO.Out(), records, opts.IO.ColorEnabled())
+	} else if format != iofmt.Table {
+		return iofmt.FormatRecords(opts.IO.Out(), format, historyFields, records, opts.IO.ColorEnabled())
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(opts.IO.Out(), "No queries found.")
+		return nil
+	}
+
+	cs := opts.IO.ColorScheme()
+
+	var header iofmt.HeaderBuilderFunc
+	if opts.IO.IsStdoutTTY() {
+		header = func(_ io.Writer, trb iofmt.TableRowBuilder) {
+			trb.AddField("#", cs.Bold)
+			trb.AddField("Time", cs.Bold)
+			trb.AddField("Time range", cs.Bold)
+			trb.AddField("Duration", cs.Bold)
+			trb.AddField("Rows", cs.Bold)
+			trb.AddField("Query", cs.Bold)
+		}
+	}
+
+	contentRow := func(trb iofmt.TableRowBuilder, k int) {
+		record := records[k]
+
+		trb.AddField(strconv.Itoa(record.N), nil)
+		trb.AddField(record.Time.Local().Format(time.RFC1123), cs.Gray)
+		trb.AddField(historyTimeRange(record.historyEntry), cs.Gray)
+		trb.AddField((time.Duration(record.DurationMS) * time.Millisecond).String(), nil)
+		if record.Error != "" {
+			trb.AddField("failed", cs.Red)
+		} else {
+			trb.AddField(humanize.Comma(int64(record.Rows)), nil)
+		}
+		// Multi-line queries would break the table.
+		trb.AddField(strings.Join(strings.Fields(record.Query), " "), nil)
+	}
+
+	return iofmt.FormatToTable(opts.IO, len(records), header, nil, contentRow)
+}
+
+func historyTimeRange(entry historyEntry) string {
+	const layout = time.DateTime
+
+	switch {
+	case entry.StartTime.IsZero() && entry.EndTime.IsZero():
+		return "-"
+	case entry.StartTime.IsZero():
+		return ".. " + entry.EndTime.Local().Format(layout)
+	case entry.EndTime.IsZero():
+		return entry.StartTime.Local().Format(layout) + " .. now"
+	}
+	return entry.StartTime.Local().Format(layout) + " .. " + entry.EndTime.Local().Format(layout)
+}
+
+func newHistoryRunCmd(f *cmdutil.Factory) *cobra.Command {
+	opts := &options{
+		Factory: f,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "run <n> [(-f|--format) <format> | (-t|--template) <template> | (-q|--jq) <expression>] [--start-time <start-time>] [--end-time <end-time>] [--timestamp-format <timestamp-format>] [--all] [(-o|--output) <filename>] [(-w|--watch) <interval>] [--split <window> [--concurrency <n>]] [--compare <offset>] [--stats]",
+		Short: "Run a query of the history again",
+		Long: heredoc.Doc(`
+			Run the query with the given number of the history again.
+
+			The query is run with the time range it was run with before, unless
+			another one is given as flags. All other flags behave like they do
+			for "axiom query".
+		`),
+
+		DisableFlagsInUseLine: true,
+
+		Args: cobra.ExactArgs(1),
+
+		Example: heredoc.Doc(`
+			# Run the query with the number 42 again:
+			$ axiom query history run 42
+
+			# Run the query with the number 42 again, for the last hour:
+			$ axiom query history run 42 --start-time -1h
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+			cmdutil.NeedsDatasets(f),
+		),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return cmdutil.NewFlagErrorf("invalid history entry %q: must be a number", args[0])
+			}
+
+			records, err := deploymentHistory(f)
+			if err != nil {
+				return err
+			}
+
+			var entry *historyEntry
+			for _, record := range records {
+				if record.N == n {
+					entry = &record.historyEntry
+					break
+				}
+			}
+			if entry == nil {
+				return fmt.Errorf("no query with number %d in the history", n)
+			}
+			opts.Query = entry.Query
+
+			// Flags given explicitly take precedence over the time range of
+			// the entry.
+			layout := time.RFC3339Nano
+			if opts.TimestampFormat != "" {
+				layout = opts.TimestampFormat
+			}
+			times := map[string]time.Time{
+				"start-time": entry.StartTime,
+				"end-time":   entry.EndTime,
+			}
+			for flag, t := range times {
+				if t.IsZero() || cmd.Flags().Changed(flag) {
+					continue
+				} else if err = cmd.Flags().Set(flag, t.Format(layout)); err != nil {
+					return err
+				}
+			}
+
+			return runQuery(cmd, opts)
+		},
+	}
+
+	addQueryFlags(cmd, opts)
+
+	return cmd
+}
+
+func newHistoryClearCmd(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Clear the history of queries",
+		Long: heredoc.Doc(`
+			Clear the history of queries of all deployments.
+		`),
+
+		Args: cobra.NoArgs,
+
+		RunE: func(*cobra.Command, []string) error {
+			if err := clearHistory(); err != nil {
+				return err
+			}
+
+			if f.IO.IsStderrTTY() {
+				cs := f.IO.ColorScheme()
+				fmt.Fprintf(f.IO.ErrOut(), "%s Cleared the history of queries\n", cs.SuccessIcon())
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
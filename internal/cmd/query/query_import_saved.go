@@ -0,0 +1,135 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmd/auth"
+	"github.com/axiomhq/cli/internal/cmdutil"
+)
+
+type importSavedOptions struct {
+	*cmdutil.Factory
+
+	// Path of the file to import from. If "-", the queries are read from
+	// stdin.
+	Path string
+	// Force replaces existing queries of the same name.
+	Force bool
+}
+
+func newImportSavedCmd(f *cmdutil.Factory) *cobra.Command {
+	opts := &importSavedOptions{
+		Factory: f,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "import-saved <filename> [--force]",
+		Short: "Import shared saved queries",
+		Long: heredoc.Doc(`
+			Import queries from a file written by "axiom query export-saved"
+			and save them for the active deployment.
+
+			The file lists the queries under the "queries" table, by name:
+
+				[queries.errors]
+				apl = "['http-logs'] | where status >= 500"
+				description = "Failed requests"
+				start_time = "-1h"
+
+			Queries with the name of an existing saved query are skipped,
+			unless "--force" is given.
+		`),
+
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return []string{"toml"}, cobra.ShellCompDirectiveFilterFileExt
+		},
+
+		Example: heredoc.Doc(`
+			# Import the queries in "queries.toml":
+			$ axiom query import-saved queries.toml
+
+			# Import the queries exported from the "eu" deployment into the
+			# "us" deployment, replacing existing ones:
+			$ axiom query export-saved -D eu | axiom query import-saved -D us --force -
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+		),
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			opts.Path = args[0]
+			return runImportSaved(opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Replace existing saved queries of the same name")
+
+	_ = cmd.RegisterFlagCompletionFunc("force", cmdutil.NoCompletion)
+
+	return cmd
+}
+
+func runImportSaved(opts *importSavedOptions) error {
+	var r io.Reader = opts.IO.In()
+	if opts.Path != "-" {
+		f, err := os.Open(opts.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	queries, err := readSharedQueries(r)
+	if err != nil {
+		return fmt.Errorf("read queries: %w", err)
+	}
+
+	sqs, err := loadSavedQueries()
+	if err != nil {
+		return err
+	}
+
+	var (
+		key      = deploymentKey(opts.Factory)
+		imported int
+		skipped  []string
+	)
+	for name, sq := range queries {
+		if _, exists := sqs.Get(key, name); exists && !opts.Force {
+			skipped = append(skipped, name)
+			continue
+		}
+		sqs.Set(key, name, sq)
+		imported++
+	}
+
+	if imported > 0 {
+		if err = sqs.Write(); err != nil {
+			return err
+		}
+	}
+
+	cs := opts.IO.ColorScheme()
+	if len(skipped) > 0 {
+		slices.Sort(skipped)
+		fmt.Fprintf(opts.IO.ErrOut(), "%s Skipped existing saved queries (use --force to replace them): %s\n",
+			cs.WarningIcon(), strings.Join(skipped, ", "))
+	}
+	if opts.IO.IsStderrTTY() {
+		fmt.Fprintf(opts.IO.ErrOut(), "%s Imported %s of %d queries\n",
+			cs.SuccessIcon(), cs.Bold(fmt.Sprint(imported)), len(queries))
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmd/auth"
+	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/pkg/iofmt"
+)
+
+type listSavedOptions struct {
+	*cmdutil.Factory
+
+	// Format to output data in. Defaults to tabular output.
+	Format string
+	// Transform shapes the output using a template or jq expression.
+	Transform iofmt.Transform
+}
+
+// savedQueryRecord is a saved query, as it is output.
+type savedQueryRecord struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	APL         string `json:"apl"`
+	StartTime   string `json:"start_time"`
+	EndTime     string `json:"end_time"`
+	Format      string `json:"format"`
+}
+
+// savedQueryFields are the fields of a saved query in tabular output formats.
+var savedQueryFields = []string{"name", "description", "apl", "start_time", "end_time", "format"}
+
+func newListSavedCmd(f *cmdutil.Factory) *cobra.Command {
+	opts := &listSavedOptions{
+		Factory: f,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "list-saved [(-f|--format) <format> | (-t|--template) <template> | (-q|--jq) <expression>]",
+		Short: "List the saved queries",
+
+		DisableFlagsInUseLine: true,
+
+		Args: cobra.NoArgs,
+
+		Example: heredoc.Doc(`
+			# List the saved queries of the active deployment:
+			$ axiom query list-saved
+
+			# List the saved queries of the "eu" deployment as JSON:
+			$ axiom query list-saved -D eu -f json
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+		),
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runListSaved(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Format, "format", "f", iofmt.Table.String(), "Format to output data in")
+
+	_ = cmd.RegisterFlagCompletionFunc("format", cmdutil.FormatCompletion)
+
+	cmdutil.AddTransformFlags(cmd, &opts.Transform)
+
+	return cmd
+}
+
+func runListSaved(ctx context.Context, opts *listSavedOptions) error {
+	format, err := cmdutil.ParseFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	if err = cmdutil.CompileTransform(opts.IO, &opts.Transform); err != nil {
+		return err
+	}
+
+	sqs, err := loadSavedQueries()
+	if err != nil {
+		return err
+	}
+
+	key := deploymentKey(opts.Factory)
+	names := sqs.Names(key)
+
+	records := make([]savedQueryRecord, len(names))
+	for i, name := range names {
+		sq, _ := sqs.Get(key, name)
+		records[i] = savedQueryRecord{
+			Name:        name,
+			Description: sq.Description,
+			APL:         sq.APL,
+			StartTime:   sq.StartTime,
+			EndTime:     sq.EndTime,
+			Format:      sq.Format,
+		}
+	}
+
+	pagerStop, err := opts.IO.StartPager(ctx)
+	if err != nil {
+		return err
+	}
+	defer pagerStop()
+
+	if opts.Transform.IsSet() {
+		return opts.Transform.Write(opts.IO.Out(), records, opts.IO.ColorEnabled())
+	} else if format != iofmt.Table {
+		return iofmt.FormatRecords(opts.IO.Out(), format, savedQueryFields, records, opts.IO.ColorEnabled())
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(opts.IO.Out(), "No saved queries found.")
+		return nil
+	}
+
+	cs := opts.IO.ColorScheme()
+
+	var header iofmt.HeaderBuilderFunc
+	if opts.IO.IsStdoutTTY() {
+		header = func(_ io.Writer, trb iofmt.TableRowBuilder) {
+			noun := "queries"
+			if len(records) == 1 {
+				noun = "query"
+			}
+			fmt.Fprintf(opts.IO.Out(), "Showing %s saved %s:\n\n", cs.Bold(strconv.Itoa(len(records))), noun)
+			trb.AddField("Name", cs.Bold)
+			trb.AddField("Description", cs.Bold)
+			trb.AddField("Time range", cs.Bold)
+			trb.AddField("Query", cs.Bold)
+		}
+	}
+
+	contentRow := func(trb iofmt.TableRowBuilder, k int) {
+		record := records[k]
+		sq, _ := sqs.Get(key, record.Name)
+
+		trb.AddField(record.Name, nil)
+		trb.AddField(record.Description, nil)
+		trb.AddField(sq.timeRange(), cs.Gray)
+		// Multi-line queries would break the table.
+		trb.AddField(strings.Join(strings.Fields(record.APL), " "), nil)
+	}
+
+	return iofmt.FormatToTable(opts.IO, len(records), header, nil, contentRow)
+}
@@ -0,0 +1,89 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmd/auth"
+	"github.com/axiomhq/cli/internal/cmdutil"
+)
+
+func newRunCmd(f *cmdutil.Factory) *cobra.Command {
+	opts := &options{
+		Factory: f,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "run <name> [(-f|--format) <format> | (-t|--template) <template> | (-q|--jq) <expression>] [--start-time <start-time>] [--end-time <end-time>] [--timestamp-format <timestamp-format>] [--param <name>=<value>...] [--all] [(-o|--output) <filename>] [(-w|--watch) <interval>] [--split <window> [--concurrency <n>]] [--compare <offset>] [--stats]",
+		Short: "Run a saved query",
+		Long: heredoc.Doc(`
+			Run a query saved using "axiom query save".
+
+			The query is run with the start time, end time and format it was
+			saved with, unless they are given as flags. Placeholders in the
+			query are given values using "--param", just like with
+			"axiom query". All other flags behave like they do for
+			"axiom query".
+		`),
+
+		DisableFlagsInUseLine: true,
+
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: savedQueryCompletionFunc(f),
+
+		Example: heredoc.Doc(`
+			# Run the saved query "errors":
+			$ axiom query run errors
+
+			# Run the saved query "errors-by-service" for the last day:
+			$ axiom query run errors-by-service --param service=checkout --start-time -1d
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+			cmdutil.NeedsDatasets(f),
+		),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			sqs, err := loadSavedQueries()
+			if err != nil {
+				return err
+			}
+
+			sq, ok := sqs.Get(deploymentKey(f), name)
+			if !ok {
+				return fmt.Errorf("no saved query %q", name)
+			}
+			opts.Query = sq.APL
+
+			// Flags given explicitly take precedence over the defaults of the
+			// saved query. Setting the flags instead of the options makes the
+			// defaults behave exactly like the flags.
+			defaults := map[string]string{
+				"start-time": sq.StartTime,
+				"end-time":   sq.EndTime,
+			}
+			if !opts.Transform.IsSet() {
+				defaults["format"] = sq.Format
+			}
+			for flag, value := range defaults {
+				if value == "" || cmd.Flags().Changed(flag) {
+					continue
+				} else if err = cmd.Flags().Set(flag, value); err != nil {
+					return err
+				}
+			}
+
+			return runQuery(cmd, opts)
+		},
+	}
+
+	addQueryFlags(cmd, opts)
+
+	return cmd
+}
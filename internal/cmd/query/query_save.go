@@ -0,0 +1,156 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmd/auth"
+	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/pkg/iofmt"
+)
+
+type saveOptions struct {
+	*cmdutil.Factory
+
+	// Name to save the query under.
+	Name string
+	// Query to save. If not supplied as an argument, which is optional, the
+	// user will be asked for it.
+	Query string
+	// File to read the query from. If "-", the query is read from stdin.
+	File string
+	// Description of the query.
+	Description string
+	// StartTime the query is run with by default.
+	StartTime string
+	// EndTime the query is run with by default.
+	EndTime string
+	// Format the result of the query is output in by default.
+	Format string
+}
+
+func newSaveCmd(f *cmdutil.Factory) *cobra.Command {
+	opts := &saveOptions{
+		Factory: f,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "save <name> [<apl-query>] [(-F|--file) <filename>] [(-d|--description) <description>] [--start-time <start-time>] [--end-time <end-time>] [(-f|--format) <format>]",
+		Short: "Save a query under a name",
+		Long: heredoc.Doc(`
+			Save a query under a name, to run it later using "axiom query run".
+
+			Queries are saved per deployment, in the data directory of the CLI.
+			An existing query of the same name is replaced. The query may
+			contain placeholders, which are given values when it is run.
+
+			The start time, end time and format are the defaults the query is
+			run with. Relative times are kept as they are and resolved every
+			time the query is run.
+		`),
+
+		DisableFlagsInUseLine: true,
+
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmdutil.NewFlagErrorf("a name is required")
+			} else if cmd.Flags().Changed("file") && len(args) > 1 {
+				return cmdutil.NewFlagErrorf("a query can't be given together with --file")
+			} else if cmd.Flags().Changed("file") {
+				return cmdutil.PopulateFromArgs(f, &opts.Name)(cmd, args)
+			}
+			return cmdutil.PopulateFromArgs(f, &opts.Name, &opts.Query)(cmd, args)
+		},
+
+		Example: heredoc.Doc(`
+			# Save a query that counts the errors of the last hour by service:
+			$ axiom query save errors "['http-logs'] | where status >= 500 | summarize count() by service" --start-time -1h
+
+			# Save the query in "errors.apl", which contains placeholders:
+			$ axiom query save errors-by-service -F errors.apl -d "Errors of a service"
+		`),
+
+		PreRunE: cmdutil.ChainRunFuncs(
+			cmdutil.AsksForSetup(f, auth.NewLoginCmd(f)),
+			cmdutil.NeedsActiveDeployment(f),
+		),
+
+		RunE: func(*cobra.Command, []string) error {
+			if err := completeSave(opts); err != nil {
+				return err
+			}
+			return runSave(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.File, "file", "F", "", "File to read the query from (- for stdin)")
+	cmd.Flags().StringVarP(&opts.Description, "description", "d", "", "Description of the query")
+	cmd.Flags().StringVar(&opts.StartTime, "start-time", "", "Default start time of the query - may also be a relative time eg: -2w, -7d, -24h, -20m")
+	cmd.Flags().StringVar(&opts.EndTime, "end-time", "", "Default end time of the query - may also be a relative time eg: -2w, -7d, -24h, -20m")
+	cmd.Flags().StringVarP(&opts.Format, "format", "f", "", "Default format to output data in")
+
+	_ = cmd.RegisterFlagCompletionFunc("file", aplFileCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("description", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("start-time", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("end-time", cmdutil.NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("format", cmdutil.FormatCompletionFunc(iofmt.Chart))
+
+	return cmd
+}
+
+func completeSave(opts *saveOptions) (err error) {
+	if !savedQueryNameRe.MatchString(opts.Name) {
+		return cmdutil.NewFlagErrorf("invalid name %q: must start with a letter or digit and only contain letters, digits, \"_\", \".\" and \"-\"", opts.Name)
+	}
+
+	if opts.File != "" {
+		opts.Query, err = readQueryFile(opts.File, opts.IO.In())
+		return err
+	} else if opts.Query != "" {
+		return nil
+	}
+
+	return survey.AskOne(&survey.Input{
+		Message: "Which query to save?",
+	}, &opts.Query, opts.IO.SurveyIO(), survey.WithValidator(survey.Required))
+}
+
+func runSave(opts *saveOptions) error {
+	sq := savedQuery{
+		APL:         opts.Query,
+		Description: opts.Description,
+		StartTime:   opts.StartTime,
+		EndTime:     opts.EndTime,
+		Format:      opts.Format,
+	}
+	if err := sq.validate(); err != nil {
+		return cmdutil.NewFlagError(err)
+	}
+
+	sqs, err := loadSavedQueries()
+	if err != nil {
+		return err
+	}
+
+	key := deploymentKey(opts.Factory)
+	_, exists := sqs.Get(key, opts.Name)
+	sqs.Set(key, opts.Name, sq)
+
+	if err = sqs.Write(); err != nil {
+		return err
+	}
+
+	if opts.IO.IsStderrTTY() {
+		cs := opts.IO.ColorScheme()
+		if exists {
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Updated saved query %s\n", cs.SuccessIcon(), cs.Bold(opts.Name))
+		} else {
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Saved query %s\n", cs.SuccessIcon(), cs.Bold(opts.Name))
+		}
+	}
+
+	return nil
+}
@@ -136,6 +136,66 @@ func TestTableHasAggregation(t *testing.T) {
 	}
 }
 
+func TestResultTables(t *testing.T) {
+	count := &query.Aggregation{Op: query.OpCount}
+
+	res := &query.Result{
+		Status: query.Status{RowsMatched: 3},
+		Tables: []query.Table{
+			{
+				Name:    "0",
+				Fields:  []query.Field{{Name: "service"}, {Name: "count_", Aggregation: count}},
+				Columns: []query.Column{{"api", "web"}, {2, 1}},
+			},
+			{
+				Name:    "_totals",
+				Fields:  []query.Field{{Name: "count_", Aggregation: count}},
+				Columns: []query.Column{{3}},
+			},
+			{
+				Fields:  []query.Field{{Name: "msg"}},
+				Columns: []query.Column{{"a"}},
+			},
+		},
+	}
+
+	tables := resultTables(res)
+	if len(tables) != 2 {
+		t.Fatalf("got %d tables, want 2", len(tables))
+	}
+	if tables[0].name != "0" || tables[0].totals == nil || tables[0].totals.Columns[0][0] != 3 {
+		t.Errorf("first table = %+v, want table 0 with totals", tables[0])
+	}
+	// Unnamed tables are named by their position.
+	if tables[1].name != "1" || tables[1].totals != nil {
+		t.Errorf("second table = %+v, want table 1 without totals", tables[1])
+	}
+
+	records, ok := resultRecords(tables).(map[string][]map[string]any)
+	if !ok {
+		t.Fatalf("records of multiple tables = %T, want rows by table name", resultRecords(tables))
+	}
+	if len(records["0"]) != 2 || records["1"][0]["msg"] != "a" {
+		t.Errorf("records = %v, want the rows of every table", records)
+	}
+	if _, ok = resultRecords(tables[:1]).([]map[string]any); !ok {
+		t.Errorf("records of single table = %T, want rows", resultRecords(tables[:1]))
+	}
+
+	if resultIsEmpty(res) {
+		t.Error("result with rows is empty")
+	}
+	// A result is only empty if none of its tables has rows.
+	res.Tables[0].Columns = []query.Column{{}, {}}
+	if resultIsEmpty(res) {
+		t.Error("result with rows in second table is empty")
+	}
+	res.Tables = res.Tables[:2]
+	if !resultIsEmpty(res) {
+		t.Error("result without rows is not empty")
+	}
+}
+
 func TestParseDurationErrors(t *testing.T) {
 	tests := []string{
 		"",
@@ -0,0 +1,554 @@
+package query
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"golang.org/x/term"
+
+	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/internal/config"
+	"github.com/axiomhq/cli/pkg/iofmt"
+)
+
+const (
+	// replHistoryFile is the name of the file the input history of the
+	// interactive session is kept in, in the data directory of the CLI.
+	replHistoryFile = "repl_history"
+	// replHistorySize is the number of queries kept in the input history.
+	replHistorySize = 500
+
+	replPrompt             = "apl> "
+	replContinuationPrompt = "...> "
+)
+
+var replHelp = heredoc.Doc(`
+	Enter an APL query and press enter to run it. A query continues on the next
+	line if a line ends with "|" or "\", or if brackets or quotes are not
+	closed. Press tab to complete dataset and field names. Press Ctrl-D to
+	exit.
+
+	Commands:
+	  .since <duration>      Query the given duration up to now, eg: .since 2h
+	  .range <start> [<end>] Query the given time range, eg: .range -2d -1d
+	  .format <format>       Output results in the given format
+	  .help                  Show this help
+	  .exit                  Exit the session
+`)
+
+var replCommands = []string{".since", ".range", ".format", ".help", ".exit"}
+
+// repl is an interactive session which runs APL queries read from the
+// terminal.
+type repl struct {
+	// opts are the options the queries are run with. The time range and
+	// format are changed by the commands of the session.
+	opts *options
+
+	term    *term.Terminal
+	history *replHistory
+	// lines are the lines read so far of the query being entered.
+	lines []string
+
+	// datasets and fields are fetched on first use and cached for the rest of
+	// the session.
+	datasets []string
+	fields   map[string][]string
+}
+
+// runInteractive runs queries read from the terminal until the user exits.
+func runInteractive(ctx context.Context, opts *options) error {
+	in, ok := opts.IO.In().(*os.File)
+	if !ok || !opts.IO.IsStdinTTY() || !opts.IO.IsStdoutTTY() {
+		return cmdutil.NewFlagErrorf("--interactive requires a terminal")
+	}
+
+	history, err := loadReplHistory()
+	if err != nil {
+		return err
+	}
+
+	r := &repl{
+		opts:    opts,
+		term:    term.NewTerminal(readWriter{in, opts.IO.Out()}, replPrompt),
+		history: history,
+		fields:  make(map[string][]string),
+	}
+	r.term.History = history
+	r.term.AutoCompleteCallback = r.complete
+
+	// Fail early on an invalid format or transform, instead of on every query.
+	if _, err = cmdutil.ParseFormat(opts.Format, iofmt.Chart); err != nil {
+		return err
+	} else if err = cmdutil.CompileTransform(opts.IO, &opts.Transform); err != nil {
+		return err
+	}
+
+	// The context of the command is canceled on the first interrupt. Queries
+	// are canceled by an interrupt instead, but the session goes on.
+	ctx = context.WithoutCancel(ctx)
+
+	cs := opts.IO.ColorScheme()
+	fmt.Fprintf(opts.IO.ErrOut(), "Interactive APL session, querying %s. Type %s for help.\n",
+		cs.Bold(r.timeRange()), cs.Bold(".help"))
+
+	for {
+		q, err := r.readQuery(int(in.Fd()))
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		} else if q == "" {
+			continue
+		}
+
+		if strings.HasPrefix(q, ".") {
+			if exit, err := r.command(q); err != nil {
+				fmt.Fprintf(opts.IO.ErrOut(), "%s %v\n", cs.ErrorIcon(), err)
+			} else if exit {
+				return nil
+			}
+			continue
+		}
+
+		queryCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err = r.run(queryCtx, q)
+		stop()
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Query canceled\n", cs.WarningIcon())
+		} else if err != nil {
+			fmt.Fprintf(opts.IO.ErrOut(), "%s %v\n", cs.ErrorIcon(), err)
+		}
+	}
+}
+
+// readWriter combines the terminal input and output.
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
+
+// readQuery reads a query, which might span multiple lines, from the terminal.
+// The terminal is only put into raw mode while reading.
+func (r *repl) readQuery(fd int) (string, error) {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+
+	if w, h, err := term.GetSize(fd); err == nil && w > 0 {
+		_ = r.term.SetSize(w, h)
+	}
+	r.term.SetBracketedPasteMode(true)
+	defer r.term.SetBracketedPasteMode(false)
+
+	r.lines = r.lines[:0]
+	r.term.SetPrompt(replPrompt)
+	for {
+		line, err := r.term.ReadLine()
+		pasted := errors.Is(err, term.ErrPasteIndicator)
+		if err != nil && !pasted {
+			return "", err
+		}
+		// A trailing backslash continues the query on the next line.
+		trimmed := strings.TrimRightFunc(line, unicode.IsSpace)
+		continued := strings.HasSuffix(trimmed, `\`)
+		if continued {
+			line = strings.TrimSuffix(trimmed, `\`)
+		}
+		r.lines = append(r.lines, line)
+
+		// Pasted lines are read until enter is pressed.
+		q := strings.Join(r.lines, "\n")
+		if pasted || continued || needsMoreInput(q) {
+			r.term.SetPrompt(replContinuationPrompt)
+			continue
+		}
+
+		q = strings.TrimSpace(q)
+		if q != "" {
+			r.history.Append(historyLine(r.lines))
+		}
+		return q, nil
+	}
+}
+
+// needsMoreInput returns true if the query is incomplete: if it ends with a
+// pipe or if brackets or quotes are not closed.
+func needsMoreInput(q string) bool {
+	if strings.HasSuffix(strings.TrimSpace(q), "|") {
+		return true
+	}
+
+	var (
+		depth int
+		quote rune
+	)
+	for i := 0; i < len(q); i++ {
+		c := rune(q[i])
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth > 0 || quote != 0
+}
+
+// historyLine joins the lines of a query into a single line for the input
+// history, so it can be recalled and edited as a whole.
+func historyLine(lines []string) string {
+	fields := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			fields = append(fields, line)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// command runs a command of the session. It returns true if the session
+// should end.
+func (r *repl) command(line string) (bool, error) {
+	args := strings.Fields(line)
+	switch args[0] {
+	case ".exit", ".quit":
+		return true, nil
+	case ".help":
+		fmt.Fprint(r.opts.IO.ErrOut(), replHelp)
+	case ".since":
+		if len(args) != 2 {
+			return false, errors.New("usage: .since <duration>")
+		}
+		start := "-" + strings.TrimLeft(args[1], "+-")
+		if _, err := parseDuration(start); err != nil {
+			return false, fmt.Errorf("invalid duration %q", args[1])
+		}
+		r.opts.StartTime, r.opts.EndTime = start, ""
+		r.printTimeRange()
+	case ".range":
+		if len(args) < 2 || len(args) > 3 {
+			return false, errors.New("usage: .range <start> [<end>]")
+		}
+		var end string
+		if len(args) == 3 {
+			end = args[2]
+		}
+		for _, ts := range []string{args[1], end} {
+			if _, err := timeStrToTime(ts, r.opts.TimestampFormat); err != nil && ts != "" {
+				return false, fmt.Errorf("invalid time %q", ts)
+			}
+		}
+		r.opts.StartTime, r.opts.EndTime = args[1], end
+		r.printTimeRange()
+	case ".format":
+		if len(args) != 2 {
+			return false, errors.New("usage: .format <format>")
+		}
+		if _, err := cmdutil.ParseFormat(args[1], iofmt.Chart); err != nil {
+			return false, err
+		}
+		r.opts.Format = args[1]
+	default:
+		return false, fmt.Errorf("unknown command %s, type .help for help", args[0])
+	}
+	return false, nil
+}
+
+func (r *repl) timeRange() string {
+	switch {
+	case r.opts.StartTime == "" && r.opts.EndTime == "":
+		return "the default time range"
+	case r.opts.EndTime == "":
+		return r.opts.StartTime + " .. now"
+	case r.opts.StartTime == "":
+		return ".. " + r.opts.EndTime
+	}
+	return r.opts.StartTime + " .. " + r.opts.EndTime
+}
+
+func (r *repl) printTimeRange() {
+	fmt.Fprintf(r.opts.IO.ErrOut(), "Querying %s\n", r.opts.IO.ColorScheme().Bold(r.timeRange()))
+}
+
+// run runs the query with the current options of the session. Relative times
+// are resolved every time a query is run.
+func (r *repl) run(ctx context.Context, q string) error {
+	opts := *r.opts
+	opts.Query = q
+	opts.history = historyEntry{}
+
+	if err := complete(&opts); err != nil {
+		return err
+	}
+	return recordHistory(&opts, run(ctx, &opts))
+}
+
+// complete is the auto completion callback of the terminal. On tab, it
+// completes the word before the cursor: a command at the start of the input,
+// a dataset name within "['" and "']" and a field name of the datasets of the
+// query elsewhere.
+func (r *repl) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	start := pos
+	for start > 0 && isWordChar(line[start-1]) {
+		start--
+	}
+	word := line[start:pos]
+
+	var candidates []string
+	switch {
+	case len(r.lines) == 0 && start == 0 && strings.HasPrefix(word, "."):
+		candidates = replCommands
+	case strings.HasSuffix(line[:start], "['") || strings.HasSuffix(line[:start], `["`):
+		candidates = r.datasetNames()
+	default:
+		candidates = r.fieldNames(strings.Join(append(slices.Clone(r.lines), line), "\n"))
+	}
+
+	matches := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+
+	completion := commonPrefix(matches)
+	if len(matches) > 1 && completion == word {
+		// Nothing to complete, so show the possible completions instead.
+		_, _ = fmt.Fprintln(r.term, strings.Join(matches, "  "))
+		return "", 0, false
+	} else if len(matches) == 0 {
+		return "", 0, false
+	}
+	return line[:start] + completion + line[pos:], start + len(completion), true
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '-' || c == '.' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func commonPrefix(ss []string) string {
+	if len(ss) == 0 {
+		return ""
+	}
+	prefix := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// datasetNames returns the names of all datasets.
+func (r *repl) datasetNames() []string {
+	if r.datasets != nil {
+		return r.datasets
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := r.opts.Client(ctx)
+	if err != nil {
+		return nil
+	}
+	datasets, err := client.Datasets.List(ctx)
+	if err != nil {
+		return nil
+	}
+
+	r.datasets = make([]string, len(datasets))
+	for i, dataset := range datasets {
+		r.datasets[i] = dataset.Name
+	}
+	slices.Sort(r.datasets)
+	return r.datasets
+}
+
+// fieldNames returns the names of the fields of the datasets the query uses.
+func (r *repl) fieldNames(q string) []string {
+	var names []string
+	for _, dataset := range queryDatasets(q) {
+		fields, ok := r.fields[dataset]
+		if !ok {
+			fields = r.fetchFieldNames(dataset)
+			r.fields[dataset] = fields
+		}
+		names = append(names, fields...)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// fetchFieldNames fetches the names of the fields of the dataset using the
+// "getschema" operator.
+func (r *repl) fetchFieldNames(dataset string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := r.opts.Client(ctx)
+	if err != nil {
+		return nil
+	}
+
+	apl := fmt.Sprintf("['%s'] | getschema", dataset)
+	res, err := client.Query(ctx, apl, query.SetStartTime(time.Now().Add(-24*time.Hour)))
+	if err != nil || len(res.Tables) == 0 {
+		return nil
+	}
+
+	return schemaFieldNames(res.Tables[0])
+}
+
+// schemaFieldNames returns the field names held by the result table of the
+// "getschema" operator. A table without rows, like the one of a dataset without
+// data in the queried range, may have no columns at all.
+func schemaFieldNames(table query.Table) []string {
+	idx := slices.IndexFunc(table.Fields, func(field query.Field) bool {
+		return field.Name == "ColumnName"
+	})
+	if idx < 0 || idx >= len(table.Columns) {
+		return nil
+	}
+
+	names := make([]string, 0, len(table.Columns[idx]))
+	for _, name := range table.Columns[idx] {
+		if s, ok := name.(string); ok {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+// queryDatasets returns the names of the datasets referenced in the query as
+// "['name']" or "[\"name\"]".
+func queryDatasets(q string) []string {
+	var datasets []string
+	for _, quote := range []string{"'", `"`} {
+		rest := q
+		for {
+			i := strings.Index(rest, "["+quote)
+			if i < 0 {
+				break
+			}
+			rest = rest[i+2:]
+			j := strings.Index(rest, quote+"]")
+			if j < 0 {
+				break
+			}
+			if name := rest[:j]; !slices.Contains(datasets, name) {
+				datasets = append(datasets, name)
+			}
+			rest = rest[j+2:]
+		}
+	}
+	return datasets
+}
+
+// replHistory is the input history of the interactive session. Queries are
+// added to it as a whole, not line by line, and persisted in the data
+// directory.
+type replHistory struct {
+	entries []string
+	path    string
+}
+
+func loadReplHistory() (*replHistory, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &replHistory{
+		path: filepath.Join(dir, replHistoryFile),
+	}
+
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if err = sc.Err(); err != nil {
+		return nil, err
+	}
+
+	// Only the most recent queries are kept. The file is trimmed once it holds
+	// twice as many, so it isn't rewritten every time.
+	if len(h.entries) > replHistorySize {
+		trim := len(h.entries) > 2*replHistorySize
+		h.entries = h.entries[len(h.entries)-replHistorySize:]
+		if trim {
+			_ = os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600)
+		}
+	}
+	return h, nil
+}
+
+// Add implements term.History. It ignores the lines read by the terminal, as
+// queries are added by Append once complete.
+func (*replHistory) Add(string) {}
+
+// Len implements term.History.
+func (h *replHistory) Len() int {
+	return len(h.entries)
+}
+
+// At implements term.History.
+func (h *replHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+// Append adds the query to the history, unless it repeats the most recent
+// one. Failing to persist the history is not an error.
+func (h *replHistory) Append(q string) {
+	if q == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == q) {
+		return
+	}
+	h.entries = append(h.entries, q)
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = fmt.Fprintln(f, q)
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"golang.org/x/term"
+)
+
+func TestNeedsMoreInput(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"['logs']", false},
+		{"['logs'] |", true},
+		{"['logs']\n| where a == 1", false},
+		{"['logs'] | where a in (1, 2", true},
+		{"['logs'] | where a == \"b", true},
+		{"['logs'] | where a == \"b\\\"", true},
+		{"['logs'] | where a == \"(\"", false},
+		{"['logs'] | where a == 'x'", false},
+		{"['lo", true},
+	}
+
+	for _, tt := range tests {
+		if got := needsMoreInput(tt.query); got != tt.want {
+			t.Errorf("needsMoreInput(%q) = %t, want %t", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHistoryLine(t *testing.T) {
+	got := historyLine([]string{"['logs']", "  | where a == 1", "", "| count"})
+	if want := "['logs'] | where a == 1 | count"; got != want {
+		t.Errorf("historyLine() = %q, want %q", got, want)
+	}
+}
+
+func TestQueryDatasets(t *testing.T) {
+	got := queryDatasets(`['logs'] | union ["traces"], ['logs']`)
+	if want := []string{"logs", "traces"}; !slices.Equal(got, want) {
+		t.Errorf("queryDatasets() = %q, want %q", got, want)
+	}
+}
+
+func TestSchemaFieldNames(t *testing.T) {
+	fields := []query.Field{{Name: "ColumnName"}, {Name: "ColumnType"}}
+
+	table := query.Table{
+		Fields:  fields,
+		Columns: []query.Column{{"_time", "status"}, {"datetime", "int"}},
+	}
+	if got := schemaFieldNames(table); !slices.Equal(got, []string{"_time", "status"}) {
+		t.Errorf("schemaFieldNames() = %v, want [_time status]", got)
+	}
+
+	// Without data in the queried range, the table has no columns.
+	if got := schemaFieldNames(query.Table{Fields: fields}); got != nil {
+		t.Errorf("schemaFieldNames() of a table without columns = %v, want nil", got)
+	}
+}
+
+func TestReplComplete(t *testing.T) {
+	var out bytes.Buffer
+	r := &repl{
+		term:     term.NewTerminal(readWriter{&bytes.Buffer{}, &out}, replPrompt),
+		datasets: []string{"http-logs", "http-traces", "metrics"},
+		fields: map[string][]string{
+			"http-logs": {"_time", "status", "service.name"},
+			"metrics":   {"_time", "value"},
+		},
+	}
+
+	tests := []struct {
+		line    string
+		pos     int
+		want    string
+		wantPos int
+		ok      bool
+	}{
+		{"['me", 4, "['metrics", 9, true},
+		{"['ht", 4, "['http-", 7, true},
+		{"['http-logs'] | where st", 24, "['http-logs'] | where status", 28, true},
+		{"['http-logs'] | where serv == 1", 26, "['http-logs'] | where service.name == 1", 34, true},
+		{"['metrics'] | where st", 22, "", 0, false},
+		{".si", 3, ".since", 6, true},
+	}
+
+	for _, tt := range tests {
+		line, pos, ok := r.complete(tt.line, tt.pos, '\t')
+		if ok != tt.ok || (ok && (line != tt.want || pos != tt.wantPos)) {
+			t.Errorf("complete(%q, %d) = %q, %d, %t, want %q, %d, %t", tt.line, tt.pos, line, pos, ok, tt.want, tt.wantPos, tt.ok)
+		}
+	}
+
+	// Nothing more to complete, so the candidates are shown.
+	if _, _, ok := r.complete("['http-", 7, '\t'); ok {
+		t.Error("complete() of ambiguous word = true, want false")
+	} else if !bytes.Contains(out.Bytes(), []byte("http-logs  http-traces")) {
+		t.Errorf("candidates not shown, got %q", out.String())
+	}
+
+	if _, _, ok := r.complete("['me", 4, 'x'); ok {
+		t.Error("complete() on key other than tab = true, want false")
+	}
+}
+
+func TestReplHistory(t *testing.T) {
+	t.Setenv("AXIOM_DATA_DIR", t.TempDir())
+
+	h, err := loadReplHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Append("['logs']")
+	h.Append("['logs'] | count")
+	h.Append("['logs'] | count")
+	h.Add("ignored")
+
+	if h, err = loadReplHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if h.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", h.Len())
+	}
+	if got := h.At(0); got != "['logs'] | count" {
+		t.Errorf("At(0) = %q, want most recent query", got)
+	}
+	if got := h.At(1); got != "['logs']" {
+		t.Errorf("At(1) = %q, want least recent query", got)
+	}
+}
@@ -0,0 +1,214 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/pelletier/go-toml"
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/internal/config"
+	"github.com/axiomhq/cli/pkg/iofmt"
+)
+
+// savedQueriesFile is the name of the file saved queries are kept in, in the
+// data directory of the CLI.
+const savedQueriesFile = "queries.toml"
+
+var savedQueryNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// savedQuery is a named query, along with the defaults it is run with.
+type savedQuery struct {
+	APL         string `toml:"apl" multiline:"true"`
+	Description string `toml:"description,omitempty"`
+	StartTime   string `toml:"start_time,omitempty"`
+	EndTime     string `toml:"end_time,omitempty"`
+	Format      string `toml:"format,omitempty"`
+}
+
+// validate the query and its defaults.
+func (sq savedQuery) validate() error {
+	if sq.APL == "" {
+		return errors.New("empty query")
+	}
+	if _, err := timeStrToTime(sq.StartTime, ""); err != nil && sq.StartTime != "" {
+		return fmt.Errorf("invalid start time %q", sq.StartTime)
+	} else if _, err = timeStrToTime(sq.EndTime, ""); err != nil && sq.EndTime != "" {
+		return fmt.Errorf("invalid end time %q", sq.EndTime)
+	}
+	if sq.Format != "" {
+		if _, err := cmdutil.ParseFormat(sq.Format, iofmt.Chart); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timeRange returns a description of the default time range of the query.
+func (sq savedQuery) timeRange() string {
+	switch {
+	case sq.StartTime == "" && sq.EndTime == "":
+		return "-"
+	case sq.EndTime == "":
+		return sq.StartTime + " .. now"
+	case sq.StartTime == "":
+		return ".. " + sq.EndTime
+	}
+	return sq.StartTime + " .. " + sq.EndTime
+}
+
+// savedQueries are the saved queries of all deployments, by deployment and
+// name.
+type savedQueries struct {
+	Deployments map[string]map[string]savedQuery `toml:"deployments"`
+
+	path string
+}
+
+// queryFile is the format of a file saved queries are shared with. It holds
+// queries by name, without reference to a deployment.
+type queryFile struct {
+	Queries map[string]savedQuery `toml:"queries"`
+}
+
+// loadSavedQueries loads the saved queries from the data directory. If no
+// queries were saved yet, none are returned.
+func loadSavedQueries() (*savedQueries, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	sqs := &savedQueries{
+		Deployments: make(map[string]map[string]savedQuery),
+
+		path: filepath.Join(dir, savedQueriesFile),
+	}
+
+	b, err := os.ReadFile(sqs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sqs, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = toml.Unmarshal(b, sqs); err != nil {
+		return nil, fmt.Errorf("%s: %w", sqs.path, err)
+	} else if sqs.Deployments == nil {
+		sqs.Deployments = make(map[string]map[string]savedQuery)
+	}
+	return sqs, nil
+}
+
+// Get returns the saved query of the deployment with the given name.
+func (sqs *savedQueries) Get(deployment, name string) (savedQuery, bool) {
+	sq, ok := sqs.Deployments[deployment][name]
+	return sq, ok
+}
+
+// Names returns the sorted names of the saved queries of the deployment.
+func (sqs *savedQueries) Names(deployment string) []string {
+	return slices.Sorted(maps.Keys(sqs.Deployments[deployment]))
+}
+
+// Set the saved query of the deployment with the given name.
+func (sqs *savedQueries) Set(deployment, name string, sq savedQuery) {
+	if sqs.Deployments[deployment] == nil {
+		sqs.Deployments[deployment] = make(map[string]savedQuery)
+	}
+	sqs.Deployments[deployment][name] = sq
+}
+
+// Delete the saved query of the deployment with the given name. It returns
+// false if there is no such query.
+func (sqs *savedQueries) Delete(deployment, name string) bool {
+	if _, ok := sqs.Deployments[deployment][name]; !ok {
+		return false
+	}
+	delete(sqs.Deployments[deployment], name)
+	if len(sqs.Deployments[deployment]) == 0 {
+		delete(sqs.Deployments, deployment)
+	}
+	return true
+}
+
+// Write the saved queries to disk. The file is replaced atomically.
+func (sqs *savedQueries) Write() error {
+	b, err := toml.Marshal(sqs)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(sqs.path), 0o700); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(sqs.path), savedQueriesFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(b); err != nil {
+		_ = f.Close()
+		return err
+	} else if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), sqs.path)
+}
+
+// readSharedQueries reads queries shared in a file.
+func readSharedQueries(r io.Reader) (map[string]savedQuery, error) {
+	var qf queryFile
+	if err := toml.NewDecoder(r).Decode(&qf); err != nil {
+		return nil, err
+	}
+	for name, sq := range qf.Queries {
+		if !savedQueryNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid query name %q", name)
+		} else if err := sq.validate(); err != nil {
+			return nil, fmt.Errorf("query %q: %w", name, err)
+		}
+	}
+	return qf.Queries, nil
+}
+
+// writeSharedQueries writes queries to share to a file.
+func writeSharedQueries(w io.Writer, queries map[string]savedQuery) error {
+	return toml.NewEncoder(w).Encode(queryFile{Queries: queries})
+}
+
+// deploymentKey returns the key the saved queries and the history of the
+// active deployment are kept by: its alias or, if configured by the environment
+// only, its URL.
+func deploymentKey(f *cmdutil.Factory) string {
+	if f.Config.ActiveDeployment != "" {
+		return f.Config.ActiveDeployment
+	}
+	dep, _ := f.Config.GetActiveDeployment()
+	return dep.URL
+}
+
+// savedQueryCompletionFunc returns a completion function which completes the
+// names of the saved queries of the active deployment.
+func savedQueryCompletionFunc(f *cmdutil.Factory) cmdutil.CompletionFunc {
+	return func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		sqs, err := loadSavedQueries()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return sqs.Names(deploymentKey(f)), cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSavedQueries(t *testing.T) {
+	t.Setenv("AXIOM_DATA_DIR", t.TempDir())
+
+	sqs, err := loadSavedQueries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := sqs.Names("prod"); len(names) != 0 {
+		t.Fatalf("got saved queries %v, want none", names)
+	}
+
+	failed := savedQuery{
+		APL:       "['http-logs']\n| where status >= 500",
+		StartTime: "-1h",
+		Format:    "json",
+	}
+	sqs.Set("prod", "errors", failed)
+	sqs.Set("prod", "all", savedQuery{APL: "['http-logs']"})
+	sqs.Set("eu", "errors", savedQuery{APL: "['eu-logs']"})
+	if err = sqs.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	if sqs, err = loadSavedQueries(); err != nil {
+		t.Fatal(err)
+	}
+	if names := sqs.Names("prod"); !slices.Equal(names, []string{"all", "errors"}) {
+		t.Errorf("Names() = %v, want [all errors]", names)
+	}
+	if sq, ok := sqs.Get("prod", "errors"); !ok || sq != failed {
+		t.Errorf("Get() = %+v, %t, want %+v", sq, ok, failed)
+	}
+	if sq, _ := sqs.Get("eu", "errors"); sq.APL != "['eu-logs']" {
+		t.Errorf("Get() = %+v, want query of eu deployment", sq)
+	}
+
+	if !sqs.Delete("eu", "errors") {
+		t.Error("Delete() = false, want true")
+	} else if sqs.Delete("eu", "errors") {
+		t.Error("Delete() of deleted query = true, want false")
+	}
+	if _, ok := sqs.Deployments["eu"]; ok {
+		t.Error("deployment without queries not removed")
+	}
+}
+
+func TestSavedQuery_Validate(t *testing.T) {
+	tests := []struct {
+		sq  savedQuery
+		err string
+	}{
+		{savedQuery{APL: "['logs']", StartTime: "-1d", EndTime: "2024-01-01T00:00:00Z", Format: "csv"}, ""},
+		{savedQuery{}, "empty query"},
+		{savedQuery{APL: "['logs']", StartTime: "yesterday-ish"}, "invalid start time"},
+		{savedQuery{APL: "['logs']", EndTime: "soon"}, "invalid end time"},
+		{savedQuery{APL: "['logs']", Format: "xml"}, "invalid format"},
+	}
+
+	for _, tt := range tests {
+		err := tt.sq.validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("validate(%+v) error = %v, want nil", tt.sq, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("validate(%+v) error = %v, want error containing %q", tt.sq, err, tt.err)
+		}
+	}
+}
+
+func TestSharedQueries(t *testing.T) {
+	queries := map[string]savedQuery{
+		"errors": {APL: "['http-logs']\n| where status >= 500", Description: "Failed requests", StartTime: "-1h"},
+		"all":    {APL: "['http-logs']"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSharedQueries(&buf, queries); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readSharedQueries(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, sq := range queries {
+		if got[name] != sq {
+			t.Errorf("query %s = %+v, want %+v", name, got[name], sq)
+		}
+	}
+	if len(got) != len(queries) {
+		t.Errorf("got %d queries, want %d", len(got), len(queries))
+	}
+
+	if _, err = readSharedQueries(strings.NewReader("[queries.\"-bad\"]\napl = \"x\"\n")); err == nil {
+		t.Error("expected error for invalid name")
+	}
+	if _, err = readSharedQueries(strings.NewReader("[queries.empty]\ndescription = \"x\"\n")); err == nil {
+		t.Error("expected error for query without APL")
+	}
+}
@@ -0,0 +1,310 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"golang.org/x/sync/errgroup"
+)
+
+// sortRe matches the APL operators that sort the rows of a query.
+var sortRe = regexp.MustCompile(`\|\s*(?:sort|order)\b`)
+
+// splitWindow is one of the time windows the range of a query is split into.
+type splitWindow struct {
+	start, end time.Time
+}
+
+func (w splitWindow) String() string {
+	return w.start.Format(time.RFC3339) + " .. " + w.end.Format(time.RFC3339)
+}
+
+// splitRange splits the time range into windows of the given size, newest
+// first. The remainder of the range is added to the oldest window.
+func splitRange(start, end time.Time, size time.Duration) []splitWindow {
+	var windows []splitWindow
+	for windowEnd := end; windowEnd.After(start); {
+		windowStart := windowEnd.Add(-size)
+		if windowStart.Sub(start) < size {
+			windowStart = start
+		}
+		windows = append(windows, splitWindow{start: windowStart, end: windowEnd})
+		windowEnd = windowStart
+	}
+	return windows
+}
+
+// isSorted returns true if the query sorts the rows it returns.
+func isSorted(apl string) bool {
+	return sortRe.MatchString(apl)
+}
+
+// execSplit runs the query for every window of the split range, at most
+// as many at once as the concurrency of the options allows, and merges their
+// results. Failed windows are reported and left out of the merged result,
+// which is then marked as partial. It only fails if all windows fail or the
+// results can't be merged.
+func execSplit(ctx context.Context, opts *options, fn queryFunc, start, end time.Time) (*query.Result, error) {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	windows := splitRange(start, end, opts.split)
+
+	var (
+		results = make([]*query.Result, len(windows))
+		errs    = make([]error, len(windows))
+		eg      errgroup.Group
+	)
+	eg.SetLimit(opts.Concurrency)
+	for i, window := range windows {
+		eg.Go(func() error {
+			results[i], errs[i] = fn(ctx, window.start, window.end)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cs := opts.IO.ColorScheme()
+
+	succeeded := make([]*query.Result, 0, len(results))
+	for i, res := range results {
+		if err := errs[i]; err != nil {
+			fmt.Fprintf(opts.IO.ErrOut(), "%s Window %s failed: %v\n", cs.ErrorIcon(), windows[i], err)
+			continue
+		}
+		opts.stats.add(res.Status, res.TraceID)
+		succeeded = append(succeeded, res)
+	}
+	if len(succeeded) == 0 {
+		return nil, fmt.Errorf("all %d windows of the query failed", len(windows))
+	}
+
+	res, err := mergeResults(succeeded)
+	if err != nil {
+		return nil, err
+	}
+	if len(succeeded) < len(windows) {
+		res.Status.IsPartial = true
+	}
+	return res, nil
+}
+
+// mergeResults merges the results of the windows of a split query into one.
+// Tables are merged with the tables of the same name of the other windows,
+// including the "_totals" tables. The rows of results that are not aggregated are concatenated. Aggregated
+// results are aggregated again by their groups, which is only possible for
+// aggregations that can be combined: count, sum, min and max.
+func mergeResults(results []*query.Result) (*query.Result, error) {
+	merged := &query.Result{
+		TraceID: results[0].TraceID,
+	}
+
+	var (
+		names  []string
+		tables = make(map[string][]query.Table)
+		seen   = make(map[string]bool)
+	)
+	for _, res := range results {
+		status := res.Status
+		merged.Status.ElapsedTime += status.ElapsedTime
+		merged.Status.RowsExamined += status.RowsExamined
+		merged.Status.RowsMatched += status.RowsMatched
+		merged.Status.IsPartial = merged.Status.IsPartial || status.IsPartial
+		merged.Status.IsEstimate = merged.Status.IsEstimate || status.IsEstimate
+		if c := status.MinCursor; c != "" && (merged.Status.MinCursor == "" || c < merged.Status.MinCursor) {
+			merged.Status.MinCursor = c
+		}
+		if c := status.MaxCursor; c > merged.Status.MaxCursor {
+			merged.Status.MaxCursor = c
+		}
+		for _, msg := range status.Messages {
+			if !seen[msg.Msg] {
+				seen[msg.Msg] = true
+				merged.Status.Messages = append(merged.Status.Messages, msg)
+			}
+		}
+
+		for i, table := range res.Tables {
+			if len(table.Fields) == 0 {
+				continue
+			}
+			name := table.Name
+			if name == "" {
+				name = strconv.Itoa(i)
+			}
+			if _, ok := tables[name]; !ok {
+				names = append(names, name)
+			}
+			tables[name] = append(tables[name], table)
+		}
+	}
+
+	for _, name := range names {
+		table, err := mergeTables(tables[name])
+		if err != nil {
+			return nil, fmt.Errorf("merge table %q: %w", name, err)
+		}
+		merged.Tables = append(merged.Tables, table)
+	}
+
+	return merged, nil
+}
+
+// mergeTables merges the tables of the windows of a split query. The fields of
+// the merged table are those of all tables, in the order they first appear.
+// Aggregated tables are merged by their groups, so they can't have fields that
+// are computed from the aggregations and all windows must use the same bins.
+func mergeTables(tables []query.Table) (query.Table, error) {
+	merged := query.Table{
+		Name:    tables[0].Name,
+		Sources: tables[0].Sources,
+		Groups:  tables[0].Groups,
+		Buckets: tables[0].Buckets,
+	}
+
+	fieldIdx := make(map[string]int)
+	for _, table := range tables {
+		for _, field := range table.Fields {
+			if _, ok := fieldIdx[field.Name]; ok {
+				continue
+			}
+			if agg := field.Aggregation; agg != nil && !mergeableAggregation(agg.Op) {
+				return query.Table{}, fmt.Errorf("the %s aggregation of %q can't be merged across windows, only count, sum, min and max can", agg.Op, field.Name)
+			}
+			fieldIdx[field.Name] = len(merged.Fields)
+			merged.Fields = append(merged.Fields, field)
+		}
+	}
+	merged.Columns = make([]query.Column, len(merged.Fields))
+
+	aggregated := tableHasAggregation(merged)
+	if aggregated {
+		if err := checkMergeableGroups(tables); err != nil {
+			return query.Table{}, err
+		}
+	}
+
+	groupRows := make(map[string]int)
+	for _, table := range tables {
+		for k := range tableLen(table) {
+			row := make([]any, len(merged.Fields))
+			for i, field := range table.Fields {
+				row[fieldIdx[field.Name]] = table.Columns[i][k]
+			}
+
+			if !aggregated {
+				appendRow(&merged, row)
+				continue
+			}
+
+			key := groupKey(merged.Fields, row)
+			prevK, ok := groupRows[key]
+			if !ok {
+				groupRows[key] = tableLen(merged)
+				appendRow(&merged, row)
+				continue
+			}
+			for i, field := range merged.Fields {
+				if field.Aggregation != nil {
+					merged.Columns[i][prevK] = mergeAggregation(field.Aggregation.Op, merged.Columns[i][prevK], row[i])
+				}
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// checkMergeableGroups checks that the rows of the aggregated tables line up by
+// their groups. A field that is neither an aggregation nor a group, like one
+// added using "extend" after "summarize", would become part of the group and
+// bins that differ between windows, like those of "bin_auto", never match.
+func checkMergeableGroups(tables []query.Table) error {
+	for _, table := range tables {
+		for _, field := range table.Fields {
+			if field.Aggregation == nil && !isGroupField(table, field.Name) {
+				return fmt.Errorf("the field %q is neither an aggregation nor a group and can't be merged across windows, compute it in a query without --split", field.Name)
+			}
+		}
+	}
+
+	first := tables[0].Buckets
+	for _, table := range tables[1:] {
+		if b := table.Buckets; (b == nil) != (first == nil) || (b != nil && fmt.Sprint(b.Size) != fmt.Sprint(first.Size)) {
+			return errors.New("the time bins differ between windows and can't be merged, bin times explicitly, e.g. using bin(_time, 1h)")
+		}
+	}
+
+	return nil
+}
+
+// isGroupField returns true if the field of the table is one of its groups or
+// the field it is bucketed on.
+func isGroupField(table query.Table, name string) bool {
+	if name == "_time" || (table.Buckets != nil && table.Buckets.Field == name) {
+		return true
+	}
+	for _, group := range table.Groups {
+		if group.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func appendRow(table *query.Table, row []any) {
+	for i, v := range row {
+		table.Columns[i] = append(table.Columns[i], v)
+	}
+}
+
+// groupKey returns the values of the group-by fields of the row as a key.
+func groupKey(fields []query.Field, row []any) string {
+	var sb strings.Builder
+	for i, field := range fields {
+		if field.Aggregation == nil {
+			sb.WriteString(fmt.Sprint(row[i]))
+			sb.WriteByte(0)
+		}
+	}
+	return sb.String()
+}
+
+func mergeableAggregation(op query.AggregationOp) bool {
+	switch op {
+	case query.OpCount, query.OpSum, query.OpMin, query.OpMax:
+		return true
+	}
+	return false
+}
+
+// mergeAggregation combines two results of the aggregation. A value that is
+// not a number, like the null of an empty sum, is ignored.
+func mergeAggregation(op query.AggregationOp, a, b any) any {
+	x, y := floatValue(a), floatValue(b)
+	switch {
+	case math.IsNaN(x):
+		return b
+	case math.IsNaN(y):
+		return a
+	}
+
+	switch op {
+	case query.OpMin:
+		return min(x, y)
+	case query.OpMax:
+		return max(x, y)
+	}
+	return x + y
+}
@@ -0,0 +1,198 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestSplitRange(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	windows := splitRange(start, start.Add(60*time.Hour), 24*time.Hour)
+	want := []splitWindow{
+		{start.Add(36 * time.Hour), start.Add(60 * time.Hour)},
+		{start, start.Add(36 * time.Hour)},
+	}
+	if fmt.Sprint(windows) != fmt.Sprint(want) {
+		t.Errorf("splitRange() = %v, want %v", windows, want)
+	}
+
+	if windows = splitRange(start, start, time.Hour); len(windows) != 0 {
+		t.Errorf("splitRange() of empty range = %v, want none", windows)
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	res1 := &query.Result{
+		TraceID: "trace-1",
+		Status:  query.Status{RowsMatched: 2, IsEstimate: true},
+		Tables: []query.Table{{
+			Fields:  []query.Field{{Name: "_time"}, {Name: "msg"}},
+			Columns: []query.Column{{"t2", "t1"}, {"b", "a"}},
+		}},
+	}
+	res2 := &query.Result{
+		TraceID: "trace-2",
+		Status:  query.Status{RowsMatched: 1},
+		Tables: []query.Table{{
+			Fields:  []query.Field{{Name: "_time"}, {Name: "level"}},
+			Columns: []query.Column{{"t0"}, {"info"}},
+		}},
+	}
+
+	merged, err := mergeResults([]*query.Result{res1, res2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.TraceID != "trace-1" || merged.Status.RowsMatched != 3 || !merged.Status.IsEstimate {
+		t.Errorf("merged status = %+v, trace = %q", merged.Status, merged.TraceID)
+	}
+
+	// Rows are concatenated and fields missing from a window are null.
+	table := merged.Tables[0]
+	if got := fmt.Sprint(tableFieldNames(table)); got != "[_time msg level]" {
+		t.Errorf("fields = %s, want [_time msg level]", got)
+	}
+	if got := fmt.Sprint(table.Columns); got != "[[t2 t1 t0] [b a <nil>] [<nil> <nil> info]]" {
+		t.Errorf("columns = %s", got)
+	}
+}
+
+func TestMergeResults_Aggregated(t *testing.T) {
+	fields := []query.Field{
+		{Name: "service"},
+		{Name: "count_", Aggregation: &query.Aggregation{Op: query.OpCount}},
+		{Name: "min_duration", Aggregation: &query.Aggregation{Op: query.OpMin}},
+		{Name: "max_duration", Aggregation: &query.Aggregation{Op: query.OpMax}},
+		{Name: "sum_bytes", Aggregation: &query.Aggregation{Op: query.OpSum}},
+	}
+
+	merged, err := mergeResults([]*query.Result{
+		{Tables: []query.Table{{
+			Fields:  fields,
+			Groups:  []query.Group{{Name: "service"}},
+			Columns: []query.Column{{"api", "web"}, {2.0, 1.0}, {5.0, 3.0}, {9.0, 3.0}, {100.0, nil}},
+		}}},
+		{Tables: []query.Table{{
+			Fields:  fields,
+			Groups:  []query.Group{{Name: "service"}},
+			Columns: []query.Column{{"db", "api"}, {4.0, 3.0}, {1.0, 4.0}, {2.0, 7.0}, {10.0, 50.0}},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Groups are merged, in the order they first appear.
+	want := "[[api web db] [5 1 4] [4 3 1] [9 3 2] [150 <nil> 10]]"
+	if got := fmt.Sprint(merged.Tables[0].Columns); got != want {
+		t.Errorf("columns = %s, want %s", got, want)
+	}
+
+	fields[1].Aggregation.Op = query.OpAvg
+	if _, err = mergeResults([]*query.Result{{Tables: []query.Table{{Fields: fields}}}}); err == nil {
+		t.Error("merging an average did not fail")
+	}
+}
+
+func TestMergeResults_Unaligned(t *testing.T) {
+	count := &query.Aggregation{Op: query.OpCount}
+
+	// A field computed after the aggregation is not a group.
+	computed := query.Table{
+		Fields:  []query.Field{{Name: "service"}, {Name: "count_", Aggregation: count}, {Name: "rate"}},
+		Groups:  []query.Group{{Name: "service"}},
+		Columns: []query.Column{{"api"}, {2.0}, {0.5}},
+	}
+	_, err := mergeResults([]*query.Result{{Tables: []query.Table{computed}}, {Tables: []query.Table{computed}}})
+	if err == nil || !strings.Contains(err.Error(), `"rate"`) {
+		t.Errorf("merging a computed field: err = %v", err)
+	}
+
+	// Automatic bins differ between windows.
+	binned := func(size time.Duration) *query.Result {
+		return &query.Result{Tables: []query.Table{{
+			Fields:  []query.Field{{Name: "_time"}, {Name: "count_", Aggregation: count}},
+			Buckets: &query.Buckets{Field: "_time", Size: float64(size)},
+			Columns: []query.Column{{"2024-01-01T00:00:00Z"}, {1.0}},
+		}}}
+	}
+	if _, err = mergeResults([]*query.Result{binned(time.Minute), binned(time.Hour)}); err == nil || !strings.Contains(err.Error(), "bins differ") {
+		t.Errorf("merging different bins: err = %v", err)
+	}
+
+	// Equal bins line up.
+	merged, err := mergeResults([]*query.Result{binned(time.Hour), binned(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(merged.Tables[0].Columns); got != "[[2024-01-01T00:00:00Z] [2]]" {
+		t.Errorf("columns = %s", got)
+	}
+}
+
+func TestMergeResults_Tables(t *testing.T) {
+	count := &query.Aggregation{Op: query.OpCount}
+	window := func(n float64) *query.Result {
+		return &query.Result{Tables: []query.Table{
+			{
+				Name:    "errors",
+				Fields:  []query.Field{{Name: "service"}, {Name: "count_", Aggregation: count}},
+				Groups:  []query.Group{{Name: "service"}},
+				Columns: []query.Column{{"api"}, {n}},
+			},
+			{
+				Name:    "_totals",
+				Fields:  []query.Field{{Name: "count_", Aggregation: count}},
+				Columns: []query.Column{{n}},
+			},
+			{
+				Name:    "requests",
+				Fields:  []query.Field{{Name: "_time"}, {Name: "uri"}},
+				Columns: []query.Column{{"t"}, {"/"}},
+			},
+		}}
+	}
+
+	merged, err := mergeResults([]*query.Result{window(2), window(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every table is merged with the tables of the same name.
+	if len(merged.Tables) != 3 {
+		t.Fatalf("tables = %d, want 3", len(merged.Tables))
+	}
+	for i, want := range []string{
+		"errors [[api] [5]]",
+		"_totals [[5]]",
+		"requests [[t t] [/ /]]",
+	} {
+		table := merged.Tables[i]
+		if got := fmt.Sprint(table.Name, " ", table.Columns); got != want {
+			t.Errorf("table %d = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestIsSorted(t *testing.T) {
+	tests := []struct {
+		apl  string
+		want bool
+	}{
+		{"['logs']", false},
+		{"['logs'] | where sorted == true", false},
+		{"['logs'] | sort by _time", true},
+		{"['logs']|order by status desc", true},
+	}
+
+	for _, tt := range tests {
+		if got := isSorted(tt.apl); got != tt.want {
+			t.Errorf("isSorted(%q) = %v, want %v", tt.apl, got, tt.want)
+		}
+	}
+}
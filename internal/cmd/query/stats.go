@@ -0,0 +1,101 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/dustin/go-humanize"
+
+	"github.com/axiomhq/cli/pkg/iofmt"
+)
+
+// queryStats are the execution details of a query, as reported by the server
+// and output using "--stats". The details of a query that takes multiple
+// requests, like an export using "--all", are summed up.
+type queryStats struct {
+	// Requests is the number of requests the query took.
+	Requests int `json:"requests"`
+	// ElapsedMS is the time the server took to execute the query, in
+	// milliseconds.
+	ElapsedMS float64 `json:"elapsed_ms"`
+	// RowsExamined is the number of rows examined by the query.
+	RowsExamined uint64 `json:"rows_examined"`
+	// RowsMatched is the number of rows that matched the query.
+	RowsMatched uint64 `json:"rows_matched"`
+	// MinCursor and MaxCursor are the ids of the oldest and newest row seen
+	// by the server.
+	MinCursor string `json:"min_cursor"`
+	MaxCursor string `json:"max_cursor"`
+	// IsPartial is true if the result is partial.
+	IsPartial bool `json:"is_partial"`
+	// IsEstimate is true if the result is estimated.
+	IsEstimate bool `json:"is_estimate"`
+	// TraceID is the trace ID of the first request.
+	TraceID string `json:"trace_id"`
+
+	elapsed time.Duration
+}
+
+// add the status of a request to the statistics.
+func (s *queryStats) add(status query.Status, traceID string) {
+	s.Requests++
+	s.elapsed += status.ElapsedTime
+	s.ElapsedMS = float64(s.elapsed) / float64(time.Millisecond)
+	s.RowsExamined += status.RowsExamined
+	s.RowsMatched += status.RowsMatched
+	if c := status.MinCursor; c != "" && (s.MinCursor == "" || c < s.MinCursor) {
+		s.MinCursor = c
+	}
+	if c := status.MaxCursor; c > s.MaxCursor {
+		s.MaxCursor = c
+	}
+	s.IsPartial = s.IsPartial || status.IsPartial
+	s.IsEstimate = s.IsEstimate || status.IsEstimate
+	if s.TraceID == "" {
+		s.TraceID = traceID
+	}
+}
+
+// printStats writes the statistics to stderr, as a JSON object if the result
+// is output in JSON format and as text otherwise.
+func printStats(opts *options, s queryStats) error {
+	if opts.format == iofmt.JSON && !opts.Transform.IsSet() {
+		return iofmt.FormatToJSON(opts.IO.ErrOut(), s, opts.IO.ColorEnabled())
+	}
+
+	cs := opts.IO.ColorScheme()
+
+	yesNo := func(b bool) string {
+		if b {
+			return cs.Yellow("yes")
+		}
+		return "no"
+	}
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	lines := [][2]string{
+		{"Elapsed time", s.elapsed.String()},
+		{"Rows examined", humanize.Comma(int64(s.RowsExamined))},
+		{"Rows matched", humanize.Comma(int64(s.RowsMatched))},
+		{"Min cursor", orDash(s.MinCursor)},
+		{"Max cursor", orDash(s.MaxCursor)},
+		{"Partial", yesNo(s.IsPartial)},
+		{"Estimate", yesNo(s.IsEstimate)},
+		{"Trace ID", orDash(s.TraceID)},
+	}
+	if s.Requests > 1 {
+		lines = append([][2]string{{"Requests", humanize.Comma(int64(s.Requests))}}, lines...)
+	}
+
+	fmt.Fprintln(opts.IO.ErrOut(), cs.Bold("Query statistics:"))
+	for _, line := range lines {
+		fmt.Fprintf(opts.IO.ErrOut(), "  %s %s\n", cs.Gray(fmt.Sprintf("%-14s", line[0]+":")), line[1])
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestQueryStats(t *testing.T) {
+	var s queryStats
+	s.add(query.Status{
+		ElapsedTime:  2 * time.Millisecond,
+		RowsExamined: 100,
+		RowsMatched:  10,
+		MinCursor:    "0d3",
+		MaxCursor:    "0d5",
+	}, "trace-1")
+	s.add(query.Status{
+		ElapsedTime:  500 * time.Microsecond,
+		RowsExamined: 50,
+		RowsMatched:  5,
+		MinCursor:    "0d1",
+		MaxCursor:    "0d4",
+		IsPartial:    true,
+	}, "trace-2")
+
+	want := queryStats{
+		Requests:     2,
+		ElapsedMS:    2.5,
+		RowsExamined: 150,
+		RowsMatched:  15,
+		MinCursor:    "0d1",
+		MaxCursor:    "0d5",
+		IsPartial:    true,
+		TraceID:      "trace-1",
+
+		elapsed: 2500 * time.Microsecond,
+	}
+	if s != want {
+		t.Errorf("stats = %+v, want %+v", s, want)
+	}
+}
@@ -0,0 +1,190 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+
+	"github.com/axiomhq/cli/pkg/terminal"
+)
+
+// clearScreen moves the cursor to the top left corner and clears the screen.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// runWatch runs the query repeatedly on the watch interval, until canceled.
+// On a TTY, the result is redrawn in place and rows and values which changed
+// since the previous run are highlighted. Otherwise, the result of every run
+// is appended to the output, preceded by a separator with the time of the run.
+// Relative times are resolved again for every run. Failing runs don't stop
+// watching.
+func runWatch(ctx context.Context, opts *options) error {
+	if err := completeQuery(opts); err != nil {
+		return err
+	}
+	// The query must not be read again from stdin.
+	opts.File = ""
+
+	var (
+		cs  = opts.IO.ColorScheme()
+		tty = opts.IO.IsStdoutTTY()
+
+		prev *query.Table
+	)
+
+	ticker := time.NewTicker(opts.Watch)
+	defer ticker.Stop()
+
+	for n := 0; ; n++ {
+		runOpts := *opts
+		runOpts.history = historyEntry{}
+		runOpts.stats = queryStats{}
+		if err := resolveQuery(&runOpts); err != nil {
+			return err
+		}
+
+		res, err := execQuery(ctx, &runOpts)
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Only the first run is recorded, so watching doesn't flood the
+		// history.
+		if n == 0 {
+			_ = recordHistory(&runOpts, err)
+		}
+
+		now := time.Now()
+		if tty {
+			fmt.Fprint(opts.IO.Out(), clearScreen)
+			fmt.Fprintf(opts.IO.Out(), "Every %s, last run at %s. Press Ctrl-C to stop.\n\n",
+				cs.Bold(opts.Watch.String()), cs.Bold(now.Format(time.TimeOnly)))
+		} else {
+			fmt.Fprintf(opts.IO.Out(), "--- %s ---\n", now.Format(time.RFC3339))
+		}
+
+		if err == nil {
+			// An empty result has no rows to compare, so all rows of the next
+			// result are new.
+			table := &query.Table{}
+			if !resultIsEmpty(res) {
+				table = &res.Tables[0]
+			}
+			var diff *resultDiff
+			if tty {
+				diff = newResultDiff(prev, table)
+			}
+			prev = table
+
+			err = printResult(ctx, &runOpts, res, diff)
+			if runOpts.Stats {
+				_ = printStats(&runOpts, runOpts.stats)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(opts.IO.ErrOut(), "%s %v\n", cs.ErrorIcon(), err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// resultDiff tells which rows and values of a result changed since the
+// previous one. Rows are matched by the values of their group-by fields or, if
+// the result is not aggregated, by all their values.
+type resultDiff struct {
+	newRows      map[int]bool
+	changedCells map[[2]int]bool
+}
+
+// newResultDiff compares the current table with the previous one. It returns
+// nil if there is nothing to compare.
+func newResultDiff(prev, cur *query.Table) *resultDiff {
+	if prev == nil || cur == nil || len(cur.Columns) == 0 {
+		return nil
+	}
+
+	var (
+		keyFields = diffKeyFields(cur)
+		prevRows  = make(map[string]int)
+	)
+	if len(prev.Columns) > 0 {
+		for k := range prev.Columns[0] {
+			prevRows[diffRowKey(prev, keyFields, k)] = k
+		}
+	}
+
+	prevColumns := make(map[string]int, len(prev.Fields))
+	for i, field := range prev.Fields {
+		prevColumns[field.Name] = i
+	}
+
+	diff := &resultDiff{
+		newRows:      make(map[int]bool),
+		changedCells: make(map[[2]int]bool),
+	}
+	for k := range cur.Columns[0] {
+		prevK, ok := prevRows[diffRowKey(cur, keyFields, k)]
+		if !ok {
+			diff.newRows[k] = true
+			continue
+		}
+		for i, field := range cur.Fields {
+			j, ok := prevColumns[field.Name]
+			if !ok || fmt.Sprint(prev.Columns[j][prevK]) != fmt.Sprint(cur.Columns[i][k]) {
+				diff.changedCells[[2]int{k, i}] = true
+			}
+		}
+	}
+	return diff
+}
+
+// diffKeyFields returns the names of the fields rows are matched by: the
+// group-by fields of aggregated results and all fields otherwise.
+func diffKeyFields(table *query.Table) []string {
+	if !tableHasAggregation(*table) {
+		return tableFieldNames(*table)
+	}
+
+	var names []string
+	for _, field := range table.Fields {
+		if field.Aggregation == nil {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+func diffRowKey(table *query.Table, keyFields []string, k int) string {
+	var sb strings.Builder
+	for _, name := range keyFields {
+		for i, field := range table.Fields {
+			if field.Name == name {
+				sb.WriteString(fmt.Sprint(table.Columns[i][k]))
+				break
+			}
+		}
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+// style returns the color function a cell is rendered with: new rows are
+// green and changed values yellow. It returns nil for unchanged cells and if
+// the diff is nil.
+func (d *resultDiff) style(cs *terminal.ColorScheme, row, col int) terminal.ColorFunc {
+	switch {
+	case d == nil:
+		return nil
+	case d.newRows[row]:
+		return cs.Green
+	case d.changedCells[[2]int{row, col}]:
+		return cs.Yellow
+	}
+	return nil
+}
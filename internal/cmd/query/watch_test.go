@@ -0,0 +1,80 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func TestNewResultDiff(t *testing.T) {
+	count := &query.Aggregation{Op: query.OpCount}
+
+	prev := &query.Table{
+		Fields: []query.Field{{Name: "service"}, {Name: "count_", Aggregation: count}},
+		Columns: []query.Column{
+			{"api", "web"},
+			{10, 5},
+		},
+	}
+	cur := &query.Table{
+		Fields: []query.Field{{Name: "service"}, {Name: "count_", Aggregation: count}},
+		Columns: []query.Column{
+			{"web", "api", "db"},
+			{5, 12, 1},
+		},
+	}
+
+	if newResultDiff(nil, cur) != nil {
+		t.Error("diff without previous result is not nil")
+	}
+
+	diff := newResultDiff(prev, cur)
+	if diff == nil {
+		t.Fatal("diff is nil")
+	}
+
+	// Rows are matched by their group: "web" is unchanged, the count of "api"
+	// changed and "db" is new.
+	if diff.newRows[0] || diff.changedCells[[2]int{0, 1}] {
+		t.Error("unchanged row of web is highlighted")
+	}
+	if !diff.changedCells[[2]int{1, 1}] || diff.changedCells[[2]int{1, 0}] {
+		t.Error("only the changed count of api must be highlighted")
+	}
+	if !diff.newRows[2] {
+		t.Error("new row of db is not highlighted")
+	}
+
+	// All rows are new after an empty result.
+	diff = newResultDiff(&query.Table{}, cur)
+	for k := range 3 {
+		if !diff.newRows[k] {
+			t.Errorf("row %d after empty result is not new", k)
+		}
+	}
+}
+
+func TestNewResultDiff_NotAggregated(t *testing.T) {
+	prev := &query.Table{
+		Fields:  []query.Field{{Name: "_time"}, {Name: "msg"}},
+		Columns: []query.Column{{"t1", "t2"}, {"a", "b"}},
+	}
+	cur := &query.Table{
+		Fields:  []query.Field{{Name: "_time"}, {Name: "msg"}},
+		Columns: []query.Column{{"t3", "t2"}, {"c", "b"}},
+	}
+
+	diff := newResultDiff(prev, cur)
+	if !diff.newRows[0] || diff.newRows[1] {
+		t.Errorf("new rows = %v, want only row 0", diff.newRows)
+	}
+	if len(diff.changedCells) != 0 {
+		t.Errorf("changed cells = %v, want none", diff.changedCells)
+	}
+	if diff.style(nil, 1, 0) != nil {
+		t.Error("unchanged cell has a style")
+	}
+	if (*resultDiff)(nil).style(nil, 0, 0) != nil {
+		t.Error("nil diff has a style")
+	}
+}
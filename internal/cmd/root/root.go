@@ -12,6 +12,7 @@ import (
 	"github.com/axiomhq/cli/internal/config"
 
 	// Core commands
+	agentCmd "github.com/axiomhq/cli/internal/cmd/agent"
 	ingestCmd "github.com/axiomhq/cli/internal/cmd/ingest"
 	queryCmd "github.com/axiomhq/cli/internal/cmd/query"
 	streamCmd "github.com/axiomhq/cli/internal/cmd/stream"
@@ -141,6 +142,7 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 	cmd.PersistentFlags().Bool("no-spinner", false, "Disable the activity indicator")
 
 	// Core commands
+	cmd.AddCommand(agentCmd.NewCmd(f))
 	cmd.AddCommand(ingestCmd.NewCmd(f))
 	cmd.AddCommand(queryCmd.NewCmd(f))
 	cmd.AddCommand(streamCmd.NewCmd(f))
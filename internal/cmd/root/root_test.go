@@ -0,0 +1,64 @@
+package root
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/cli/internal/cmdutil"
+	"github.com/axiomhq/cli/internal/config"
+	"github.com/axiomhq/cli/pkg/terminal"
+)
+
+// The agent has its own configuration file but takes its credentials from the
+// CLI configuration file given using the global "--config" flag.
+func TestNewCmd_AgentConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	cliConfig := filepath.Join(dir, "cli.toml")
+	require.NoError(t, os.WriteFile(cliConfig, []byte(`
+active_deployment = "default"
+
+[deployments.default]
+url = "https://api.axiom.co"
+token = "xaat-00000000-0000-0000-0000-000000000000"
+
+[deployments.eu]
+url = "https://api.eu.axiom.co"
+token = "xaat-00000000-0000-0000-0000-000000000000"
+`), 0o600))
+
+	agentConfig := filepath.Join(dir, "agent.toml")
+	require.NoError(t, os.WriteFile(agentConfig, []byte(`
+deployment = "eu"
+
+[[sources]]
+type = "socket"
+listen = "tcp://127.0.0.1:5140"
+dataset = "logs"
+`), 0o600))
+
+	run := func(args ...string) (*cmdutil.Factory, error) {
+		f := &cmdutil.Factory{
+			Config: &config.Config{},
+			IO:     terminal.TestIO(),
+		}
+		cmd := NewCmd(f)
+		cmdutil.InheritRootPersistenPreRun(cmd)
+		cmd.SetArgs(args)
+		_, err := cmd.ExecuteContextC(t.Context())
+		return f, err
+	}
+
+	f, err := run("-C", cliConfig, "agent", "-c", agentConfig, "--validate")
+	require.NoError(t, err)
+	assert.Equal(t, cliConfig, f.Config.ConfigFilePath)
+	assert.Contains(t, f.Config.Deployments, "eu")
+
+	// Without the CLI configuration, the deployment of the agent is unknown.
+	_, err = run("agent", "--agent-config", agentConfig, "--validate")
+	assert.ErrorContains(t, err, `deployment "eu" not configured`)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
@@ -26,6 +27,8 @@ type options struct {
 	Dataset string
 	// Format to output data in. Defaults to tabular output.
 	Format string
+	// Transform shapes the output using a template or jq expression.
+	Transform iofmt.Transform
 }
 
 // NewCmd creates and returns the stream command.
@@ -35,9 +38,23 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:   "stream [<dataset-name>] [(-f|--format)=json|table]",
+		Use:   "stream [<dataset-name>] [(-f|--format) <format> | (-t|--template) <template> | (-q|--jq) <expression>]",
 		Short: "Livestream data",
-		Long:  `Livestream data from an Axiom dataset.`,
+		Long: heredoc.Doc(`
+			Livestream data from an Axiom dataset.
+
+			As the stream never ends, events are written as they arrive: the
+			"json" format writes one JSON object per event and line, just like
+			"ndjson". The "json", "ndjson" and "yaml" formats write the events
+			as returned by the server, with their data in the "data" field.
+			The "csv", "tsv" and "markdown" formats write the "_time" and the
+			data fields of the events as columns. The columns are taken from
+			the first event.
+
+			Use "--template" or "--jq" to shape the output with a Go template or
+			a jq expression instead. Both are applied to every event, as
+			returned by the server.
+		`),
 
 		DisableFlagsInUseLine: true,
 
@@ -50,6 +67,12 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 			
 			# Stream the "http-logs" dataset:
 			$ axiom stream http-logs
+
+			# Stream the "http-logs" dataset as CSV:
+			$ axiom stream http-logs --format csv
+
+			# Stream the URIs of failed requests of the "http-logs" dataset:
+			$ axiom stream http-logs --jq 'select(.data.status >= 500) | .data.uri'
 		`),
 
 		Annotations: map[string]string{
@@ -74,6 +97,8 @@ func NewCmd(f *cmdutil.Factory) *cobra.Command {
 
 	_ = cmd.RegisterFlagCompletionFunc("format", cmdutil.FormatCompletion)
 
+	cmdutil.AddTransformFlags(cmd, &opts.Transform)
+
 	return cmd
 }
 
@@ -119,11 +144,32 @@ func complete(ctx context.Context, opts *options) error {
 }
 
 func run(ctx context.Context, opts *options) error {
+	format, err := cmdutil.ParseFormat(opts.Format)
+	if err != nil {
+		return err
+	} else if err = cmdutil.CompileTransform(opts.IO, &opts.Transform); err != nil {
+		return err
+	}
+
 	client, err := opts.Client(ctx)
 	if err != nil {
 		return err
 	}
 
+	var enc iofmt.Encoder
+	switch {
+	case opts.Transform.IsSet(), format == iofmt.Table:
+	case format == iofmt.JSON:
+		enc, _ = iofmt.NewEncoder(opts.IO.Out(), iofmt.NDJSON, nil, opts.IO.ColorEnabled())
+	default:
+		if enc, err = iofmt.NewEncoder(opts.IO.Out(), format, nil, opts.IO.ColorEnabled()); err != nil {
+			return err
+		}
+	}
+	if enc != nil {
+		defer enc.Close()
+	}
+
 	cs := opts.IO.ColorScheme()
 
 	if opts.IO.IsStdoutTTY() {
@@ -158,18 +204,30 @@ func run(ctx context.Context, opts *options) error {
 			cursor = res.Matches[len(res.Matches)-1].RowID
 
 			for _, entry := range res.Matches {
-				var data any
-				switch opts.Format {
-				case iofmt.JSON.String():
-					data = entry
-				default:
+				switch {
+				case opts.Transform.IsSet():
+					err = opts.Transform.Write(opts.IO.Out(), entry, opts.IO.ColorEnabled())
+				case format == iofmt.Table:
 					fmt.Fprintf(opts.IO.Out(), "%s\t", cs.Gray(entry.Time.Format(time.RFC1123)))
-					data = entry.Data
+					err = iofmt.FormatToJSON(opts.IO.Out(), entry.Data, opts.IO.ColorEnabled())
+				case format == iofmt.CSV, format == iofmt.TSV, format == iofmt.Markdown:
+					err = enc.Encode(flattenEntry(entry))
+				default:
+					err = enc.Encode(entry)
 				}
-				if err = iofmt.FormatToJSON(opts.IO.Out(), data, opts.IO.ColorEnabled()); err != nil {
+				if err != nil {
 					return err
 				}
 			}
 		}
 	}
 }
+
+// flattenEntry returns the data of the entry along with its timestamp, for
+// tabular output formats.
+func flattenEntry(entry querylegacy.Entry) map[string]any {
+	row := make(map[string]any, len(entry.Data)+1)
+	maps.Copy(row, entry.Data)
+	row[ingest.TimestampField] = entry.Time
+	return row
+}
@@ -23,15 +23,24 @@ func NoCompletion(*cobra.Command, []string, string) ([]string, cobra.ShellCompDi
 
 // FormatCompletion returns a completion function which completes the valid
 // output formats of the `iofmt` package.
-func FormatCompletion(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	res := make([]string, 0, len(iofmt.Formats()))
-	for _, validFormat := range iofmt.Formats() {
-		if strings.HasPrefix(validFormat.String(), toComplete) {
-			res = append(res, validFormat.String())
+func FormatCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return FormatCompletionFunc()(cmd, args, toComplete)
+}
+
+// FormatCompletionFunc returns a completion function which completes the valid
+// output formats of the `iofmt` package and the given extra formats.
+func FormatCompletionFunc(extra ...iofmt.Format) CompletionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		formats := append(iofmt.Formats(), extra...)
+		res := make([]string, 0, len(formats))
+		for _, validFormat := range formats {
+			if strings.HasPrefix(validFormat.String(), toComplete) {
+				res = append(res, validFormat.String())
+			}
 		}
+		sort.Strings(res)
+		return res, cobra.ShellCompDirectiveNoFileComp
 	}
-	sort.Strings(res)
-	return res, cobra.ShellCompDirectiveNoFileComp
 }
 
 // DatasetCompletionFunc returns a completion function which completes the
@@ -40,3 +40,27 @@ func (e FlagError) Error() string {
 func (e FlagError) Unwrap() error {
 	return e.err
 }
+
+// An ExitError is an error that makes the application exit with a specific
+// exit code, to distinguish it from other errors. Its message is printed like
+// that of any other error, unless it wraps ErrSilent.
+type ExitError struct {
+	Code int
+	err  error
+}
+
+// NewExitError returns a new *ExitError that exits with the given code and
+// wraps the given error.
+func NewExitError(code int, err error) *ExitError {
+	return &ExitError{Code: code, err: err}
+}
+
+// Error implements the error interface.
+func (e ExitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap implements unwrapping functionality.
+func (e ExitError) Unwrap() error {
+	return e.err
+}
@@ -3,6 +3,7 @@ package cmdutil
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/axiomhq/axiom-go/axiom"
 
@@ -35,3 +36,14 @@ func (f *Factory) Client(ctx context.Context) (*axiom.Client, error) {
 	return client.New(ctx, deployment.URL, deployment.Token, deployment.OrganizationID,
 		deployment.EdgeURL, deployment.EdgeRegion, f.Config.Insecure)
 }
+
+// DeploymentClient returns an Axiom client configured to talk to the
+// configured deployment with the given alias. Overrides are not applied.
+func (f *Factory) DeploymentClient(ctx context.Context, alias string) (*axiom.Client, error) {
+	deployment, ok := f.Config.Deployments[alias]
+	if !ok {
+		return nil, fmt.Errorf("deployment %q not configured", alias)
+	}
+	return client.New(ctx, deployment.URL, deployment.Token, deployment.OrganizationID,
+		deployment.EdgeURL, deployment.EdgeRegion, f.Config.Insecure)
+}
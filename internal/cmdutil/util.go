@@ -1,6 +1,13 @@
 package cmdutil
 
-import "github.com/spf13/cobra"
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/axiomhq/cli/pkg/iofmt"
+	"github.com/axiomhq/cli/pkg/terminal"
+)
 
 // DefaultCompletion sets default values for Args and ValidArgsFunction on all
 // child commands. If Args is nil it is set to cobra.NoArgs, if
@@ -38,3 +45,48 @@ func InheritRootPersistenPreRun(cmd *cobra.Command) {
 		InheritRootPersistenPreRun(c)
 	}
 }
+
+// ParseFormat parses the value of a "--format" flag into an output format.
+// Besides the formats supported by every command, the given extra formats are
+// valid. An invalid format is reported as a flag error.
+func ParseFormat(s string, extra ...iofmt.Format) (iofmt.Format, error) {
+	formats := append(iofmt.Formats(), extra...)
+	for _, format := range formats {
+		if s == format.String() {
+			return format, nil
+		}
+	}
+
+	valid := make([]string, len(formats))
+	for i, format := range formats {
+		valid[i] = format.String()
+	}
+	return 0, NewFlagErrorf("invalid format %q (valid formats: %s)", s, strings.Join(valid, ", "))
+}
+
+// AddTransformFlags adds the "--template" and "--jq" flags, which shape the
+// output of a command instead of an output format, to the given command. If
+// the command has a "--format" flag, it must be added before.
+func AddTransformFlags(cmd *cobra.Command, t *iofmt.Transform) {
+	cmd.Flags().StringVarP(&t.Template, "template", "t", "", "Format JSON output using a Go template")
+	cmd.Flags().StringVarP(&t.JQ, "jq", "q", "", "Filter JSON output using a jq expression")
+
+	_ = cmd.RegisterFlagCompletionFunc("template", NoCompletion)
+	_ = cmd.RegisterFlagCompletionFunc("jq", NoCompletion)
+
+	if cmd.Flags().Lookup("format") != nil {
+		cmd.MarkFlagsMutuallyExclusive("format", "template", "jq")
+	} else {
+		cmd.MarkFlagsMutuallyExclusive("template", "jq")
+	}
+}
+
+// CompileTransform compiles the transform given by the flags added with
+// AddTransformFlags. The template functions of the color scheme are available
+// in templates.
+func CompileTransform(io *terminal.IO, t *iofmt.Transform) error {
+	if err := t.Compile(io.ColorScheme().TemplateFuncs()); err != nil {
+		return NewFlagError(err)
+	}
+	return nil
+}
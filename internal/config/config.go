@@ -79,6 +79,21 @@ func (c *Config) GetActiveDeployment() (Deployment, bool) {
 	return dep, true
 }
 
+// DataDir returns the directory the CLI keeps its data in, like saved queries.
+// It is the "axiom" directory in the users configuration directory, unless set
+// by the AXIOM_DATA_DIR environment variable. The directory might not exist.
+func DataDir() (string, error) {
+	if dir := os.Getenv("AXIOM_DATA_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, "axiom"), nil
+}
+
 // HasDefaultConfigFile returns true if the default configuration file exists.
 func HasDefaultConfigFile() bool {
 	_, err := os.Stat(defaultConfigFile())
@@ -0,0 +1,410 @@
+package iofmt
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/axiomhq/cli/pkg/terminal"
+)
+
+// chartHeight is the height of a line chart, in lines.
+const chartHeight = 10
+
+// minChartWidth is the minimum width of the plot area of a chart, no matter how
+// narrow the terminal is.
+const minChartWidth = 10
+
+var (
+	sparkLevels      = []rune("▁▂▃▄▅▆▇█")
+	sparkLevelsASCII = []rune(".:-=+*#%@")
+	barEighths       = []rune(" ▏▎▍▌▋▊▉")
+)
+
+// ChartSeries is a named series of values. Missing values are NaN.
+type ChartSeries struct {
+	Name   string
+	Values []float64
+}
+
+// ChartData is the data to output in Chart format. If it has times, every series
+// holds one value per time. A single series is drawn as a line chart and
+// multiple series as one sparkline per series. Without times, every series
+// holds a single value and the series are drawn as bars.
+type ChartData struct {
+	Times  []time.Time
+	Series []ChartSeries
+}
+
+// FormatToChart formats in Chart format. The chart is sized to the width of
+// the terminal and drawn using Unicode braille and block characters. If color
+// or Unicode output is disabled, it is drawn in plain ASCII.
+func FormatToChart(io *terminal.IO, chart ChartData) error {
+	r := chartRenderer{
+		cs:    io.ColorScheme(),
+		width: io.TerminalWidth(),
+		ascii: !io.ColorEnabled() || !io.UnicodeEnabled(),
+	}
+	return r.render(io.Out(), chart)
+}
+
+type chartRenderer struct {
+	cs    *terminal.ColorScheme
+	width int
+	ascii bool
+}
+
+func (r chartRenderer) render(w io.Writer, chart ChartData) error {
+	bw := bufio.NewWriter(w)
+
+	switch {
+	case len(chart.Series) == 0:
+		return nil
+	case len(chart.Times) == 0:
+		r.bars(bw, chart.Series)
+	case len(chart.Series) == 1:
+		r.line(bw, chart.Times, chart.Series[0])
+	default:
+		r.sparklines(bw, chart.Times, chart.Series)
+	}
+
+	return bw.Flush()
+}
+
+// line draws a single series as a line chart with a labeled value axis. Using
+// braille characters, every character holds 2x4 dots.
+func (r chartRenderer) line(w *bufio.Writer, times []time.Time, series ChartSeries) {
+	lo, hi := chartRange(series.Values)
+	if lo == hi {
+		switch {
+		case lo > 0:
+			lo = 0
+		case lo < 0:
+			hi = 0
+		default:
+			hi = 1
+		}
+	}
+
+	labels := make([]string, chartHeight)
+	labels[0] = formatChartValue(hi)
+	labels[chartHeight/2] = formatChartValue((lo + hi) / 2)
+	labels[chartHeight-1] = formatChartValue(lo)
+	labelWidth := 0
+	for _, label := range labels {
+		labelWidth = max(labelWidth, len(label))
+	}
+
+	plotWidth := max(r.width-labelWidth-2, minChartWidth)
+	cols, rows := plotWidth*2, chartHeight*4
+	if r.ascii {
+		cols, rows = plotWidth, chartHeight
+	}
+
+	dots := make([][]bool, rows)
+	for y := range dots {
+		dots[y] = make([]bool, cols)
+	}
+
+	// Points are spread evenly across the plot area and connected to their
+	// predecessor by filling the dots in between.
+	points := resample(series.Values, cols)
+	prevX, prevY := -1, 0
+	for i, v := range points {
+		if math.IsNaN(v) {
+			prevX = -1
+			continue
+		}
+
+		x := 0
+		if len(points) > 1 {
+			x = i * (cols - 1) / (len(points) - 1)
+		}
+		y := int(math.Round((hi - v) / (hi - lo) * float64(rows-1)))
+
+		if prevX < 0 {
+			dots[y][x] = true
+		} else {
+			lastY := prevY
+			for xx := prevX + 1; xx <= x; xx++ {
+				yy := prevY + int(math.Round(float64((y-prevY)*(xx-prevX))/float64(x-prevX)))
+				for _, d := range fillRange(lastY, yy) {
+					dots[d][xx] = true
+				}
+				lastY = yy
+			}
+		}
+		prevX, prevY = x, y
+	}
+
+	vertical, tick, corner, horizontal := "│", "┤", "└", "─"
+	if r.ascii {
+		vertical, tick, corner, horizontal = "|", "+", "+", "-"
+	}
+
+	if series.Name != "" {
+		w.WriteString(r.cs.Bold(series.Name) + "\n")
+	}
+	for row, label := range labels {
+		axis := vertical
+		if label != "" {
+			axis = tick
+		}
+
+		var sb strings.Builder
+		for col := range plotWidth {
+			if r.ascii {
+				if dots[row][col] {
+					sb.WriteByte('*')
+				} else {
+					sb.WriteByte(' ')
+				}
+				continue
+			}
+			sb.WriteRune(brailleCell(dots, row*4, col*2))
+		}
+
+		w.WriteString(padLeft(label, labelWidth) + " " + axis)
+		w.WriteString(r.cs.Blue(strings.TrimRight(sb.String(), " ")) + "\n")
+	}
+	w.WriteString(strings.Repeat(" ", labelWidth+1) + corner + strings.Repeat(horizontal, plotWidth) + "\n")
+	w.WriteString(r.cs.Gray(timeAxis(times, labelWidth+2, plotWidth)) + "\n")
+}
+
+// sparklines draws every series as a sparkline, along with its minimum and
+// maximum value. Every sparkline is scaled to the range of its own values.
+func (r chartRenderer) sparklines(w *bufio.Writer, times []time.Time, series []ChartSeries) {
+	levels := sparkLevels
+	if r.ascii {
+		levels = sparkLevelsASCII
+	}
+
+	names := make([]string, len(series))
+	summaries := make([]string, len(series))
+	nameWidth, summaryWidth := 0, 0
+	for i, s := range series {
+		names[i] = r.truncate(s.Name, r.width/3)
+		nameWidth = max(nameWidth, utf8.RuneCountInString(names[i]))
+
+		lo, hi := chartRange(s.Values)
+		summaries[i] = "min " + formatChartValue(lo) + "  max " + formatChartValue(hi)
+		summaryWidth = max(summaryWidth, len(summaries[i]))
+	}
+
+	sparkWidth := max(r.width-nameWidth-summaryWidth-4, minChartWidth)
+	for i, s := range series {
+		lo, hi := chartRange(s.Values)
+
+		var sb strings.Builder
+		for _, v := range resample(s.Values, sparkWidth) {
+			switch {
+			case math.IsNaN(v):
+				sb.WriteByte(' ')
+			case hi == lo:
+				sb.WriteRune(levels[len(levels)/2])
+			default:
+				level := int(math.Round((v - lo) / (hi - lo) * float64(len(levels)-1)))
+				sb.WriteRune(levels[level])
+			}
+		}
+		spark := padRight(sb.String(), sparkWidth)
+
+		w.WriteString(padRight(names[i], nameWidth) + "  " + r.cs.Blue(spark) + "  " + r.cs.Gray(summaries[i]) + "\n")
+	}
+	w.WriteString(r.cs.Gray(timeAxis(times, nameWidth+2, min(len(times), sparkWidth))) + "\n")
+}
+
+// bars draws the single value of every series as a horizontal bar. The bars
+// are scaled to the largest absolute value.
+func (r chartRenderer) bars(w *bufio.Writer, series []ChartSeries) {
+	var (
+		names  = make([]string, len(series))
+		values = make([]float64, len(series))
+		labels = make([]string, len(series))
+
+		nameWidth, labelWidth int
+		maxAbs                float64
+	)
+	for i, s := range series {
+		names[i] = r.truncate(s.Name, r.width/3)
+		nameWidth = max(nameWidth, utf8.RuneCountInString(names[i]))
+
+		values[i] = math.NaN()
+		if len(s.Values) > 0 {
+			values[i] = s.Values[0]
+		}
+		labels[i] = "-"
+		if !math.IsNaN(values[i]) {
+			labels[i] = formatChartValue(values[i])
+			maxAbs = max(maxAbs, math.Abs(values[i]))
+		}
+		labelWidth = max(labelWidth, len(labels[i]))
+	}
+
+	barWidth := max(r.width-nameWidth-labelWidth-4, minChartWidth)
+	for i := range series {
+		var bar string
+		if !math.IsNaN(values[i]) && maxAbs > 0 {
+			length := math.Abs(values[i]) / maxAbs * float64(barWidth)
+			if r.ascii {
+				bar = strings.Repeat("#", int(math.Round(length)))
+			} else {
+				full := int(length)
+				bar = strings.Repeat("█", full)
+				if eighths := int((length - float64(full)) * 8); eighths > 0 {
+					bar += string(barEighths[eighths])
+				}
+			}
+		}
+		if bar == "" && !math.IsNaN(values[i]) && values[i] != 0 {
+			// Tiny values still get a bar, so they don't look like zero.
+			bar = string(barEighths[1])
+			if r.ascii {
+				bar = "#"
+			}
+		}
+
+		w.WriteString(padRight(names[i], nameWidth) + "  " + r.cs.Blue(padRight(bar, barWidth)) + "  " + padLeft(labels[i], labelWidth) + "\n")
+	}
+}
+
+func (r chartRenderer) truncate(s string, maxWidth int) string {
+	maxWidth = max(maxWidth, 4)
+	if utf8.RuneCountInString(s) <= maxWidth {
+		return s
+	}
+	ellipsis := "…"
+	if r.ascii {
+		ellipsis = "~"
+	}
+	return string([]rune(s)[:maxWidth-1]) + ellipsis
+}
+
+// brailleCell returns the braille character for the 2x4 dots starting at the
+// given row and column. Cells without dots are returned as spaces.
+func brailleCell(dots [][]bool, row, col int) rune {
+	// Bits of the dots of a braille character, by row and column.
+	bits := [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+	cell := rune(0x2800)
+	for y := range 4 {
+		for x := range 2 {
+			if dots[row+y][col+x] {
+				cell |= bits[y][x]
+			}
+		}
+	}
+	if cell == 0x2800 {
+		return ' '
+	}
+	return cell
+}
+
+// timeAxis returns the first and last time, aligned to the start and end of a
+// plot area of the given width, which is indented by the given offset. If the
+// plot area is too narrow, only the first time is returned.
+func timeAxis(times []time.Time, indent, width int) string {
+	first, last := times[0], times[len(times)-1]
+
+	layout := "2006-01-02 15:04"
+	if y1, m1, d1 := first.Date(); len(times) > 1 {
+		if y2, m2, d2 := last.Date(); y1 == y2 && m1 == m2 && d1 == d2 {
+			layout = time.TimeOnly
+		}
+	}
+
+	start, end := first.Format(layout), last.Format(layout)
+	axis := strings.Repeat(" ", indent) + start
+	if gap := width - len(start) - len(end); len(times) > 1 && gap > 0 {
+		axis += strings.Repeat(" ", gap) + end
+	}
+	return axis
+}
+
+// resample reduces the values to at most n values by averaging consecutive
+// values. NaN values are ignored.
+func resample(values []float64, n int) []float64 {
+	if len(values) <= n {
+		return values
+	}
+
+	res := make([]float64, n)
+	for i := range res {
+		var (
+			sum   float64
+			count int
+		)
+		for _, v := range values[i*len(values)/n : (i+1)*len(values)/n] {
+			if !math.IsNaN(v) {
+				sum += v
+				count++
+			}
+		}
+		res[i] = math.NaN()
+		if count > 0 {
+			res[i] = sum / float64(count)
+		}
+	}
+	return res
+}
+
+// chartRange returns the minimum and maximum of the values, ignoring NaN
+// values. It returns zero for both if there are no values.
+func chartRange(values []float64) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = min(lo, v), max(hi, v)
+		}
+	}
+	if math.IsInf(lo, 1) {
+		return 0, 0
+	}
+	return lo, hi
+}
+
+// fillRange returns the integers from a to b, excluding a unless a equals b.
+func fillRange(a, b int) []int {
+	if a == b {
+		return []int{a}
+	}
+
+	step := 1
+	if b < a {
+		step = -1
+	}
+	var res []int
+	for i := a + step; i != b+step; i += step {
+		res = append(res, i)
+	}
+	return res
+}
+
+// formatChartValue formats a value compactly, using SI prefixes for large
+// values.
+func formatChartValue(v float64) string {
+	for _, unit := range []struct {
+		prefix string
+		scale  float64
+	}{{"T", 1e12}, {"G", 1e9}, {"M", 1e6}, {"k", 1e3}} {
+		if math.Abs(v) >= unit.scale {
+			return strconv.FormatFloat(math.Round(v/unit.scale*10)/10, 'f', -1, 64) + unit.prefix
+		}
+	}
+	if math.Abs(v) < 1 {
+		return strconv.FormatFloat(v, 'g', 3, 64)
+	}
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+func padLeft(s string, width int) string {
+	return strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0)) + s
+}
+
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0))
+}
@@ -0,0 +1,126 @@
+package iofmt
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/axiomhq/cli/pkg/terminal"
+)
+
+func renderTestChart(t *testing.T, chart ChartData, width int, ascii bool) []string {
+	t.Helper()
+
+	r := chartRenderer{
+		cs:    terminal.NewColorScheme(false),
+		width: width,
+		ascii: ascii,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, r.render(&buf, chart))
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func testTimes(n int) []time.Time {
+	times := make([]time.Time, n)
+	for i := range times {
+		times[i] = time.Date(2024, 1, 1, 12, i, 0, 0, time.UTC)
+	}
+	return times
+}
+
+func TestChart_Line(t *testing.T) {
+	chart := ChartData{
+		Times:  testTimes(3),
+		Series: []ChartSeries{{Name: "count_", Values: []float64{0, 10, 5}}},
+	}
+
+	for _, ascii := range []bool{false, true} {
+		lines := renderTestChart(t, chart, 40, ascii)
+
+		// Title, plot, x-axis and time labels.
+		require.Len(t, lines, chartHeight+3)
+		assert.Equal(t, "count_", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "10 "), lines[1])
+		assert.True(t, strings.HasPrefix(lines[chartHeight], " 0 "), lines[chartHeight])
+		assert.Equal(t, "    12:00:00                    12:02:00", lines[chartHeight+2])
+
+		for _, line := range lines {
+			assert.LessOrEqual(t, len([]rune(line)), 40, line)
+			if ascii {
+				assert.Equal(t, len(line), len([]rune(line)), "non-ASCII line %q", line)
+			}
+		}
+	}
+
+	lines := renderTestChart(t, chart, 40, true)
+	// The maximum is plotted in the top row, at the center.
+	assert.Equal(t, "10 +", lines[1][:4])
+	assert.Equal(t, byte('*'), lines[1][4+18])
+	// The first value is plotted in the bottom row, at the very left.
+	assert.Equal(t, " 0 +*", lines[chartHeight][:5])
+}
+
+func TestChart_Sparklines(t *testing.T) {
+	chart := ChartData{
+		Times: testTimes(4),
+		Series: []ChartSeries{
+			{Name: "api", Values: []float64{1, 2, 3, 4}},
+			{Name: "web", Values: []float64{4, math.NaN(), 2, 2}},
+		},
+	}
+
+	lines := renderTestChart(t, chart, 60, false)
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "api  ▁▃▆█ "), lines[0])
+	assert.True(t, strings.HasSuffix(lines[0], "min 1  max 4"), lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "web  █ ▁▁ "), lines[1])
+	assert.True(t, strings.HasSuffix(lines[1], "min 2  max 4"), lines[1])
+
+	lines = renderTestChart(t, chart, 60, true)
+	assert.True(t, strings.HasPrefix(lines[0], "api  .=*@ "), lines[0])
+}
+
+func TestChart_Bars(t *testing.T) {
+	chart := ChartData{
+		Series: []ChartSeries{
+			{Name: "api", Values: []float64{1500}},
+			{Name: "web", Values: []float64{750}},
+			{Name: "db", Values: []float64{math.NaN()}},
+		},
+	}
+
+	lines := renderTestChart(t, chart, 30, true)
+	assert.Equal(t, []string{
+		"api  ###################  1.5k",
+		"web  ##########            750",
+		"db                           -",
+	}, lines)
+}
+
+func TestResample(t *testing.T) {
+	assert.Equal(t, []float64{1, 2}, resample([]float64{1, 2}, 4))
+	assert.Equal(t, []float64{1.5, 3}, resample([]float64{1, 2, math.NaN(), 3}, 2))
+}
+
+func TestFormatChartValue(t *testing.T) {
+	tests := map[float64]string{
+		0:         "0",
+		0.12345:   "0.123",
+		42:        "42",
+		12.345:    "12.3",
+		1234:      "1.2k",
+		-2500000:  "-2.5M",
+		3e9:       "3G",
+		999999e12: "999999T",
+	}
+	for v, want := range tests {
+		assert.Equal(t, want, formatChartValue(v), "%v", v)
+	}
+}
@@ -1,3 +1,24 @@
 // Package iofmt provides utilities for formatting and outputting structured
 // data.
+//
+// Commands output a list of records, like datasets or the rows of a query
+// result, in one of the supported formats:
+//
+//   - table: A human readable table. Not meant to be parsed.
+//   - json: A single JSON array holding all records as objects, on one line.
+//     An empty list is written as "[]". Commands that never finish, like
+//     streaming, write one object per line instead, just like ndjson.
+//   - ndjson: Newline delimited JSON, one object per record and line. Nothing
+//     is written for an empty list.
+//   - csv and tsv: Comma and tab separated values with a header row.
+//   - yaml: A YAML sequence holding all records as mappings.
+//   - markdown: A Markdown table with a header row.
+//
+// Commands that output time series, like query results, additionally support
+// the chart format, which draws them as line charts, sparklines or bars in the
+// terminal.
+//
+// All formats but table represent records the way they are encoded to JSON.
+// In csv, tsv and markdown output, nested objects and arrays are written as
+// JSON.
 package iofmt
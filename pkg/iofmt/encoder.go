@@ -0,0 +1,326 @@
+package iofmt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Encoder writes records of structured data in an output format. Records are
+// written as they are encoded, so encoders are suitable for streaming. Close
+// must be called once all records are encoded.
+type Encoder interface {
+	// Encode writes a single record. A record is a map of field names to
+	// values or any value that encodes to a JSON object.
+	Encode(record any) error
+	// Close writes what completes the output, like the closing bracket of a
+	// JSON array or the header of an empty table. It doesn't close the
+	// underlying writer.
+	Close() error
+}
+
+// NewEncoder returns an Encoder that writes records to w in the given format.
+// The fields select and order the columns of the tabular formats CSV, TSV and
+// Markdown. If no fields are given, the fields of the first record are used,
+// in alphabetical order. The fields are ignored by all other formats. Color is
+// only supported by the JSON and NDJSON formats. The Table format is not
+// supported, use FormatToTable instead.
+func NewEncoder(w io.Writer, format Format, fields []string, colorEnabled bool) (Encoder, error) {
+	switch format {
+	case JSON:
+		return &jsonArrayEncoder{w: w, colorEnabled: colorEnabled}, nil
+	case NDJSON:
+		return &ndjsonEncoder{w: w, colorEnabled: colorEnabled}, nil
+	case CSV:
+		return newTabularEncoder(&csvWriter{w: csv.NewWriter(w)}, fields), nil
+	case TSV:
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+		return newTabularEncoder(&csvWriter{w: cw}, fields), nil
+	case YAML:
+		return &yamlEncoder{w: w}, nil
+	case Markdown:
+		return newTabularEncoder(&markdownWriter{w: w}, fields), nil
+	}
+	return nil, fmt.Errorf("format %q does not support encoding records", format)
+}
+
+// FormatRecords formats all records in the given format, which must not be
+// Table. See NewEncoder for the meaning of fields.
+func FormatRecords[T any](w io.Writer, format Format, fields []string, records []T, colorEnabled bool) error {
+	enc, err := NewEncoder(w, format, fields, colorEnabled)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err = enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// encodeJSON returns the value encoded by FormatToJSON, without the trailing
+// newline.
+func encodeJSON(v any, colorEnabled bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := FormatToJSON(&buf, v, colorEnabled); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonArrayEncoder writes records as the elements of a single JSON array on
+// one line, just like a slice of them encoded by FormatToJSON.
+type jsonArrayEncoder struct {
+	w            io.Writer
+	colorEnabled bool
+	n            int
+}
+
+func (e *jsonArrayEncoder) Encode(record any) error {
+	b, err := encodeJSON(record, e.colorEnabled)
+	if err != nil {
+		return err
+	}
+
+	sep := ","
+	if e.n == 0 {
+		sep = "["
+	}
+	e.n++
+
+	_, err = fmt.Fprintf(e.w, "%s%s", sep, b)
+	return err
+}
+
+func (e *jsonArrayEncoder) Close() error {
+	if e.n == 0 {
+		_, err := io.WriteString(e.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// ndjsonEncoder writes every record as a JSON object on its own line.
+type ndjsonEncoder struct {
+	w            io.Writer
+	colorEnabled bool
+}
+
+func (e *ndjsonEncoder) Encode(record any) error {
+	return FormatToJSON(e.w, record, e.colorEnabled)
+}
+
+func (e *ndjsonEncoder) Close() error { return nil }
+
+// yamlEncoder writes records as the items of a YAML sequence. Every record is
+// written as a single item sequence, which concatenated make up the sequence
+// of all records.
+type yamlEncoder struct {
+	w io.Writer
+	n int
+}
+
+func (e *yamlEncoder) Encode(record any) error {
+	v, err := normalize(record)
+	if err != nil {
+		return err
+	}
+	e.n++
+
+	b, err := yaml.Marshal([]any{v})
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *yamlEncoder) Close() error {
+	if e.n == 0 {
+		_, err := io.WriteString(e.w, "[]\n")
+		return err
+	}
+	return nil
+}
+
+// rowWriter writes the rows of a table.
+type rowWriter interface {
+	WriteHeader(fields []string) error
+	WriteRow(values []string) error
+	Flush() error
+}
+
+// tabularEncoder writes records as the rows of a table with a header row.
+type tabularEncoder struct {
+	w      rowWriter
+	fields []string
+	header bool
+}
+
+func newTabularEncoder(w rowWriter, fields []string) *tabularEncoder {
+	return &tabularEncoder{w: w, fields: fields}
+}
+
+func (e *tabularEncoder) Encode(record any) error {
+	v, err := normalize(record)
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("cannot encode %T as a table row", record)
+	}
+
+	if e.fields == nil {
+		e.fields = slices.Sorted(maps.Keys(m))
+	}
+	if err = e.writeHeader(); err != nil {
+		return err
+	}
+
+	values := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		if values[i], err = cellValue(m[field]); err != nil {
+			return err
+		}
+	}
+
+	if err = e.w.WriteRow(values); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *tabularEncoder) Close() error {
+	// Without fields, there is no header to write.
+	if e.fields == nil {
+		return nil
+	} else if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *tabularEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.w.WriteHeader(e.fields)
+}
+
+// cellValue returns the string representation of a value in a table cell.
+// Objects and arrays are written as JSON.
+func cellValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return fmt.Sprint(v), nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) WriteHeader(fields []string) error { return w.w.Write(fields) }
+func (w *csvWriter) WriteRow(values []string) error    { return w.w.Write(values) }
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type markdownWriter struct {
+	w io.Writer
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"|", `\|`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+)
+
+func (w *markdownWriter) WriteHeader(fields []string) error {
+	if err := w.WriteRow(fields); err != nil {
+		return err
+	}
+	sep := make([]string, len(fields))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	_, err := fmt.Fprintf(w.w, "| %s |\n", strings.Join(sep, " | "))
+	return err
+}
+
+func (w *markdownWriter) WriteRow(values []string) error {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = markdownEscaper.Replace(v)
+	}
+	_, err := fmt.Fprintf(w.w, "| %s |\n", strings.Join(escaped, " | "))
+	return err
+}
+
+func (w *markdownWriter) Flush() error { return nil }
+
+// normalize returns the value as it would be encoded to JSON, made up of maps,
+// slices, strings, numbers, booleans and nil. This makes sure all formats
+// respect the JSON representation of a value, like field names given by struct
+// tags. Integers are decoded as int, so no precision is lost, and all other
+// numbers as float64.
+func normalize(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var res any
+	if err = dec.Decode(&res); err != nil {
+		return nil, err
+	}
+	return convertNumbers(res), nil
+}
+
+func convertNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = convertNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = convertNumbers(e)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil && int64(int(i)) == i {
+			return int(i)
+		} else if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
@@ -0,0 +1,151 @@
+package iofmt
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRecord struct {
+	Name    string         `json:"name"`
+	Count   int64          `json:"count"`
+	Created time.Time      `json:"created"`
+	Tags    []string       `json:"tags"`
+	Extra   map[string]any `json:"extra,omitempty"`
+}
+
+var testRecords = []any{
+	testRecord{
+		Name:    "a",
+		Count:   9007199254740993,
+		Created: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tags:    []string{"x", "y"},
+	},
+	map[string]any{
+		"name":  "b|c",
+		"count": 1.5,
+		"tags":  nil,
+		"extra": map[string]any{"line": "1\n2"},
+	},
+}
+
+func TestNewEncoder(t *testing.T) {
+	tests := []struct {
+		format Format
+		fields []string
+		want   string
+	}{
+		{
+			format: JSON,
+			want: `[{"name":"a","count":9007199254740993,"created":"2024-01-01T00:00:00Z","tags":["x","y"]},` +
+				`{"count":1.5,"extra":{"line":"1\n2"},"name":"b|c","tags":null}]` + "\n",
+		},
+		{
+			format: NDJSON,
+			want: `{"name":"a","count":9007199254740993,"created":"2024-01-01T00:00:00Z","tags":["x","y"]}` + "\n" +
+				`{"count":1.5,"extra":{"line":"1\n2"},"name":"b|c","tags":null}` + "\n",
+		},
+		{
+			format: CSV,
+			fields: []string{"name", "count", "tags", "extra"},
+			want: "name,count,tags,extra\n" +
+				"a,9007199254740993,\"[\"\"x\"\",\"\"y\"\"]\",\n" +
+				"b|c,1.5,,\"{\"\"line\"\":\"\"1\\n2\"\"}\"\n",
+		},
+		{
+			format: CSV,
+			want: "count,created,name,tags\n" +
+				"9007199254740993,2024-01-01T00:00:00Z,a,\"[\"\"x\"\",\"\"y\"\"]\"\n" +
+				"1.5,,b|c,\n",
+		},
+		{
+			format: TSV,
+			fields: []string{"name", "count"},
+			want:   "name\tcount\na\t9007199254740993\nb|c\t1.5\n",
+		},
+		{
+			format: YAML,
+			want: "- count: 9007199254740993\n" +
+				"  created: \"2024-01-01T00:00:00Z\"\n" +
+				"  name: a\n" +
+				"  tags:\n" +
+				"    - x\n" +
+				"    - \"y\"\n" +
+				"- count: 1.5\n" +
+				"  extra:\n" +
+				"    line: |-\n" +
+				"        1\n" +
+				"        2\n" +
+				"  name: b|c\n" +
+				"  tags: null\n",
+		},
+		{
+			format: Markdown,
+			fields: []string{"name", "extra"},
+			want: "| name | extra |\n" +
+				"| --- | --- |\n" +
+				"| a |  |\n" +
+				"| b\\|c | {\"line\":\"1\\\\n2\"} |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := NewEncoder(&buf, tt.format, tt.fields, false)
+			require.NoError(t, err)
+
+			for _, record := range testRecords {
+				require.NoError(t, enc.Encode(record))
+			}
+			require.NoError(t, enc.Close())
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestNewEncoder_Empty(t *testing.T) {
+	tests := []struct {
+		format Format
+		fields []string
+		want   string
+	}{
+		{JSON, nil, "[]\n"},
+		{NDJSON, nil, ""},
+		{CSV, nil, ""},
+		{CSV, []string{"a", "b"}, "a,b\n"},
+		{YAML, nil, "[]\n"},
+		{Markdown, []string{"a"}, "| a |\n| --- |\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := NewEncoder(&buf, tt.format, tt.fields, false)
+			require.NoError(t, err)
+			require.NoError(t, enc.Close())
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestNewEncoder_Table(t *testing.T) {
+	_, err := NewEncoder(&bytes.Buffer{}, Table, nil, false)
+	assert.Error(t, err)
+}
+
+func TestFormatFromString(t *testing.T) {
+	for _, format := range Formats() {
+		got, err := FormatFromString(format.String())
+		require.NoError(t, err)
+		assert.Equal(t, format, got)
+	}
+
+	_, err := FormatFromString("xml")
+	assert.Error(t, err)
+}
@@ -10,27 +10,34 @@ type Format uint8
 const (
 	// Table formats output in tabular style.
 	Table Format = iota + 1 // table
-	// JSON formats output as one or more JSON objects.
+	// JSON formats output as a single JSON array of objects.
 	JSON // json
+	// NDJSON formats output as newline delimited JSON, one object per line.
+	NDJSON // ndjson
+	// CSV formats output as comma separated values with a header row.
+	CSV // csv
+	// TSV formats output as tab separated values with a header row.
+	TSV // tsv
+	// YAML formats output as a YAML sequence of mappings.
+	YAML // yaml
+	// Markdown formats output as a Markdown table.
+	Markdown // markdown
+	// Chart renders time series as charts in the terminal. Only commands that
+	// output time series support it, so it is not part of Formats.
+	Chart // chart
 )
 
-// Formats returns all supported formats.
+// Formats returns all formats supported by every command.
 func Formats() []Format {
-	return []Format{Table, JSON}
+	return []Format{Table, JSON, NDJSON, CSV, TSV, YAML, Markdown}
 }
 
 // FormatFromString parses a supported Format from its string representation.
 func FormatFromString(s string) (Format, error) {
-	var format Format
-
-	switch s {
-	case Table.String():
-		format = Table
-	case JSON.String():
-		format = JSON
-	default:
-		return 0, fmt.Errorf("unknown format %q", s)
+	for _, format := range Formats() {
+		if s == format.String() {
+			return format, nil
+		}
 	}
-
-	return format, nil
+	return 0, fmt.Errorf("unknown format %q", s)
 }
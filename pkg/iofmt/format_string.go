@@ -10,11 +10,17 @@ func _() {
 	var x [1]struct{}
 	_ = x[Table-1]
 	_ = x[JSON-2]
+	_ = x[NDJSON-3]
+	_ = x[CSV-4]
+	_ = x[TSV-5]
+	_ = x[YAML-6]
+	_ = x[Markdown-7]
+	_ = x[Chart-8]
 }
 
-const _Format_name = "tablejson"
+const _Format_name = "tablejsonndjsoncsvtsvyamlmarkdownchart"
 
-var _Format_index = [...]uint8{0, 5, 9}
+var _Format_index = [...]uint8{0, 5, 9, 15, 18, 21, 25, 33, 38}
 
 func (i Format) String() string {
 	idx := int(i) - 1
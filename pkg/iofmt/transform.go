@@ -0,0 +1,106 @@
+package iofmt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/itchyny/gojq"
+)
+
+// Transform shapes output using a Go template or a jq expression, as an
+// alternative to an output format. Both work on the data as it is encoded to
+// JSON, so fields are accessed by their JSON names. At most one of Template
+// and JQ may be set.
+type Transform struct {
+	// Template is a Go template, see https://pkg.go.dev/text/template.
+	Template string
+	// JQ is a jq expression, see https://jqlang.github.io/jq/manual.
+	JQ string
+
+	tmpl *template.Template
+	code *gojq.Code
+}
+
+// IsSet returns true if a template or jq expression is set.
+func (t *Transform) IsSet() bool {
+	return t.Template != "" || t.JQ != ""
+}
+
+// Compile parses the template or jq expression. The given functions are
+// available in the template, in addition to "json", which encodes a value as
+// JSON.
+func (t *Transform) Compile(funcs template.FuncMap) error {
+	switch {
+	case t.Template != "" && t.JQ != "":
+		return errors.New("template and jq expression are mutually exclusive")
+	case t.Template != "":
+		tmpl, err := template.New("template").
+			Funcs(template.FuncMap{"json": templateJSON}).
+			Funcs(funcs).
+			Parse(t.Template)
+		if err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+		t.tmpl = tmpl
+	case t.JQ != "":
+		q, err := gojq.Parse(t.JQ)
+		if err != nil {
+			return fmt.Errorf("invalid jq expression: %w", err)
+		}
+		code, err := gojq.Compile(q)
+		if err != nil {
+			return fmt.Errorf("invalid jq expression: %w", err)
+		}
+		t.code = code
+	}
+	return nil
+}
+
+// Write executes the template or jq expression on the data and writes the
+// result to w. Compile must be called first. The results of a jq expression
+// are written one per line, strings as is and all other values as JSON.
+func (t *Transform) Write(w io.Writer, data any, colorEnabled bool) error {
+	v, err := normalize(data)
+	if err != nil {
+		return err
+	}
+
+	if t.tmpl != nil {
+		return t.tmpl.Execute(w, v)
+	} else if t.code == nil {
+		return errors.New("transform not compiled")
+	}
+
+	iter := t.code.Run(v)
+	for {
+		res, ok := iter.Next()
+		if !ok {
+			return nil
+		}
+
+		switch res := res.(type) {
+		case error:
+			var haltErr *gojq.HaltError
+			if errors.As(res, &haltErr) && haltErr.Value() == nil {
+				return nil
+			}
+			return fmt.Errorf("jq: %w", res)
+		case string:
+			if _, err = fmt.Fprintln(w, res); err != nil {
+				return err
+			}
+		default:
+			if err = FormatToJSON(w, res, colorEnabled); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func templateJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
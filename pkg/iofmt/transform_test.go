@@ -0,0 +1,82 @@
+package iofmt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var transformRecords = []map[string]any{
+	{"name": "a", "status": 200, "tags": []string{"x"}},
+	{"name": "b", "status": 503, "tags": nil},
+	{"name": "c", "status": 9007199254740993},
+}
+
+func TestTransform(t *testing.T) {
+	funcs := template.FuncMap{"upper": strings.ToUpper}
+
+	tests := []struct {
+		name      string
+		transform Transform
+		want      string
+	}{
+		{
+			name:      "template",
+			transform: Transform{Template: `{{range .}}{{.name | upper}} {{.status}}{{"\n"}}{{end}}`},
+			want:      "A 200\nB 503\nC 9007199254740993\n",
+		},
+		{
+			name:      "template json",
+			transform: Transform{Template: `{{range .}}{{json .tags}}{{"\n"}}{{end}}`},
+			want:      "[\"x\"]\nnull\nnull\n",
+		},
+		{
+			name:      "jq select",
+			transform: Transform{JQ: `.[] | select(.status >= 500)`},
+			want:      "{\"name\":\"b\",\"status\":503,\"tags\":null}\n{\"name\":\"c\",\"status\":9007199254740993}\n",
+		},
+		{
+			name:      "jq strings",
+			transform: Transform{JQ: `.[].name`},
+			want:      "a\nb\nc\n",
+		},
+		{
+			name:      "jq no results",
+			transform: Transform{JQ: `.[] | select(.status == 404)`},
+			want:      "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.True(t, tt.transform.IsSet())
+			require.NoError(t, tt.transform.Compile(funcs))
+
+			var buf bytes.Buffer
+			require.NoError(t, tt.transform.Write(&buf, transformRecords, false))
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestTransform_Errors(t *testing.T) {
+	assert.False(t, (&Transform{}).IsSet())
+
+	err := (&Transform{Template: "{{.name"}).Compile(nil)
+	assert.ErrorContains(t, err, "invalid template")
+
+	err = (&Transform{JQ: ".[] |"}).Compile(nil)
+	assert.ErrorContains(t, err, "invalid jq expression")
+
+	err = (&Transform{Template: "x", JQ: "."}).Compile(nil)
+	assert.Error(t, err)
+
+	tr := Transform{JQ: `error("boom")`}
+	require.NoError(t, tr.Compile(nil))
+	assert.ErrorContains(t, tr.Write(&bytes.Buffer{}, nil, false), "boom")
+}
@@ -0,0 +1,23 @@
+package iofmt
+
+import (
+	"io"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// FormatToYAML formats the given data in YAML format. Like in all formats, the
+// data is represented the way it is encoded to JSON.
+func FormatToYAML(w io.Writer, v any) error {
+	v, err := normalize(v)
+	if err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
@@ -45,8 +46,9 @@ type IO struct {
 	isStdoutTTY bool
 	isStderrTTY bool
 
-	colorScheme  *ColorScheme
-	colorEnabled bool
+	colorScheme    *ColorScheme
+	colorEnabled   bool
+	unicodeEnabled bool
 
 	pagerCommand             string
 	activityIndicator        *spinner.Spinner
@@ -71,6 +73,7 @@ func NewIO() *IO {
 
 	io.colorEnabled = envColorForced() || (!envColorDisabled() && io.isStdoutTTY)
 	io.colorScheme = NewColorScheme(io.colorEnabled)
+	io.unicodeEnabled = envUnicodeEnabled()
 
 	if io.isStdoutTTY && io.isStderrTTY {
 		color := "fgMagenta"
@@ -115,6 +118,12 @@ func (io *IO) ColorEnabled() bool {
 	return io.colorEnabled
 }
 
+// UnicodeEnabled returns true if the locale of the environment supports
+// Unicode output beyond plain ASCII.
+func (io *IO) UnicodeEnabled() bool {
+	return io.unicodeEnabled
+}
+
 // IsStdinTTY returns true if a TTY is attached to stdin.
 func (io *IO) IsStdinTTY() bool {
 	return io.isStdinTTY
@@ -255,6 +264,18 @@ func (io *IO) Doc(s string) string {
 	return doc.Wrap(s, io.TerminalWidth()-2)
 }
 
+// envUnicodeEnabled reports if the locale of the environment uses UTF-8. The
+// first of LC_ALL, LC_CTYPE and LANG that is set determines the locale. On
+// Windows, the console always supports Unicode.
+func envUnicodeEnabled() bool {
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := strings.ToLower(os.Getenv(key)); v != "" {
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return runtime.GOOS == "windows"
+}
+
 func isTerminal(f *os.File) bool {
 	fd := f.Fd()
 	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
@@ -0,0 +1,59 @@
+package terminal
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvUnicodeEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		lcAll   string
+		lcCtype string
+		lang    string
+		want    bool
+	}{
+		{
+			name: "pristine env",
+			want: runtime.GOOS == "windows",
+		},
+		{
+			name: "LANG UTF-8",
+			lang: "en_US.UTF-8",
+			want: true,
+		},
+		{
+			name: "LANG utf8",
+			lang: "de_DE.utf8",
+			want: true,
+		},
+		{
+			name: "LANG C",
+			lang: "C",
+			want: false,
+		},
+		{
+			name:    "LC_CTYPE takes precedence over LANG",
+			lcCtype: "POSIX",
+			lang:    "en_US.UTF-8",
+			want:    false,
+		},
+		{
+			name:  "LC_ALL takes precedence over LANG",
+			lcAll: "C.UTF-8",
+			lang:  "C",
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_CTYPE", tt.lcCtype)
+			t.Setenv("LANG", tt.lang)
+
+			assert.Equal(t, tt.want, envUnicodeEnabled())
+		})
+	}
+}